@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -17,6 +19,45 @@ type Config struct {
 	DBName     string
 	JWTSecret  string
 	Port       string
+	UploadDir  string
+
+	// JWTPreviousSecrets and JWTPreviousPublicKeyFiles are keys that no
+	// longer sign tokens but whose tokens are still accepted, for rotation.
+	// With JWTPrivateKeyFile set tokens are signed with that RSA or Ed25519
+	// key instead of JWTSecret
+	JWTPreviousSecrets        []string
+	JWTPrivateKeyFile         string
+	JWTPreviousPublicKeyFiles []string
+
+	// AccessTokenTTL is how long a JWT is accepted; RefreshTokenTTL is how
+	// long a session may stay idle before the user has to log in again
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// LoginAttemptStore is where failed logins are counted: "memory" for a
+	// single instance or "postgres" to share the count between replicas
+	LoginAttemptStore string
+
+	// TrustedProxies are the addresses or CIDR ranges of the reverse
+	// proxies whose X-Forwarded-For is believed; empty ignores the header
+	TrustedProxies []string
+
+	// TOTPIssuer is the name authenticator apps show next to the username
+	TOTPIssuer string
+
+	// OIDC single sign-on; disabled unless OIDCIssuerURL is set.
+	// OIDCFrontendURL is where the browser is sent back to after the IdP
+	// callback. OIDCDefaultRole is given to users whose groups match no
+	// mapping; empty refuses them
+	OIDCIssuerURL     string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCFrontendURL   string
+	OIDCScopes        []string
+	OIDCGroupsClaim   string
+	OIDCUsernameClaim string
+	OIDCDefaultRole   string
 }
 
 func LoadConfig() *Config {
@@ -26,8 +67,31 @@ func LoadConfig() *Config {
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "warehouse_db"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
+		JWTSecret:  getEnv("JWT_SECRET", ""),
 		Port:       getEnv("PORT", "8080"),
+		UploadDir:  getEnv("UPLOAD_DIR", "uploads"),
+
+		JWTPreviousSecrets:        getListEnv("JWT_PREVIOUS_SECRETS"),
+		JWTPrivateKeyFile:         getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTPreviousPublicKeyFiles: getListEnv("JWT_PREVIOUS_PUBLIC_KEY_FILES"),
+
+		AccessTokenTTL:  getDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDurationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+
+		LoginAttemptStore: getEnv("LOGIN_ATTEMPT_STORE", "memory"),
+		TrustedProxies:    getListEnv("TRUSTED_PROXIES"),
+
+		TOTPIssuer: getEnv("TOTP_ISSUER", "Warehouse"),
+
+		OIDCIssuerURL:     getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/oidc/callback"),
+		OIDCFrontendURL:   getEnv("OIDC_FRONTEND_URL", "http://localhost:3000/login"),
+		OIDCScopes:        getListEnv("OIDC_SCOPES"),
+		OIDCGroupsClaim:   getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCUsernameClaim: getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		OIDCDefaultRole:   getEnv("OIDC_DEFAULT_ROLE", ""),
 	}
 }
 
@@ -38,6 +102,33 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getListEnv splits a comma-separated variable, skipping empty items
+func getListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getDurationEnv parses a duration such as "15m" or "168h", falling back to
+// defaultValue when the variable is unset or invalid
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
 func InitDB(cfg *Config) (*sql.DB, error) {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
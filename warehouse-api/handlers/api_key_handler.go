@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+	"warehouse-api/services"
+
+	"github.com/gorilla/mux"
+)
+
+type APIKeyHandler struct {
+	apiKeyService services.APIKeyService
+	userRepo      repositories.UserRepository
+	roleService   services.RoleService
+	auditService  services.AuditService
+}
+
+func NewAPIKeyHandler(apiKeyService services.APIKeyService, userRepo repositories.UserRepository,
+	roleService services.RoleService, auditService services.AuditService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		userRepo:      userRepo,
+		roleService:   roleService,
+		auditService:  auditService,
+	}
+}
+
+// GetAll lists every key, revoked ones last, with when and from where each
+// was last used
+func (h *APIKeyHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.apiKeyService.GetAll()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get API keys", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "API keys retrieved successfully", keys, nil)
+}
+
+func (h *APIKeyHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	key := h.findKey(w, id)
+	if key == nil {
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "API key retrieved successfully", key, nil)
+}
+
+// Create issues a new key for user_id. The key is in the response and
+// cannot be retrieved again
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	req, key := h.decodeKey(w, r)
+	if key == nil {
+		return
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Expires at must be in the future", "")
+		return
+	}
+
+	if req.UserID <= 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "User ID is required", "")
+		return
+	}
+	owner := h.findOwner(w, r, req.UserID)
+	if owner == nil {
+		return
+	}
+	if !owner.IsActive {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "User is disabled", "")
+		return
+	}
+	key.UserID = owner.ID
+	key.CreatedBy = &claims.UserID
+
+	created, err := h.apiKeyService.Create(key)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create API key", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "create", "api_key", created.ID, created.Nama, nil, created.APIKey)
+	SendSuccessResponse(w, http.StatusCreated, "API key created successfully; store the key now, it is not shown again", created, nil)
+}
+
+// Update changes the name, scopes, IP allowlist and expiry of a key; the key
+// itself and its owner stay the same
+func (h *APIKeyHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	_, key := h.decodeKey(w, r)
+	if key == nil {
+		return
+	}
+	key.ID = id
+
+	existing := h.findKey(w, id)
+	if existing == nil {
+		return
+	}
+	if existing.RevokedAt != nil {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Revoked API key cannot be changed", "")
+		return
+	}
+	if h.findOwner(w, r, existing.UserID) == nil {
+		return
+	}
+
+	if err := h.apiKeyService.Update(key); err != nil {
+		if err.Error() == "api key not found" {
+			SendErrorResponse(w, http.StatusNotFound, "API key not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update API key", err.Error())
+		return
+	}
+
+	result := h.findKey(w, id)
+	if result == nil {
+		return
+	}
+
+	recordAudit(h.auditService, r, "update", "api_key", result.ID, result.Nama, existing, result)
+	SendSuccessResponse(w, http.StatusOK, "API key updated successfully", result, nil)
+}
+
+// Revoke stops a key from working immediately; it stays listed
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	existing := h.findKey(w, id)
+	if existing == nil {
+		return
+	}
+	if existing.RevokedAt != nil {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "API key is already revoked", "")
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(id); err != nil {
+		if err.Error() == "api key not found" {
+			SendErrorResponse(w, http.StatusNotFound, "API key not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke API key", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "revoke", "api_key", existing.ID, existing.Nama, existing, nil)
+	SendSuccessResponse(w, http.StatusOK, "API key revoked successfully", nil, nil)
+}
+
+// decodeKey reads and validates an API key request, sending an error
+// response and returning nil when it is invalid. Scopes are de-duplicated
+// and must all exist and be held by the caller; allowed IPs must be
+// addresses or CIDR ranges
+func (h *APIKeyHandler) decodeKey(w http.ResponseWriter, r *http.Request) (*models.APIKeyRequest, *models.APIKey) {
+	var req models.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return nil, nil
+	}
+
+	// Validate input
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Nama == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama is required", "")
+		return nil, nil
+	}
+	if len(req.Nama) > 100 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama cannot be longer than 100 characters", "")
+		return nil, nil
+	}
+	if len(req.Scopes) == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "At least one scope is required", "")
+		return nil, nil
+	}
+
+	catalog, err := h.roleService.GetPermissions()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get permissions", err.Error())
+		return nil, nil
+	}
+	known := make(map[string]bool, len(catalog))
+	for _, p := range catalog {
+		known[p.Kode] = true
+	}
+
+	seen := make(map[string]bool)
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !known[scope] {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Unknown scope", scope)
+			return nil, nil
+		}
+		if !middleware.HasPermission(r.Context(), scope) {
+			SendErrorResponse(w, http.StatusForbidden, "You cannot grant a scope you do not have", scope)
+			return nil, nil
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	allowedIPs := make([]string, 0, len(req.AllowedIPs))
+	for _, entry := range req.AllowedIPs {
+		entry = strings.TrimSpace(entry)
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			allowedIPs = append(allowedIPs, network.String())
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			allowedIPs = append(allowedIPs, ip.String())
+			continue
+		}
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Allowed IPs must be IP addresses or CIDR ranges", entry)
+		return nil, nil
+	}
+
+	return &req, &models.APIKey{
+		Nama:       req.Nama,
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+	}
+}
+
+// findOwner returns the user a key belongs to, sending an error response and
+// returning nil when they do not exist or their role grants a permission the
+// caller does not have; otherwise api_key:manage would let anyone act as admin
+func (h *APIKeyHandler) findOwner(w http.ResponseWriter, r *http.Request, userID int) *models.User {
+	owner, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get user", err.Error())
+		return nil
+	}
+	if owner == nil {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "User not found", "")
+		return nil
+	}
+
+	permissions, err := h.roleService.RolePermissions(owner.Role)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get permissions", err.Error())
+		return nil
+	}
+	for permission, granted := range permissions {
+		if granted && !middleware.HasPermission(r.Context(), permission) {
+			SendErrorResponse(w, http.StatusForbidden, "You cannot manage API keys of a user with more permissions than you", "")
+			return nil
+		}
+	}
+
+	return owner
+}
+
+// findKey sends an error response and returns nil unless API key id exists
+func (h *APIKeyHandler) findKey(w http.ResponseWriter, id int) *models.APIKey {
+	key, err := h.apiKeyService.GetByID(id)
+	if err != nil {
+		if err.Error() == "api key not found" {
+			SendErrorResponse(w, http.StatusNotFound, "API key not found", "")
+			return nil
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get API key", err.Error())
+		return nil
+	}
+
+	return key
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/services"
+)
+
+type AuditHandler struct {
+	auditService services.AuditService
+}
+
+func NewAuditHandler(auditService services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAll lists audit entries, newest first, filtered by user_id, entity_type,
+// entity_id, entity_label, action and an inclusive from/to date range
+func (h *AuditHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	filter := &models.AuditFilter{
+		EntityType:  query.Get("entity_type"),
+		EntityLabel: query.Get("entity_label"),
+		Action:      query.Get("action"),
+		From:        query.Get("from"),
+		To:          query.Get("to"),
+	}
+	for name, value := range map[string]*int{"user_id": &filter.UserID, "entity_id": &filter.EntityID} {
+		if raw := query.Get(name); raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				SendErrorResponse(w, http.StatusBadRequest, "Invalid "+name, err.Error())
+				return
+			}
+			*value = id
+		}
+	}
+	for name, value := range map[string]string{"from": filter.From, "to": filter.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, "Invalid "+name+" date", "dates must be YYYY-MM-DD")
+			return
+		}
+	}
+
+	entries, total, err := h.auditService.GetAll(filter, limit, offset)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get audit log", err.Error())
+		return
+	}
+
+	meta := &models.Meta{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Audit log retrieved successfully", entries, meta)
+}
+
+// recordAudit writes an audit entry for a change the current user has just
+// made. The change is already committed, so a failure is logged instead of
+// failing the request. entityID 0 and an empty label are stored as NULL
+func recordAudit(auditService services.AuditService, r *http.Request, action, entityType string,
+	entityID int, label string, before, after interface{}) {
+	entry := &models.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+	}
+	if claims, err := middleware.GetUserFromContext(r.Context()); err == nil {
+		entry.UserID = &claims.UserID
+		entry.Username = &claims.Username
+	}
+	if entityID != 0 {
+		entry.EntityID = &entityID
+	}
+	if label != "" {
+		entry.EntityLabel = &label
+	}
+	ip := middleware.ClientIP(r)
+	entry.IPAddress = &ip
+
+	if err := auditService.Record(entry, before, after); err != nil {
+		log.Printf("Failed to record audit %s %s %d: %v", action, entityType, entityID, err)
+	}
+}
@@ -2,25 +2,70 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"warehouse-api/middleware"
 	"warehouse-api/models"
 	"warehouse-api/repositories"
+	"warehouse-api/services"
 
 	"github.com/gorilla/mux"
 )
 
 type BarangHandler struct {
-	barangRepo repositories.BarangRepository
+	barangRepo    repositories.BarangRepository
+	kategoriRepo  repositories.KategoriRepository
+	barangService services.BarangService
+	fileService   services.FileService
+	auditService  services.AuditService
 }
 
-func NewBarangHandler(barangRepo repositories.BarangRepository) *BarangHandler {
-	return &BarangHandler{barangRepo: barangRepo}
+func NewBarangHandler(barangRepo repositories.BarangRepository, kategoriRepo repositories.KategoriRepository,
+	barangService services.BarangService, fileService services.FileService, auditService services.AuditService) *BarangHandler {
+	return &BarangHandler{barangRepo: barangRepo, kategoriRepo: kategoriRepo, barangService: barangService,
+		fileService: fileService, auditService: auditService}
+}
+
+// barangStatus reads the status filter, defaulting to active barang only so
+// archived ones stay out of pickers
+func barangStatus(r *http.Request) (string, bool) {
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+		return models.BarangStatusActive, true
+	case models.BarangStatusActive, models.BarangStatusArchived, models.BarangStatusAll:
+		return status, true
+	default:
+		return "", false
+	}
 }
 
 func (h *BarangHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	search := r.URL.Query().Get("search")
+	kategoriID, _ := strconv.Atoi(r.URL.Query().Get("kategori_id"))
+	status, ok := barangStatus(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid status", "status must be active, archived or all")
+		return
+	}
+	format, ok := exportFormat(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid format", "format must be json, csv or xlsx")
+		return
+	}
+	if format != "" {
+		columns := []string{"kode_barang", "nama_barang", "kategori", "satuan", "harga_beli", "harga_jual", "is_kit", "archived_at", "created_at", "updated_at"}
+		SendExportResponse(w, format, "barang", columns, func(write func(values ...interface{}) error) error {
+			return h.barangRepo.ForEach(search, kategoriID, status, func(b models.Barang) error {
+				return write(b.KodeBarang, b.NamaBarang, b.Kategori, b.Satuan, b.HargaBeli, b.HargaJual,
+					b.IsKit, b.ArchivedAt, b.CreatedAt, b.UpdatedAt)
+			})
+		})
+		return
+	}
+
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 
@@ -33,7 +78,7 @@ func (h *BarangHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 
 	offset := (page - 1) * limit
 
-	barangs, total, err := h.barangRepo.FindAll(search, limit, offset)
+	barangs, total, err := h.barangRepo.FindAll(search, kategoriID, status, limit, offset)
 	if err != nil {
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
 		return
@@ -50,6 +95,28 @@ func (h *BarangHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 
 func (h *BarangHandler) GetAllWithStok(w http.ResponseWriter, r *http.Request) {
 	search := r.URL.Query().Get("search")
+	kategoriID, _ := strconv.Atoi(r.URL.Query().Get("kategori_id"))
+	status, ok := barangStatus(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid status", "status must be active, archived or all")
+		return
+	}
+	format, ok := exportFormat(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid format", "format must be json, csv or xlsx")
+		return
+	}
+	if format != "" {
+		columns := []string{"kode_barang", "nama_barang", "kategori", "satuan", "harga_beli", "harga_jual", "is_kit", "qty_masuk", "qty_keluar", "qty_akhir"}
+		SendExportResponse(w, format, "barang-stok", columns, func(write func(values ...interface{}) error) error {
+			return h.barangRepo.ForEachWithStok(search, kategoriID, status, func(b models.BarangWithStok) error {
+				return write(b.KodeBarang, b.NamaBarang, b.Kategori, b.Satuan, b.HargaBeli, b.HargaJual,
+					b.IsKit, b.QtyMasuk, b.QtyKeluar, b.StokAkhir)
+			})
+		})
+		return
+	}
+
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 
@@ -62,7 +129,7 @@ func (h *BarangHandler) GetAllWithStok(w http.ResponseWriter, r *http.Request) {
 
 	offset := (page - 1) * limit
 
-	barangs, total, err := h.barangRepo.FindAllWithStok(search, limit, offset)
+	barangs, total, err := h.barangRepo.FindAllWithStok(search, kategoriID, status, limit, offset)
 	if err != nil {
 		log.Printf("Error in GetAllWithStok: %v", err)
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
@@ -112,20 +179,31 @@ func (h *BarangHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.checkKategori(w, req.KategoriID) {
+		return
+	}
+
 	barang := &models.Barang{
 		KodeBarang: req.KodeBarang,
 		NamaBarang: req.NamaBarang,
 		Kategori:   req.Kategori,
+		KategoriID: req.KategoriID,
 		Satuan:     req.Satuan,
 		HargaBeli:  req.HargaBeli,
 		HargaJual:  req.HargaJual,
+		IsKit:      req.IsKit,
 	}
 
 	if err := h.barangRepo.Create(barang); err != nil {
+		if strings.HasSuffix(err.Error(), " already exists as a barcode") {
+			SendErrorResponse(w, http.StatusConflict, "Kode barang already exists as a barcode", err.Error())
+			return
+		}
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create barang", err.Error())
 		return
 	}
 
+	recordAudit(h.auditService, r, "create", "barang", barang.ID, barang.KodeBarang, nil, barang)
 	SendSuccessResponse(w, http.StatusCreated, "Barang created successfully", barang, nil)
 }
 
@@ -160,24 +238,72 @@ func (h *BarangHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Prices can only be changed with barang:update_price
+	priceChanged := req.HargaBeli != existing.HargaBeli || req.HargaJual != existing.HargaJual
+	if priceChanged && !middleware.HasPermission(r.Context(), "barang:update_price") {
+		SendErrorResponse(w, http.StatusForbidden, "Insufficient permissions to change prices", "")
+		return
+	}
+
+	if !h.checkKategori(w, req.KategoriID) {
+		return
+	}
+
 	barang := &models.Barang{
 		ID:         id,
 		KodeBarang: existing.KodeBarang,
 		NamaBarang: req.NamaBarang,
 		Kategori:   req.Kategori,
+		KategoriID: req.KategoriID,
 		Satuan:     req.Satuan,
 		HargaBeli:  req.HargaBeli,
 		HargaJual:  req.HargaJual,
+		IsKit:      existing.IsKit,
+	}
+
+	// Keep the linked kategori when only the name was sent back unchanged
+	if barang.KategoriID == nil && barang.Kategori == existing.Kategori {
+		barang.KategoriID = existing.KategoriID
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
 	}
 
-	if err := h.barangRepo.Update(barang); err != nil {
+	if err := h.barangService.UpdateBarang(barang, claims.UserID); err != nil {
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update barang", err.Error())
 		return
 	}
 
+	barang.ArchivedAt = existing.ArchivedAt
+	barang.CreatedAt = existing.CreatedAt
+	recordAudit(h.auditService, r, "update", "barang", barang.ID, barang.KodeBarang, existing, barang)
 	SendSuccessResponse(w, http.StatusOK, "Barang updated successfully", barang, nil)
 }
 
+// checkKategori rejects a kategori_id that does not exist; it writes the error
+// response and returns false when the request must stop
+func (h *BarangHandler) checkKategori(w http.ResponseWriter, kategoriID *int) bool {
+	if kategoriID == nil {
+		return true
+	}
+
+	if _, err := h.kategoriRepo.FindByID(*kategoriID); err != nil {
+		if err.Error() == "kategori not found" {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Kategori not found", "")
+			return false
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+		return false
+	}
+
+	return true
+}
+
+// Delete permanently removes a barang that has never been transacted
 func (h *BarangHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -187,7 +313,7 @@ func (h *BarangHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if barang exists
-	_, err = h.barangRepo.FindByID(id)
+	existing, err := h.barangRepo.FindByID(id)
 	if err != nil {
 		if err.Error() == "barang not found" {
 			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
@@ -197,10 +323,285 @@ func (h *BarangHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The image rows cascade with the barang, so their files are looked up first
+	gambars, err := h.fileService.ListGambar(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get gambar", err.Error())
+		return
+	}
+
 	if err := h.barangRepo.Delete(id); err != nil {
-		SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete barang", err.Error())
+		switch err.Error() {
+		case "barang not found":
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+		case "barang has transactions":
+			SendErrorResponse(w, http.StatusConflict, "Barang has transactions and cannot be deleted; archive it instead", "")
+		case "barang is used by a kit or bom":
+			SendErrorResponse(w, http.StatusConflict, "Barang is used by a kit or BOM and cannot be deleted; archive it instead", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete barang", err.Error())
+		}
 		return
 	}
+	h.fileService.DeleteGambarFiles(gambars)
 
+	recordAudit(h.auditService, r, "delete", "barang", existing.ID, existing.KodeBarang, existing, nil)
 	SendSuccessResponse(w, http.StatusOK, "Barang deleted successfully", nil, nil)
 }
+
+// Archive hides a barang from pickers and blocks new purchases and sales of it;
+// its stock history and documents are kept
+func (h *BarangHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	h.setArchived(w, r, id, "archive", func() error { return h.barangRepo.Archive(id, claims.UserID) },
+		"Barang archived successfully")
+}
+
+func (h *BarangHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	h.setArchived(w, r, id, "restore", func() error { return h.barangRepo.Restore(id) },
+		"Barang restored successfully")
+}
+
+// setArchived runs an archive or restore of barang id and responds with the
+// updated barang
+func (h *BarangHandler) setArchived(w http.ResponseWriter, r *http.Request, id int, action string,
+	apply func() error, message string) {
+	// Check if barang exists
+	existing, err := h.barangRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return
+	}
+
+	if err := apply(); err != nil {
+		switch err.Error() {
+		case "barang is already archived":
+			SendErrorResponse(w, http.StatusConflict, "Barang is already archived", "")
+		case "barang is not archived":
+			SendErrorResponse(w, http.StatusConflict, "Barang is not archived", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to update barang", err.Error())
+		}
+		return
+	}
+
+	barang, err := h.barangRepo.FindByID(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, action, "barang", barang.ID, barang.KodeBarang, existing, barang)
+	SendSuccessResponse(w, http.StatusOK, message, barang, nil)
+}
+
+func (h *BarangHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	histories, total, err := h.barangService.GetPriceHistory(id, limit, offset)
+	if err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price history", err.Error())
+		return
+	}
+
+	meta := &models.Meta{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Price history retrieved successfully", histories, meta)
+}
+
+func (h *BarangHandler) GetPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	jadwals, err := h.barangService.GetPriceSchedules(id)
+	if err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price schedule", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Price schedule retrieved successfully", jadwals, nil)
+}
+
+func (h *BarangHandler) SchedulePriceChange(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.CreateJadwalHargaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.BerlakuMulai == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Berlaku mulai is required", "")
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	jadwal, err := h.barangService.SchedulePriceChange(id, &req, claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "barang not found":
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+		case "harga cannot be negative", "invalid berlaku_mulai format", "berlaku_mulai must be in the future":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid price schedule", err.Error())
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to schedule price change", err.Error())
+		}
+		return
+	}
+
+	recordAudit(h.auditService, r, "schedule_price", "barang", id, "", nil, jadwal)
+	SendSuccessResponse(w, http.StatusCreated, "Price change scheduled successfully", jadwal, nil)
+}
+
+func (h *BarangHandler) CancelPriceSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	jadwalID, err := strconv.Atoi(vars["jadwal_id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid jadwal ID", err.Error())
+		return
+	}
+
+	if err := h.barangService.CancelPriceSchedule(id, jadwalID); err != nil {
+		if err.Error() == "pending jadwal harga not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Pending price schedule not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to cancel price schedule", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "cancel_price_schedule", "barang", id, "", map[string]int{"jadwal_id": jadwalID}, nil)
+	SendSuccessResponse(w, http.StatusOK, "Price schedule cancelled successfully", nil, nil)
+}
+
+// maxImportFileSize bounds an uploaded barang import file
+const maxImportFileSize = 10 << 20
+
+// Import validates a CSV or XLSX file of barang uploaded as the multipart field
+// "file". With mode=commit valid files are written; otherwise (mode=dry_run,
+// the default) only the per-row report is returned
+func (h *BarangHandler) Import(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "dry_run"
+	}
+	if mode != "dry_run" && mode != "commit" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "mode must be dry_run or commit", "")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "File is required", err.Error())
+		return
+	}
+	defer file.Close()
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	result, err := h.barangService.ImportBarang(header.Filename, file, mode == "commit", claims.UserID)
+	if err != nil {
+		if fileErr, ok := err.(*services.ImportFileError); ok {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid import file", fileErr.Error())
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to import barang", err.Error())
+		return
+	}
+
+	if mode == "commit" && !result.Committed {
+		SendErrorResponseWithData(w, http.StatusUnprocessableEntity, "Import has invalid rows, nothing was saved",
+			fmt.Sprintf("%d of %d rows have errors", result.ErrorRows, result.TotalRows), result)
+		return
+	}
+
+	message := "Import validated successfully"
+	if result.Committed {
+		message = "Barang imported successfully"
+		summary := map[string]interface{}{"file": header.Filename, "created": result.Created, "updated": result.Updated}
+		recordAudit(h.auditService, r, "import", "barang", 0, "", nil, summary)
+	}
+	SendSuccessResponse(w, http.StatusOK, message, result, nil)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+	"warehouse-api/services"
+
+	"github.com/gorilla/mux"
+)
+
+type BarcodeHandler struct {
+	barcodeRepo  repositories.BarcodeRepository
+	barangRepo   repositories.BarangRepository
+	auditService services.AuditService
+}
+
+func NewBarcodeHandler(barcodeRepo repositories.BarcodeRepository, barangRepo repositories.BarangRepository,
+	auditService services.AuditService) *BarcodeHandler {
+	return &BarcodeHandler{barcodeRepo: barcodeRepo, barangRepo: barangRepo, auditService: auditService}
+}
+
+// Lookup resolves a scanned code to its barang with current stock
+func (h *BarcodeHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimSpace(mux.Vars(r)["code"])
+	if code == "" {
+		SendErrorResponse(w, http.StatusBadRequest, "Barcode is required", "")
+		return
+	}
+
+	status, ok := barangStatus(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid status", "status must be active, archived or all")
+		return
+	}
+
+	barang, err := h.barangRepo.FindByBarcode(code)
+	if err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return
+	}
+
+	// Scanning is for picking barang, so archived ones are only returned
+	// when asked for
+	if barang.ArchivedAt != nil && status == models.BarangStatusActive {
+		SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived",
+			fmt.Sprintf("barang %s is archived", barang.KodeBarang), "BARANG_ARCHIVED")
+		return
+	}
+	if barang.ArchivedAt == nil && status == models.BarangStatusArchived {
+		SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+		return
+	}
+
+	withStok, err := h.barangRepo.FindWithStokByID(barang.ID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return
+	}
+
+	barcodes, err := h.barcodeRepo.FindByBarangID(barang.ID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barcode", err.Error())
+		return
+	}
+
+	result := models.BarangScan{
+		BarangWithStok: *withStok,
+		Barcodes:       barcodes,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Barang retrieved successfully", result, nil)
+}
+
+func (h *BarcodeHandler) GetBarcodes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if _, err := h.barangRepo.FindByID(id); err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return
+	}
+
+	barcodes, err := h.barcodeRepo.FindByBarangID(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barcode", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Barcode retrieved successfully", barcodes, nil)
+}
+
+// SetBarcodes replaces every barcode of a barang; an empty list removes them all
+func (h *BarcodeHandler) SetBarcodes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.SetBarcodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	barang, err := h.barangRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return
+	}
+
+	// Validate barcodes
+	seen := make(map[string]bool)
+	barcodes := make([]string, 0, len(req.Barcodes))
+	for _, code := range req.Barcodes {
+		code = strings.TrimSpace(code)
+		if code == "" || len(code) > 50 {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Barcode must be 1-50 characters", "")
+			return
+		}
+		if seen[code] {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Barcode must be unique", code)
+			return
+		}
+		if isNumeric(code) && len(code) == 13 && !validEAN13(code) {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid EAN-13 check digit", code)
+			return
+		}
+		seen[code] = true
+		barcodes = append(barcodes, code)
+	}
+
+	before, err := h.barcodeRepo.FindByBarangID(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barcode", err.Error())
+		return
+	}
+
+	result, err := h.barcodeRepo.SetBarcodes(id, barcodes)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "barcode ") && strings.HasSuffix(err.Error(), " already exists") {
+			SendErrorResponse(w, http.StatusConflict, "Barcode already exists", err.Error())
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update barcode", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "update_barcode", "barang", id, barang.KodeBarang, before, result)
+	SendSuccessResponse(w, http.StatusOK, "Barcode updated successfully", result, nil)
+}
+
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validEAN13 checks the GS1 check digit of a 13-digit code
+func validEAN13(code string) bool {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(code[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10-sum%10)%10 == int(code[12]-'0')
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+type BOMHandler struct {
+	bomRepo    repositories.BOMRepository
+	barangRepo repositories.BarangRepository
+}
+
+func NewBOMHandler(bomRepo repositories.BOMRepository, barangRepo repositories.BarangRepository) *BOMHandler {
+	return &BOMHandler{bomRepo: bomRepo, barangRepo: barangRepo}
+}
+
+// buildDetails validates BOM lines and sends an error response (returning
+// false) when they are empty, duplicated, self-referencing or use a kit or
+// an archived barang
+func (h *BOMHandler) buildDetails(w http.ResponseWriter, barangID int, items []models.CreateBOMDetailRequest) ([]models.BOMDetail, bool) {
+	if len(items) == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Details cannot be empty", "")
+		return nil, false
+	}
+
+	seen := make(map[int]bool)
+	details := make([]models.BOMDetail, 0, len(items))
+	for _, item := range items {
+		if item.Qty < 1 {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Komponen qty must be at least 1", "")
+			return nil, false
+		}
+		if item.BarangID == barangID || seen[item.BarangID] {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Komponen must be unique and cannot be the finished barang",
+				fmt.Sprintf("barang_id %d", item.BarangID))
+			return nil, false
+		}
+		seen[item.BarangID] = true
+
+		barang, err := h.barangRepo.FindByID(item.BarangID)
+		if err != nil {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Barang not found",
+				fmt.Sprintf("barang with id %d not found", item.BarangID))
+			return nil, false
+		}
+		if barang.IsKit {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "A kit cannot be a komponen of a BOM",
+				fmt.Sprintf("barang %s is a kit", barang.KodeBarang))
+			return nil, false
+		}
+		if barang.ArchivedAt != nil {
+			SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived",
+				fmt.Sprintf("barang %s is archived", barang.KodeBarang), "BARANG_ARCHIVED")
+			return nil, false
+		}
+
+		details = append(details, models.BOMDetail{
+			KomponenBarangID: item.BarangID,
+			Qty:              item.Qty,
+		})
+	}
+
+	return details, true
+}
+
+func (h *BOMHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	barangID, _ := strconv.Atoi(r.URL.Query().Get("barang_id"))
+
+	boms, err := h.bomRepo.FindAll(barangID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get BOM", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "BOM retrieved successfully", boms, nil)
+}
+
+func (h *BOMHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	bom, err := h.bomRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "bom not found" {
+			SendErrorResponse(w, http.StatusNotFound, "BOM not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get BOM", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "BOM retrieved successfully", bom, nil)
+}
+
+func (h *BOMHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateBOMRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input - kode BOM auto-generate jika kosong
+	if req.BarangID == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "barang_id is required", "")
+		return
+	}
+
+	barang, err := h.barangRepo.FindByID(req.BarangID)
+	if err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Barang not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return
+	}
+	if barang.IsKit {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "A kit cannot be produced", "")
+		return
+	}
+	if barang.ArchivedAt != nil {
+		SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived",
+			fmt.Sprintf("barang %s is archived", barang.KodeBarang), "BARANG_ARCHIVED")
+		return
+	}
+
+	details, ok := h.buildDetails(w, req.BarangID, req.Details)
+	if !ok {
+		return
+	}
+
+	bom := &models.BOMWithDetail{
+		BOM: models.BOM{
+			KodeBOM:    req.KodeBOM,
+			BarangID:   req.BarangID,
+			Keterangan: req.Keterangan,
+		},
+		Details: details,
+	}
+
+	if err := h.bomRepo.Create(bom); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create BOM", err.Error())
+		return
+	}
+
+	result, err := h.bomRepo.FindByID(bom.ID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get BOM", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusCreated, "BOM created successfully", result, nil)
+}
+
+func (h *BOMHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.UpdateBOMRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Check if BOM exists
+	existing, err := h.bomRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "bom not found" {
+			SendErrorResponse(w, http.StatusNotFound, "BOM not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get BOM", err.Error())
+		return
+	}
+
+	details, ok := h.buildDetails(w, existing.BarangID, req.Details)
+	if !ok {
+		return
+	}
+
+	existing.Keterangan = req.Keterangan
+	existing.Details = details
+
+	if err := h.bomRepo.Update(existing); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update BOM", err.Error())
+		return
+	}
+
+	result, err := h.bomRepo.FindByID(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get BOM", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "BOM updated successfully", result, nil)
+}
+
+func (h *BOMHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if err := h.bomRepo.Delete(id); err != nil {
+		switch err.Error() {
+		case "bom not found":
+			SendErrorResponse(w, http.StatusNotFound, "BOM not found", "")
+		case "bom is used by produksi":
+			SendErrorResponse(w, http.StatusConflict, "BOM is used by produksi and cannot be deleted", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete BOM", err.Error())
+		}
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "BOM deleted successfully", nil, nil)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+type CustomerHandler struct {
+	customerRepo  repositories.CustomerRepository
+	priceListRepo repositories.PriceListRepository
+}
+
+func NewCustomerHandler(customerRepo repositories.CustomerRepository,
+	priceListRepo repositories.PriceListRepository) *CustomerHandler {
+	return &CustomerHandler{customerRepo: customerRepo, priceListRepo: priceListRepo}
+}
+
+// validatePriceList sends an error response and returns false if the price list does not exist
+func (h *CustomerHandler) validatePriceList(w http.ResponseWriter, priceListID *int) bool {
+	if priceListID == nil {
+		return true
+	}
+
+	if _, err := h.priceListRepo.FindByID(*priceListID); err != nil {
+		if err.Error() == "price list not found" {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Price list not found", "")
+			return false
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price list", err.Error())
+		return false
+	}
+
+	return true
+}
+
+func (h *CustomerHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	customers, total, err := h.customerRepo.FindAll(search, limit, offset)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get customer", err.Error())
+		return
+	}
+
+	meta := &models.Meta{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Customer retrieved successfully", customers, meta)
+}
+
+func (h *CustomerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	customer, err := h.customerRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "customer not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Customer not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get customer", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Customer retrieved successfully", customer, nil)
+}
+
+func (h *CustomerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input - kode customer auto-generate jika kosong
+	if req.NamaCustomer == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama customer is required", "")
+		return
+	}
+	if req.LimitKredit < 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Limit kredit cannot be negative", "")
+		return
+	}
+	if !h.validatePriceList(w, req.PriceListID) {
+		return
+	}
+
+	customer := &models.Customer{
+		KodeCustomer: req.KodeCustomer,
+		NamaCustomer: req.NamaCustomer,
+		Alamat:       req.Alamat,
+		Telepon:      req.Telepon,
+		NPWP:         req.NPWP,
+		LimitKredit:  req.LimitKredit,
+		PriceListID:  req.PriceListID,
+	}
+
+	if err := h.customerRepo.Create(customer); err != nil {
+		if err.Error() == "customer already exists" {
+			SendErrorResponse(w, http.StatusConflict, "Customer already exists", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create customer", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusCreated, "Customer created successfully", customer, nil)
+}
+
+func (h *CustomerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.UpdateCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.NamaCustomer == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama customer is required", "")
+		return
+	}
+	if req.LimitKredit < 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Limit kredit cannot be negative", "")
+		return
+	}
+	if !h.validatePriceList(w, req.PriceListID) {
+		return
+	}
+
+	// Check if customer exists
+	existing, err := h.customerRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "customer not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Customer not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get customer", err.Error())
+		return
+	}
+
+	customer := &models.Customer{
+		ID:           id,
+		KodeCustomer: existing.KodeCustomer,
+		NamaCustomer: req.NamaCustomer,
+		Alamat:       req.Alamat,
+		Telepon:      req.Telepon,
+		NPWP:         req.NPWP,
+		LimitKredit:  req.LimitKredit,
+		PriceListID:  req.PriceListID,
+		Piutang:      existing.Piutang,
+		CreatedAt:    existing.CreatedAt,
+	}
+
+	if err := h.customerRepo.Update(customer); err != nil {
+		if err.Error() == "customer already exists" {
+			SendErrorResponse(w, http.StatusConflict, "Customer already exists", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update customer", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Customer updated successfully", customer, nil)
+}
+
+func (h *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if err := h.customerRepo.Delete(id); err != nil {
+		switch err.Error() {
+		case "customer not found":
+			SendErrorResponse(w, http.StatusNotFound, "Customer not found", "")
+		case "customer is used by penjualan":
+			SendErrorResponse(w, http.StatusConflict, "Customer is used by penjualan and cannot be deleted", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete customer", err.Error())
+		}
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Customer deleted successfully", nil, nil)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+	"warehouse-api/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DokumenHandler struct {
+	dokumenService services.DokumenService
+	templateRepo   repositories.TemplateDokumenRepository
+}
+
+func NewDokumenHandler(dokumenService services.DokumenService, templateRepo repositories.TemplateDokumenRepository) *DokumenHandler {
+	return &DokumenHandler{dokumenService: dokumenService, templateRepo: templateRepo}
+}
+
+func (h *DokumenHandler) FakturPenjualan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	pdf, filename, err := h.dokumenService.RenderFakturPenjualan(id)
+	if err != nil {
+		switch err.Error() {
+		case "penjualan not found":
+			SendErrorResponse(w, http.StatusNotFound, "Penjualan not found", "")
+		case "template dokumen not found":
+			SendErrorResponse(w, http.StatusNotFound, "Template dokumen not found", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to render faktur", err.Error())
+		}
+		return
+	}
+
+	SendPDFResponse(w, filename, pdf)
+}
+
+// PenerimaanBarang prints the goods receipt of a pembelian; reprints are marked COPY
+func (h *DokumenHandler) PenerimaanBarang(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	pdf, filename, err := h.dokumenService.RenderPenerimaanBarang(id, claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "pembelian not found":
+			SendErrorResponse(w, http.StatusNotFound, "Pembelian not found", "")
+		case "template dokumen not found":
+			SendErrorResponse(w, http.StatusNotFound, "Template dokumen not found", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to render penerimaan barang", err.Error())
+		}
+		return
+	}
+
+	SendPDFResponse(w, filename, pdf)
+}
+
+// SuratJalan prints the delivery note of a penjualan; reprints are marked COPY
+func (h *DokumenHandler) SuratJalan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	pdf, filename, err := h.dokumenService.RenderSuratJalan(id, claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "penjualan not found":
+			SendErrorResponse(w, http.StatusNotFound, "Penjualan not found", "")
+		case "template dokumen not found":
+			SendErrorResponse(w, http.StatusNotFound, "Template dokumen not found", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to render surat jalan", err.Error())
+		}
+		return
+	}
+
+	SendPDFResponse(w, filename, pdf)
+}
+
+func (h *DokumenHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.templateRepo.FindAll()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get template dokumen", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Template dokumen retrieved successfully", templates, nil)
+}
+
+func (h *DokumenHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.templateRepo.FindByJenis(mux.Vars(r)["jenis"])
+	if err != nil {
+		if err.Error() == "template dokumen not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Template dokumen not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get template dokumen", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Template dokumen retrieved successfully", template, nil)
+}
+
+func (h *DokumenHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	jenis := mux.Vars(r)["jenis"]
+
+	var req models.UpdateTemplateDokumenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.Judul == "" || req.NamaPerusahaan == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Judul and nama perusahaan are required", "")
+		return
+	}
+	if req.UkuranKertas == "" {
+		req.UkuranKertas = "A4"
+	}
+	if req.UkuranKertas != "A4" && req.UkuranKertas != "A5" && req.UkuranKertas != "Letter" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Ukuran kertas must be A4, A5 or Letter", "")
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	template := &models.TemplateDokumen{
+		Jenis:              jenis,
+		Judul:              req.Judul,
+		NamaPerusahaan:     req.NamaPerusahaan,
+		Alamat:             req.Alamat,
+		Telepon:            req.Telepon,
+		Email:              req.Email,
+		NPWP:               req.NPWP,
+		Catatan:            req.Catatan,
+		LabelTtdKiri:       req.LabelTtdKiri,
+		LabelTtdKanan:      req.LabelTtdKanan,
+		NamaTtdKanan:       req.NamaTtdKanan,
+		UkuranKertas:       req.UkuranKertas,
+		TampilkanTerbilang: req.TampilkanTerbilang,
+		UpdatedBy:          &claims.UserID,
+	}
+
+	if err := h.templateRepo.Update(template); err != nil {
+		if err.Error() == "template dokumen not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Template dokumen not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update template dokumen", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Template dokumen updated successfully", template, nil)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	contentTypeCSV  = "text/csv"
+	contentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// exportFormat picks the list format from the format query parameter, or else
+// the Accept header: "csv", "xlsx" or "" for the usual JSON page. ok is false
+// for an unknown format parameter
+func exportFormat(r *http.Request) (format string, ok bool) {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "csv":
+		return "csv", true
+	case "xlsx":
+		return "xlsx", true
+	case "json":
+		return "", true
+	case "":
+	default:
+		return "", false
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, contentTypeCSV):
+		return "csv", true
+	case strings.Contains(accept, contentTypeXLSX):
+		return "xlsx", true
+	}
+	return "", true
+}
+
+// exportRows is filled by a list handler: it calls write once per row
+type exportRows func(write func(values ...interface{}) error) error
+
+// SendExportResponse sends every row as a CSV or XLSX attachment named
+// name-YYYYMMDD. CSV rows are streamed as they are read; an XLSX workbook is
+// built in a temporary file and sent once complete
+func SendExportResponse(w http.ResponseWriter, format, name string, columns []string, rows exportRows) {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), format)
+
+	var err error
+	if format == "xlsx" {
+		err = sendXLSX(w, filename, columns, rows)
+	} else {
+		err = sendCSV(w, filename, columns, rows)
+	}
+	if err != nil {
+		log.Printf("Error exporting %s: %v", filename, err)
+	}
+}
+
+// trackingWriter remembers whether the response body has been started
+type trackingWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
+
+func sendCSV(w http.ResponseWriter, filename string, columns []string, rows exportRows) error {
+	w.Header().Set("Content-Type", contentTypeCSV+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	body := &trackingWriter{w: w}
+	writer := csv.NewWriter(body)
+	record := make([]string, len(columns))
+
+	err := writer.Write(columns)
+	if err == nil {
+		err = rows(func(values ...interface{}) error {
+			for i, v := range values {
+				record[i] = exportCSVValue(v)
+			}
+			return writer.Write(record)
+		})
+	}
+	if err == nil {
+		writer.Flush()
+		err = writer.Error()
+	}
+
+	// Once rows have been sent the status can no longer change
+	if err != nil && !body.written {
+		w.Header().Del("Content-Disposition")
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to export data", err.Error())
+	}
+	return err
+}
+
+func sendXLSX(w http.ResponseWriter, filename string, columns []string, rows exportRows) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	err := writeXLSXSheet(f, columns, rows)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to export data", err.Error())
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentTypeXLSX)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	_, err = f.WriteTo(w)
+	return err
+}
+
+func writeXLSXSheet(f *excelize.File, columns []string, rows exportRows) error {
+	sheet := f.GetSheetName(0)
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, c := range columns {
+		header[i] = c
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	row := 1
+	err = rows(func(values ...interface{}) error {
+		row++
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		for i, v := range values {
+			switch v := v.(type) {
+			case string:
+				values[i] = exportText(v)
+			case time.Time:
+				values[i] = exportTime(v)
+			case *time.Time:
+				if v == nil {
+					values[i] = nil
+				} else {
+					values[i] = exportTime(*v)
+				}
+			case *int:
+				if v == nil {
+					values[i] = nil
+				} else {
+					values[i] = *v
+				}
+			}
+		}
+		return sw.SetRow(cell, values)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sw.Flush()
+}
+
+func exportCSVValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return exportText(v)
+	case int:
+		return strconv.Itoa(v)
+	case *int:
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return exportTime(v)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return exportTime(*v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// exportText stops a spreadsheet from reading user-entered text as a formula:
+// text starting with a formula character gets a leading apostrophe
+func exportText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func exportTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// exportDate trims a DATE column scanned as "2006-01-02T00:00:00Z" to its date
+func exportDate(tanggal string) string {
+	if len(tanggal) >= 10 {
+		return tanggal[:10]
+	}
+	return tanggal
+}
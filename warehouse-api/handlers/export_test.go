@@ -0,0 +1,32 @@
+package handlers
+
+import "testing"
+
+func TestExportCSVValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"plain text", "Laptop ASUS", "Laptop ASUS"},
+		{"empty text", "", ""},
+		{"formula", "=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"plus", "+62812", "'+62812"},
+		{"minus", "-1+1", "'-1+1"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"carriage return", "\r=1", "'\r=1"},
+		{"formula character later", "a=b", "a=b"},
+		{"negative number stays a number", -5, "-5"},
+		{"negative float", -2.5, "-2.5"},
+		{"nil", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exportCSVValue(tt.value); got != tt.want {
+				t.Errorf("exportCSVValue(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
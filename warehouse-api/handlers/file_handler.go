@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"warehouse-api/middleware"
+	"warehouse-api/services"
+	"warehouse-api/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// multipartOverhead is allowed on top of the file size limit for the rest of
+// the multipart body
+const multipartOverhead = 1 << 20
+
+// FileHandler serves barang images and pembelian/penjualan attachments. The
+// lampiran routes carry the document type in the {tipe} route variable
+type FileHandler struct {
+	fileService  services.FileService
+	auditService services.AuditService
+}
+
+func NewFileHandler(fileService services.FileService, auditService services.AuditService) *FileHandler {
+	return &FileHandler{fileService: fileService, auditService: auditService}
+}
+
+func (h *FileHandler) GetGambar(w http.ResponseWriter, r *http.Request) {
+	barangID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	gambars, err := h.fileService.ListGambar(barangID)
+	if err != nil {
+		sendFileError(w, "Failed to get gambar", err)
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Gambar retrieved successfully", gambars, nil)
+}
+
+func (h *FileHandler) UploadGambar(w http.ResponseWriter, r *http.Request) {
+	barangID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxGambarSize+multipartOverhead)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		sendFormFileError(w, err, services.MaxGambarSize)
+		return
+	}
+	defer file.Close()
+
+	gambar, err := h.fileService.UploadGambar(barangID, header.Filename, file, claims.UserID)
+	if err != nil {
+		sendFileError(w, "Failed to upload gambar", err)
+		return
+	}
+
+	recordAudit(h.auditService, r, "upload_gambar", "barang", barangID, "", nil, gambar)
+	SendSuccessResponse(w, http.StatusCreated, "Gambar uploaded successfully", gambar, nil)
+}
+
+func (h *FileHandler) DownloadGambar(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	barangID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+	gambarID, err := strconv.Atoi(vars["gambar_id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid gambar ID", err.Error())
+		return
+	}
+
+	thumbnail, _ := strconv.ParseBool(r.URL.Query().Get("thumbnail"))
+	file, gambar, err := h.fileService.OpenGambar(barangID, gambarID, thumbnail)
+	if err != nil {
+		sendFileError(w, "Failed to get gambar", err)
+		return
+	}
+	defer file.Close()
+
+	contentType, filename := gambar.ContentType, gambar.NamaFile
+	if thumbnail {
+		contentType, filename = "image/jpeg", thumbnailName(filename)
+	}
+	download, _ := strconv.ParseBool(r.URL.Query().Get("download"))
+	SendFileResponse(w, contentType, filename, download, file)
+}
+
+func (h *FileHandler) DeleteGambar(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	barangID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+	gambarID, err := strconv.Atoi(vars["gambar_id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid gambar ID", err.Error())
+		return
+	}
+
+	if err := h.fileService.DeleteGambar(barangID, gambarID); err != nil {
+		sendFileError(w, "Failed to delete gambar", err)
+		return
+	}
+
+	recordAudit(h.auditService, r, "delete_gambar", "barang", barangID, "", map[string]int{"gambar_id": gambarID}, nil)
+	SendSuccessResponse(w, http.StatusOK, "Gambar deleted successfully", nil, nil)
+}
+
+func (h *FileHandler) GetLampiran(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	lampirans, err := h.fileService.ListLampiran(vars["tipe"], id)
+	if err != nil {
+		sendFileError(w, "Failed to get lampiran", err)
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Lampiran retrieved successfully", lampirans, nil)
+}
+
+func (h *FileHandler) UploadLampiran(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxLampiranSize+multipartOverhead)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		sendFormFileError(w, err, services.MaxLampiranSize)
+		return
+	}
+	defer file.Close()
+
+	lampiran, err := h.fileService.UploadLampiran(vars["tipe"], id, header.Filename, r.FormValue("keterangan"),
+		file, claims.UserID)
+	if err != nil {
+		sendFileError(w, "Failed to upload lampiran", err)
+		return
+	}
+
+	recordAudit(h.auditService, r, "upload_lampiran", vars["tipe"], id, "", nil, lampiran)
+	SendSuccessResponse(w, http.StatusCreated, "Lampiran uploaded successfully", lampiran, nil)
+}
+
+func (h *FileHandler) DownloadLampiran(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+	lampiranID, err := strconv.Atoi(vars["lampiran_id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid lampiran ID", err.Error())
+		return
+	}
+
+	thumbnail, _ := strconv.ParseBool(r.URL.Query().Get("thumbnail"))
+	file, lampiran, err := h.fileService.OpenLampiran(vars["tipe"], id, lampiranID, thumbnail)
+	if err != nil {
+		sendFileError(w, "Failed to get lampiran", err)
+		return
+	}
+	defer file.Close()
+
+	contentType, filename := lampiran.ContentType, lampiran.NamaFile
+	if thumbnail {
+		contentType, filename = "image/jpeg", thumbnailName(filename)
+	}
+	download, _ := strconv.ParseBool(r.URL.Query().Get("download"))
+	SendFileResponse(w, contentType, filename, download, file)
+}
+
+func (h *FileHandler) DeleteLampiran(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+	lampiranID, err := strconv.Atoi(vars["lampiran_id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid lampiran ID", err.Error())
+		return
+	}
+
+	if err := h.fileService.DeleteLampiran(vars["tipe"], id, lampiranID); err != nil {
+		sendFileError(w, "Failed to delete lampiran", err)
+		return
+	}
+
+	recordAudit(h.auditService, r, "delete_lampiran", vars["tipe"], id, "", map[string]int{"lampiran_id": lampiranID}, nil)
+	SendSuccessResponse(w, http.StatusOK, "Lampiran deleted successfully", nil, nil)
+}
+
+// sendFormFileError reports a missing or oversized multipart file
+func sendFormFileError(w http.ResponseWriter, err error, maxSize int64) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, "File is too large",
+			(&services.FileTooLargeError{MaxSize: maxSize}).Error())
+		return
+	}
+	SendErrorResponse(w, http.StatusBadRequest, "File is required", err.Error())
+}
+
+// sendFileError maps file service errors to responses
+func sendFileError(w http.ResponseWriter, message string, err error) {
+	switch e := err.(type) {
+	case *services.FileTooLargeError:
+		SendErrorResponse(w, http.StatusRequestEntityTooLarge, "File is too large", e.Error())
+		return
+	case *services.UnsupportedFileError:
+		SendErrorResponse(w, http.StatusUnsupportedMediaType, "Unsupported file type", e.Error())
+		return
+	}
+
+	if err == storage.ErrNotFound {
+		SendErrorResponse(w, http.StatusNotFound, "File not found", "")
+		return
+	}
+
+	switch err.Error() {
+	case "barang not found", "pembelian not found", "penjualan not found",
+		"gambar not found", "lampiran not found", "thumbnail not found":
+		SendErrorResponse(w, http.StatusNotFound, strings.ToUpper(err.Error()[:1])+err.Error()[1:], "")
+	default:
+		SendErrorResponse(w, http.StatusInternalServerError, message, err.Error())
+	}
+}
+
+// thumbnailName names a thumbnail after its original file
+func thumbnailName(filename string) string {
+	if i := strings.LastIndex(filename, "."); i > 0 {
+		filename = filename[:i]
+	}
+	return filename + "_thumb.jpg"
+}
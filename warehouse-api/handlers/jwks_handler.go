@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"warehouse-api/middleware"
+)
+
+type JWKSHandler struct {
+	keys *middleware.KeySet
+}
+
+func NewJWKSHandler(keys *middleware.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// Get serves the public signing keys as a plain JWKS document, not wrapped
+// in the usual response, so other services can verify access tokens
+func (h *JWKSHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.keys.JWKS())
+}
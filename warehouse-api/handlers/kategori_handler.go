@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+type KategoriHandler struct {
+	kategoriRepo repositories.KategoriRepository
+}
+
+func NewKategoriHandler(kategoriRepo repositories.KategoriRepository) *KategoriHandler {
+	return &KategoriHandler{kategoriRepo: kategoriRepo}
+}
+
+// GetAll lists every kategori ordered by path, or nested under their parents
+// with ?tree=true
+func (h *KategoriHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	kategoris, err := h.kategoriRepo.FindAll()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+		return
+	}
+
+	if tree, _ := strconv.ParseBool(r.URL.Query().Get("tree")); tree {
+		SendSuccessResponse(w, http.StatusOK, "Kategori retrieved successfully", buildKategoriTree(kategoris), nil)
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Kategori retrieved successfully", kategoris, nil)
+}
+
+// buildKategoriTree nests a path-ordered kategori list; parents always come
+// before their children in that order
+func buildKategoriTree(kategoris []models.Kategori) []*models.KategoriTree {
+	roots := []*models.KategoriTree{}
+	nodes := make(map[int]*models.KategoriTree, len(kategoris))
+
+	for _, k := range kategoris {
+		node := &models.KategoriTree{Kategori: k, Children: []*models.KategoriTree{}}
+		nodes[k.ID] = node
+
+		if k.ParentID == nil {
+			roots = append(roots, node)
+		} else if parent, ok := nodes[*k.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return roots
+}
+
+func (h *KategoriHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	kategori, err := h.kategoriRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "kategori not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Kategori not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Kategori retrieved successfully", kategori, nil)
+}
+
+func (h *KategoriHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateKategoriRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Nama == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama is required", "")
+		return
+	}
+	if len(req.Nama) > 100 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama cannot be longer than 100 characters", "")
+		return
+	}
+
+	if req.ParentID != nil {
+		if _, err := h.kategoriRepo.FindByID(*req.ParentID); err != nil {
+			if err.Error() == "kategori not found" {
+				SendErrorResponse(w, http.StatusUnprocessableEntity, "Parent kategori not found", "")
+				return
+			}
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+			return
+		}
+	}
+
+	kategori := &models.Kategori{
+		Nama:     req.Nama,
+		ParentID: req.ParentID,
+	}
+
+	if err := h.kategoriRepo.Create(kategori); err != nil {
+		if err.Error() == "kategori already exists" {
+			SendErrorResponse(w, http.StatusConflict, "Kategori already exists under this parent", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create kategori", err.Error())
+		return
+	}
+
+	result, err := h.kategoriRepo.FindByID(kategori.ID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusCreated, "Kategori created successfully", result, nil)
+}
+
+func (h *KategoriHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.UpdateKategoriRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Nama == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama is required", "")
+		return
+	}
+	if len(req.Nama) > 100 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama cannot be longer than 100 characters", "")
+		return
+	}
+
+	// Check if kategori exists
+	existing, err := h.kategoriRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "kategori not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Kategori not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+		return
+	}
+
+	// A kategori cannot be moved below itself or one of its descendants
+	if req.ParentID != nil {
+		if _, err := h.kategoriRepo.FindByID(*req.ParentID); err != nil {
+			if err.Error() == "kategori not found" {
+				SendErrorResponse(w, http.StatusUnprocessableEntity, "Parent kategori not found", "")
+				return
+			}
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+			return
+		}
+
+		circular, err := h.kategoriRepo.IsDescendant(*req.ParentID, id)
+		if err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to update kategori", err.Error())
+			return
+		}
+		if circular {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Kategori cannot be moved below itself or its sub-kategori", "")
+			return
+		}
+	}
+
+	existing.Nama = req.Nama
+	existing.ParentID = req.ParentID
+
+	if err := h.kategoriRepo.Update(existing); err != nil {
+		switch err.Error() {
+		case "kategori not found":
+			SendErrorResponse(w, http.StatusNotFound, "Kategori not found", "")
+		case "kategori already exists":
+			SendErrorResponse(w, http.StatusConflict, "Kategori already exists under this parent", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to update kategori", err.Error())
+		}
+		return
+	}
+
+	result, err := h.kategoriRepo.FindByID(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get kategori", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Kategori updated successfully", result, nil)
+}
+
+func (h *KategoriHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if err := h.kategoriRepo.Delete(id); err != nil {
+		switch err.Error() {
+		case "kategori not found":
+			SendErrorResponse(w, http.StatusNotFound, "Kategori not found", "")
+		case "kategori has sub-kategori":
+			SendErrorResponse(w, http.StatusConflict, "Kategori has sub-kategori and cannot be deleted", "")
+		case "kategori is used by barang":
+			SendErrorResponse(w, http.StatusConflict, "Kategori is used by barang and cannot be deleted", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete kategori", err.Error())
+		}
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Kategori deleted successfully", nil, nil)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+	"warehouse-api/services"
+
+	"github.com/gorilla/mux"
+)
+
+type KitHandler struct {
+	kitRepo      repositories.KitRepository
+	barangRepo   repositories.BarangRepository
+	auditService services.AuditService
+}
+
+func NewKitHandler(kitRepo repositories.KitRepository, barangRepo repositories.BarangRepository,
+	auditService services.AuditService) *KitHandler {
+	return &KitHandler{kitRepo: kitRepo, barangRepo: barangRepo, auditService: auditService}
+}
+
+// findKit sends an error response and returns nil unless id refers to a kit barang
+func (h *KitHandler) findKit(w http.ResponseWriter, id int) *models.Barang {
+	barang, err := h.barangRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "barang not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+			return nil
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get barang", err.Error())
+		return nil
+	}
+
+	if !barang.IsKit {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Barang is not a kit", "")
+		return nil
+	}
+
+	return barang
+}
+
+func (h *KitHandler) GetKomponen(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if h.findKit(w, id) == nil {
+		return
+	}
+
+	komponen, err := h.kitRepo.FindKomponen(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get komponen", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Komponen retrieved successfully", komponen, nil)
+}
+
+// SetKomponen replaces the bill of components of a kit
+func (h *KitHandler) SetKomponen(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.SetKomponenKitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	kit := h.findKit(w, id)
+	if kit == nil {
+		return
+	}
+
+	// Validate komponen
+	if len(req.Komponen) == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Komponen cannot be empty", "")
+		return
+	}
+
+	seen := make(map[int]bool)
+	komponen := make([]models.KomponenKit, 0, len(req.Komponen))
+	for _, item := range req.Komponen {
+		if item.Qty < 1 {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Komponen qty must be at least 1", "")
+			return
+		}
+		if item.BarangID == id || seen[item.BarangID] {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Komponen must be unique and cannot be the kit itself",
+				fmt.Sprintf("barang_id %d", item.BarangID))
+			return
+		}
+		seen[item.BarangID] = true
+
+		barang, err := h.barangRepo.FindByID(item.BarangID)
+		if err != nil {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Barang not found",
+				fmt.Sprintf("barang with id %d not found", item.BarangID))
+			return
+		}
+		if barang.IsKit {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "A kit cannot be a komponen of another kit",
+				fmt.Sprintf("barang %s is a kit", barang.KodeBarang))
+			return
+		}
+		if barang.ArchivedAt != nil {
+			SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived",
+				fmt.Sprintf("barang %s is archived", barang.KodeBarang), "BARANG_ARCHIVED")
+			return
+		}
+
+		komponen = append(komponen, models.KomponenKit{
+			KomponenBarangID: item.BarangID,
+			Qty:              item.Qty,
+		})
+	}
+
+	before, err := h.kitRepo.FindKomponen(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get komponen", err.Error())
+		return
+	}
+
+	if err := h.kitRepo.SetKomponen(id, komponen); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update komponen", err.Error())
+		return
+	}
+
+	result, err := h.kitRepo.FindKomponen(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get komponen", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "update_komponen", "barang", id, kit.KodeBarang, before, result)
+	SendSuccessResponse(w, http.StatusOK, "Komponen updated successfully", result, nil)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/services"
+)
+
+type LabelHandler struct {
+	labelService services.LabelService
+}
+
+func NewLabelHandler(labelService services.LabelService) *LabelHandler {
+	return &LabelHandler{labelService: labelService}
+}
+
+// Print renders barcode label sheets as a PDF document
+func (h *LabelHandler) Print(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateLabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if len(req.Items) == 0 && req.PembelianID == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Items or pembelian_id is required", "")
+		return
+	}
+	// Printing a purchase's labels reveals what was received on it
+	if req.PembelianID != 0 && !middleware.HasPermission(r.Context(), "pembelian:read") {
+		SendErrorResponse(w, http.StatusForbidden, "Insufficient permissions to read pembelian", "")
+		return
+	}
+
+	pdf, err := h.labelService.RenderLabels(&req)
+	if err != nil {
+		if labelErr, ok := err.(*services.LabelRequestError); ok {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid label request", labelErr.Error())
+			return
+		}
+		if err.Error() == "pembelian not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Pembelian not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to render labels", err.Error())
+		return
+	}
+
+	SendPDFResponse(w, "label-barang.pdf", pdf)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/services"
+)
+
+// ssoStateCookie ties the IdP callback to the browser that started the login
+const ssoStateCookie = "sso_state"
+
+type OIDCHandler struct {
+	oidcService      services.OIDCService
+	twoFactorService services.TwoFactorService
+	sessionService   services.SessionService
+	auditService     services.AuditService
+	frontendURL      string
+}
+
+func NewOIDCHandler(oidcService services.OIDCService, twoFactorService services.TwoFactorService,
+	sessionService services.SessionService, auditService services.AuditService, frontendURL string) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, twoFactorService: twoFactorService,
+		sessionService: sessionService, auditService: auditService, frontendURL: frontendURL}
+}
+
+// GetConfig tells the login page whether to offer SSO
+func (h *OIDCHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	SendSuccessResponse(w, http.StatusOK, "SSO config retrieved successfully",
+		models.SSOConfigResponse{Enabled: h.oidcService.Enabled()}, nil)
+}
+
+// Login sends the browser to the IdP
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.oidcService.Begin(r.Context())
+	if err != nil {
+		log.Printf("SSO login failed: %v", err)
+		h.redirectError(w, r, ssoErrorCode(err))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    state,
+		Path:     "/api/oidc",
+		MaxAge:   int(services.SSOStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback is where the IdP sends the browser back to. The browser goes on
+// to the login page with a one-time code it exchanges at /login/sso, so the
+// tokens never appear in a URL
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	cookie, err := r.Cookie(ssoStateCookie)
+	http.SetCookie(w, &http.Cookie{Name: ssoStateCookie, Path: "/api/oidc", MaxAge: -1, HttpOnly: true})
+	if err != nil || state == "" || cookie.Value != state {
+		h.redirectError(w, r, "invalid_state")
+		return
+	}
+
+	if idpError := query.Get("error"); idpError != "" {
+		log.Printf("SSO login refused by IdP: %s: %s", idpError, query.Get("error_description"))
+		h.redirectError(w, r, "denied")
+		return
+	}
+
+	login, err := h.oidcService.Complete(r.Context(), state, query.Get("code"))
+	if err != nil {
+		log.Printf("SSO callback failed: %v", err)
+		h.redirectError(w, r, ssoErrorCode(err))
+		return
+	}
+	user := login.User
+
+	if login.Created {
+		recordAudit(h.auditService, r, "sso_provision", "user", user.ID, user.Username, nil, user)
+	}
+	if login.PreviousRole != "" {
+		// Existing sessions still carry the old role
+		if err := h.sessionService.RevokeUserSessions(user.ID, 0); err != nil {
+			log.Printf("SSO callback failed: %v", err)
+			h.redirectError(w, r, "failed")
+			return
+		}
+		recordAudit(h.auditService, r, "sso_role_sync", "user", user.ID, user.Username,
+			map[string]string{"role": login.PreviousRole}, map[string]string{"role": user.Role})
+	}
+
+	challenge, err := h.twoFactorService.CreateChallenge(user, "sso", middleware.ClientIP(r))
+	if err != nil {
+		log.Printf("SSO callback failed: %v", err)
+		h.redirectError(w, r, "failed")
+		return
+	}
+
+	h.redirect(w, r, "sso_code", challenge.ChallengeToken)
+}
+
+func (h *OIDCHandler) redirectError(w http.ResponseWriter, r *http.Request, code string) {
+	h.redirect(w, r, "sso_error", code)
+}
+
+func (h *OIDCHandler) redirect(w http.ResponseWriter, r *http.Request, key, value string) {
+	target := h.frontendURL
+	if strings.Contains(target, "?") {
+		target += "&"
+	} else {
+		target += "?"
+	}
+	http.Redirect(w, r, target+url.Values{key: {value}}.Encode(), http.StatusFound)
+}
+
+// ssoErrorCode is the short reason the login page shows a message for;
+// details stay in the server log
+func ssoErrorCode(err error) string {
+	switch err.Error() {
+	case "sso not configured":
+		return "not_configured"
+	case "invalid sso state":
+		return "invalid_state"
+	case "no role for groups":
+		return "no_role"
+	case "username already exists":
+		return "username_taken"
+	case "user is disabled":
+		return "disabled"
+	default:
+		return "failed"
+	}
+}
+
+func (h *OIDCHandler) GetGroupRoles(w http.ResponseWriter, r *http.Request) {
+	mappings, err := h.oidcService.GetGroupRoles()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get SSO group roles", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "SSO group roles retrieved successfully", mappings, nil)
+}
+
+// SetGroupRoles replaces the group to role mappings. The first mapping that
+// matches one of a user's groups gives their role on their next SSO login
+func (h *OIDCHandler) SetGroupRoles(w http.ResponseWriter, r *http.Request) {
+	var req models.SSOGroupRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	for i := range req.Mappings {
+		req.Mappings[i].GroupName = strings.TrimSpace(req.Mappings[i].GroupName)
+		if req.Mappings[i].GroupName == "" || req.Mappings[i].Role == "" {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Group and role are required", "")
+			return
+		}
+	}
+
+	before, err := h.oidcService.GetGroupRoles()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get SSO group roles", err.Error())
+		return
+	}
+
+	if err := h.oidcService.SetGroupRoles(req.Mappings); err != nil {
+		switch err.Error() {
+		case "duplicate group":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Each group can only be mapped once", "")
+		case "role not found":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Role not found", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to update SSO group roles", err.Error())
+		}
+		return
+	}
+
+	mappings, err := h.oidcService.GetGroupRoles()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get SSO group roles", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "update", "sso_group_role", 0, "", before, mappings)
+	SendSuccessResponse(w, http.StatusOK, "SSO group roles updated successfully", mappings, nil)
+}
+
+// LoginSSO exchanges the one-time code from the SSO callback. From here on
+// the login is the same as after a correct password, 2FA included
+func (h *UserHandler) LoginSSO(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginSSORequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	challenge, user, ip := h.findChallenge(w, r, req.Code, "sso")
+	if challenge == nil {
+		return
+	}
+
+	if err := h.twoFactorService.CompleteChallenge(challenge); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	h.startLogin(w, r, user, ip)
+}
@@ -13,10 +13,11 @@ import (
 
 type PembelianHandler struct {
 	pembelianService services.PembelianService
+	auditService     services.AuditService
 }
 
-func NewPembelianHandler(pembelianService services.PembelianService) *PembelianHandler {
-	return &PembelianHandler{pembelianService: pembelianService}
+func NewPembelianHandler(pembelianService services.PembelianService, auditService services.AuditService) *PembelianHandler {
+	return &PembelianHandler{pembelianService: pembelianService, auditService: auditService}
 }
 
 func (h *PembelianHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -27,8 +28,8 @@ func (h *PembelianHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate input - no faktur sudah auto-generate
-	if req.Tanggal == "" || req.Supplier == "" {
-		SendErrorResponse(w, http.StatusUnprocessableEntity, "Tanggal and supplier are required", "")
+	if req.Tanggal == "" || req.SupplierID == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Tanggal and supplier_id are required", "")
 		return
 	}
 
@@ -46,14 +47,34 @@ func (h *PembelianHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.pembelianService.CreatePembelian(&req, claims.UserID)
 	if err != nil {
+		if archivedErr, ok := err.(*services.BarangArchivedError); ok {
+			SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived", archivedErr.Error(), "BARANG_ARCHIVED")
+			return
+		}
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create pembelian", err.Error())
 		return
 	}
 
+	recordAudit(h.auditService, r, "create", "pembelian", result.ID, result.NoFaktur, nil, result)
 	SendSuccessResponse(w, http.StatusCreated, "Pembelian created successfully", result, nil)
 }
 
 func (h *PembelianHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid format", "format must be json, csv or xlsx")
+		return
+	}
+	if format != "" {
+		columns := []string{"no_faktur", "tanggal", "supplier", "total", "keterangan", "created_at"}
+		SendExportResponse(w, format, "pembelian", columns, func(write func(values ...interface{}) error) error {
+			return h.pembelianService.ForEachPembelian(func(p models.BeliHeader) error {
+				return write(p.NoFaktur, exportDate(p.Tanggal), p.Supplier, p.Total, p.Keterangan, p.CreatedAt)
+			})
+		})
+		return
+	}
+
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 
@@ -13,10 +13,11 @@ import (
 
 type PenjualanHandler struct {
 	penjualanService services.PenjualanService
+	auditService     services.AuditService
 }
 
-func NewPenjualanHandler(penjualanService services.PenjualanService) *PenjualanHandler {
-	return &PenjualanHandler{penjualanService: penjualanService}
+func NewPenjualanHandler(penjualanService services.PenjualanService, auditService services.AuditService) *PenjualanHandler {
+	return &PenjualanHandler{penjualanService: penjualanService, auditService: auditService}
 }
 
 func (h *PenjualanHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -27,8 +28,8 @@ func (h *PenjualanHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate input - no faktur sudah auto-generate
-	if req.Tanggal == "" || req.Customer == "" {
-		SendErrorResponse(w, http.StatusUnprocessableEntity, "Tanggal and customer are required", "")
+	if req.Tanggal == "" || req.CustomerID == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Tanggal and customer_id are required", "")
 		return
 	}
 
@@ -44,6 +45,12 @@ func (h *PenjualanHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pushing a customer over their credit limit needs its own permission
+	if req.OverrideLimitKredit && !middleware.HasPermission(r.Context(), "penjualan:override_credit_limit") {
+		SendErrorResponse(w, http.StatusForbidden, "Insufficient permissions to override credit limit", "")
+		return
+	}
+
 	result, err := h.penjualanService.CreatePenjualan(&req, claims.UserID)
 	if err != nil {
 		// Check if it's an insufficient stock error
@@ -51,14 +58,40 @@ func (h *PenjualanHandler) Create(w http.ResponseWriter, r *http.Request) {
 			SendErrorResponseWithCode(w, http.StatusBadRequest, "Insufficient stock", insufficientErr.Error(), "INSUFFICIENT_STOCK")
 			return
 		}
+		// Check if it's a credit limit error
+		if creditErr, ok := err.(*services.CreditLimitExceededError); ok {
+			SendErrorResponseWithCode(w, http.StatusBadRequest, "Credit limit exceeded", creditErr.Error(), "CREDIT_LIMIT_EXCEEDED")
+			return
+		}
+		if archivedErr, ok := err.(*services.BarangArchivedError); ok {
+			SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived", archivedErr.Error(), "BARANG_ARCHIVED")
+			return
+		}
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create penjualan", err.Error())
 		return
 	}
 
+	recordAudit(h.auditService, r, "create", "penjualan", result.ID, result.NoFaktur, nil, result)
 	SendSuccessResponse(w, http.StatusCreated, "Penjualan created successfully", result, nil)
 }
 
 func (h *PenjualanHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid format", "format must be json, csv or xlsx")
+		return
+	}
+	if format != "" {
+		columns := []string{"no_faktur", "tanggal", "customer", "total", "terbayar", "sisa", "keterangan", "created_at"}
+		SendExportResponse(w, format, "penjualan", columns, func(write func(values ...interface{}) error) error {
+			return h.penjualanService.ForEachPenjualan(func(p models.JualHeader) error {
+				return write(p.NoFaktur, exportDate(p.Tanggal), p.Customer, p.Total, p.Terbayar, p.Total-p.Terbayar,
+					p.Keterangan, p.CreatedAt)
+			})
+		})
+		return
+	}
+
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 
@@ -106,3 +139,77 @@ func (h *PenjualanHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	SendSuccessResponse(w, http.StatusOK, "Penjualan retrieved successfully", penjualan, nil)
 }
+
+func (h *PenjualanHandler) CreatePembayaran(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.CreatePembayaranRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.Tanggal == "" || req.Jumlah <= 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Tanggal and jumlah are required", "")
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	pembayaran, err := h.penjualanService.CreatePembayaran(id, &req, claims.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "penjualan not found":
+			SendErrorResponse(w, http.StatusNotFound, "Penjualan not found", "")
+		case "pembayaran exceeds outstanding amount":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Pembayaran exceeds outstanding amount", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to create pembayaran", err.Error())
+		}
+		return
+	}
+
+	recordAudit(h.auditService, r, "create_pembayaran", "penjualan", id, "", nil, pembayaran)
+	SendSuccessResponse(w, http.StatusCreated, "Pembayaran created successfully", pembayaran, nil)
+}
+
+// ResolveHarga returns the price CreatePenjualan would auto-fill for a customer, barang and qty
+func (h *PenjualanHandler) ResolveHarga(w http.ResponseWriter, r *http.Request) {
+	customerID, _ := strconv.Atoi(r.URL.Query().Get("customer_id"))
+	barangID, _ := strconv.Atoi(r.URL.Query().Get("barang_id"))
+	qty, _ := strconv.Atoi(r.URL.Query().Get("qty"))
+
+	if customerID == 0 || barangID == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "customer_id and barang_id are required", "")
+		return
+	}
+	if qty < 1 {
+		qty = 1
+	}
+
+	resolved, err := h.penjualanService.ResolveHarga(customerID, barangID, qty)
+	if err != nil {
+		switch err.Error() {
+		case "customer not found":
+			SendErrorResponse(w, http.StatusNotFound, "Customer not found", "")
+		case "barang not found":
+			SendErrorResponse(w, http.StatusNotFound, "Barang not found", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to resolve harga", err.Error())
+		}
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Harga resolved successfully", resolved, nil)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+type PriceListHandler struct {
+	priceListRepo repositories.PriceListRepository
+	barangRepo    repositories.BarangRepository
+}
+
+func NewPriceListHandler(priceListRepo repositories.PriceListRepository,
+	barangRepo repositories.BarangRepository) *PriceListHandler {
+	return &PriceListHandler{priceListRepo: priceListRepo, barangRepo: barangRepo}
+}
+
+func (h *PriceListHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	priceLists, err := h.priceListRepo.FindAll()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price list", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Price list retrieved successfully", priceLists, nil)
+}
+
+func (h *PriceListHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	priceList, err := h.priceListRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "price list not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Price list not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price list", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Price list retrieved successfully", priceList, nil)
+}
+
+func (h *PriceListHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreatePriceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.KodePriceList == "" || req.NamaPriceList == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Kode and nama price list are required", "")
+		return
+	}
+
+	priceList := &models.PriceList{
+		KodePriceList: req.KodePriceList,
+		NamaPriceList: req.NamaPriceList,
+		Keterangan:    req.Keterangan,
+	}
+
+	if err := h.priceListRepo.Create(priceList); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create price list", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusCreated, "Price list created successfully", priceList, nil)
+}
+
+func (h *PriceListHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.UpdatePriceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.NamaPriceList == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama price list is required", "")
+		return
+	}
+
+	// Check if price list exists
+	existing, err := h.priceListRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "price list not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Price list not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price list", err.Error())
+		return
+	}
+
+	priceList := &models.PriceList{
+		ID:            id,
+		KodePriceList: existing.KodePriceList,
+		NamaPriceList: req.NamaPriceList,
+		Keterangan:    req.Keterangan,
+		CreatedAt:     existing.CreatedAt,
+	}
+
+	if err := h.priceListRepo.Update(priceList); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update price list", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Price list updated successfully", priceList, nil)
+}
+
+func (h *PriceListHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if err := h.priceListRepo.Delete(id); err != nil {
+		switch err.Error() {
+		case "price list not found":
+			SendErrorResponse(w, http.StatusNotFound, "Price list not found", "")
+		case "price list is used by customer":
+			SendErrorResponse(w, http.StatusConflict, "Price list is assigned to customers and cannot be deleted", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete price list", err.Error())
+		}
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Price list deleted successfully", nil, nil)
+}
+
+// SetItems replaces all barang prices and quantity breaks of a price list
+func (h *PriceListHandler) SetItems(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.SetPriceListItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate items
+	seen := make(map[string]bool)
+	items := make([]models.PriceListItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		if item.MinQty == 0 {
+			item.MinQty = 1
+		}
+		if item.MinQty < 1 || item.Harga < 0 {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Min qty must be at least 1 and harga cannot be negative", "")
+			return
+		}
+
+		key := fmt.Sprintf("%d/%d", item.BarangID, item.MinQty)
+		if seen[key] {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Duplicate price for the same barang and min qty",
+				fmt.Sprintf("barang_id %d, min_qty %d", item.BarangID, item.MinQty))
+			return
+		}
+		seen[key] = true
+
+		if _, err := h.barangRepo.FindByID(item.BarangID); err != nil {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Barang not found",
+				fmt.Sprintf("barang with id %d not found", item.BarangID))
+			return
+		}
+
+		items = append(items, models.PriceListItem{
+			BarangID: item.BarangID,
+			MinQty:   item.MinQty,
+			Harga:    item.Harga,
+		})
+	}
+
+	// Check if price list exists
+	if _, err := h.priceListRepo.FindByID(id); err != nil {
+		if err.Error() == "price list not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Price list not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price list", err.Error())
+		return
+	}
+
+	if err := h.priceListRepo.SetItems(id, items); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update price list items", err.Error())
+		return
+	}
+
+	priceList, err := h.priceListRepo.FindByID(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get price list", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Price list items updated successfully", priceList, nil)
+}
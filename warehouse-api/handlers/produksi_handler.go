@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/services"
+
+	"github.com/gorilla/mux"
+)
+
+type ProduksiHandler struct {
+	produksiService services.ProduksiService
+}
+
+func NewProduksiHandler(produksiService services.ProduksiService) *ProduksiHandler {
+	return &ProduksiHandler{produksiService: produksiService}
+}
+
+func (h *ProduksiHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateProduksiRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input - no produksi auto-generate
+	if req.Tanggal == "" || req.BomID == 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Tanggal and bom_id are required", "")
+		return
+	}
+
+	if req.QtyRencana < 1 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "qty_rencana must be at least 1", "")
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	result, err := h.produksiService.CreateProduksi(&req, claims.UserID)
+	if err != nil {
+		if archivedErr, ok := err.(*services.BarangArchivedError); ok {
+			SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived", archivedErr.Error(), "BARANG_ARCHIVED")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create produksi", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusCreated, "Produksi created successfully", result, nil)
+}
+
+func (h *ProduksiHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	produksis, total, err := h.produksiService.GetAllProduksi(status, limit, offset)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get produksi", err.Error())
+		return
+	}
+
+	meta := &models.Meta{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Produksi retrieved successfully", produksis, meta)
+}
+
+func (h *ProduksiHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	produksi, err := h.produksiService.GetProduksiByID(id)
+	if err != nil {
+		if err.Error() == "produksi not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Produksi not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get produksi", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Produksi retrieved successfully", produksi, nil)
+}
+
+// CreateRealisasi records finished and scrapped quantities against a production order
+func (h *ProduksiHandler) CreateRealisasi(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.CreateRealisasiRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.Tanggal == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Tanggal is required", "")
+		return
+	}
+
+	// Get user from context
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	result, err := h.produksiService.CreateRealisasi(id, &req, claims.UserID)
+	if err != nil {
+		// Check if it's an insufficient stock error
+		if insufficientErr, ok := err.(*services.InsufficientStockError); ok {
+			SendErrorResponseWithCode(w, http.StatusBadRequest, "Insufficient stock", insufficientErr.Error(), "INSUFFICIENT_STOCK")
+			return
+		}
+		if archivedErr, ok := err.(*services.BarangArchivedError); ok {
+			SendErrorResponseWithCode(w, http.StatusUnprocessableEntity, "Barang is archived", archivedErr.Error(), "BARANG_ARCHIVED")
+			return
+		}
+		h.sendProduksiError(w, err, "Failed to create realisasi")
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusCreated, "Realisasi created successfully", result, nil)
+}
+
+func (h *ProduksiHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	result, err := h.produksiService.CancelProduksi(id)
+	if err != nil {
+		h.sendProduksiError(w, err, "Failed to cancel produksi")
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Produksi cancelled successfully", result, nil)
+}
+
+// sendProduksiError maps validation errors from the produksi service to HTTP statuses
+func (h *ProduksiHandler) sendProduksiError(w http.ResponseWriter, err error, message string) {
+	msg := err.Error()
+	switch {
+	case msg == "produksi not found":
+		SendErrorResponse(w, http.StatusNotFound, "Produksi not found", "")
+	case strings.HasPrefix(msg, "produksi is already"):
+		SendErrorResponse(w, http.StatusConflict, msg, "")
+	case strings.HasPrefix(msg, "qty"):
+		SendErrorResponse(w, http.StatusUnprocessableEntity, msg, "")
+	default:
+		SendErrorResponse(w, http.StatusInternalServerError, message, err.Error())
+	}
+}
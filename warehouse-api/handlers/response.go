@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"warehouse-api/models"
 )
 
@@ -49,3 +53,41 @@ func SendErrorResponseWithCode(w http.ResponseWriter, status int, message string
 
 	json.NewEncoder(w).Encode(response)
 }
+
+// SendErrorResponseWithData sends a standardized error response with a report in data
+func SendErrorResponseWithData(w http.ResponseWriter, status int, message string, error string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := models.ErrorResponse{
+		Success: false,
+		Message: message,
+		Error:   error,
+		Data:    data,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// SendPDFResponse sends a rendered PDF document for inline display
+func SendPDFResponse(w http.ResponseWriter, filename string, data []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// SendFileResponse streams a stored file, inline unless download is set
+func SendFileResponse(w http.ResponseWriter, contentType, filename string, download bool, file io.Reader) {
+	disposition := "inline"
+	if download {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
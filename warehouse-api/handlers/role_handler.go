@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/services"
+
+	"github.com/gorilla/mux"
+)
+
+type RoleHandler struct {
+	roleService      services.RoleService
+	twoFactorService services.TwoFactorService
+	sessionService   services.SessionService
+	auditService     services.AuditService
+}
+
+func NewRoleHandler(roleService services.RoleService, twoFactorService services.TwoFactorService,
+	sessionService services.SessionService, auditService services.AuditService) *RoleHandler {
+	return &RoleHandler{roleService: roleService, twoFactorService: twoFactorService,
+		sessionService: sessionService, auditService: auditService}
+}
+
+func (h *RoleHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.GetAll()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get roles", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Roles retrieved successfully", roles, nil)
+}
+
+// GetPermissions lists every permission a role can be granted
+func (h *RoleHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.roleService.GetPermissions()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get permissions", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Permissions retrieved successfully", permissions, nil)
+}
+
+func (h *RoleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	role := h.findRole(w, id)
+	if role == nil {
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Role retrieved successfully", role, nil)
+}
+
+func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	role := h.decodeRole(w, r)
+	if role == nil {
+		return
+	}
+
+	if err := h.roleService.Create(role); err != nil {
+		if err.Error() == "role already exists" {
+			SendErrorResponse(w, http.StatusConflict, "Role already exists", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create role", err.Error())
+		return
+	}
+
+	result := h.findRole(w, role.ID)
+	if result == nil {
+		return
+	}
+
+	recordAudit(h.auditService, r, "create", "role", result.ID, result.Nama, nil, result)
+	SendSuccessResponse(w, http.StatusCreated, "Role created successfully", result, nil)
+}
+
+func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	role := h.decodeRole(w, r)
+	if role == nil {
+		return
+	}
+	role.ID = id
+
+	existing := h.findRole(w, id)
+	if existing == nil {
+		return
+	}
+	if existing.IsSystem {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "System role cannot be changed", "")
+		return
+	}
+
+	if err := h.roleService.Update(role); err != nil {
+		switch err.Error() {
+		case "role not found":
+			SendErrorResponse(w, http.StatusNotFound, "Role not found", "")
+		case "role already exists":
+			SendErrorResponse(w, http.StatusConflict, "Role already exists", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to update role", err.Error())
+		}
+		return
+	}
+
+	result := h.findRole(w, id)
+	if result == nil {
+		return
+	}
+
+	recordAudit(h.auditService, r, "update", "role", result.ID, result.Nama, existing, result)
+	SendSuccessResponse(w, http.StatusOK, "Role updated successfully", result, nil)
+}
+
+func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	existing := h.findRole(w, id)
+	if existing == nil {
+		return
+	}
+	if existing.IsSystem {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "System role cannot be deleted", "")
+		return
+	}
+
+	if err := h.roleService.Delete(id); err != nil {
+		switch err.Error() {
+		case "role not found":
+			SendErrorResponse(w, http.StatusNotFound, "Role not found", "")
+		case "role is used by users":
+			SendErrorResponse(w, http.StatusConflict, "Role is used by users and cannot be deleted", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete role", err.Error())
+		}
+		return
+	}
+
+	recordAudit(h.auditService, r, "delete", "role", existing.ID, existing.Nama, existing, nil)
+	SendSuccessResponse(w, http.StatusOK, "Role deleted successfully", nil, nil)
+}
+
+// SetRequire2FA turns the 2FA requirement of a role on or off; this works
+// on the admin role too. Turning it on logs out the role's users who have
+// not enabled 2FA, so they enrol when they log in again
+func (h *RoleHandler) SetRequire2FA(w http.ResponseWriter, r *http.Request) {
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.RoleTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	existing := h.findRole(w, id)
+	if existing == nil {
+		return
+	}
+
+	var unenrolled []int
+	if req.Require2FA {
+		if unenrolled, err = h.twoFactorService.UsersWithout2FA(existing.Nama); err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to get users", err.Error())
+			return
+		}
+		// Requiring 2FA of your own role before you use it would log you out
+		for _, userID := range unenrolled {
+			if userID == claims.UserID {
+				SendErrorResponse(w, http.StatusUnprocessableEntity,
+					"Enable two-factor authentication on your own account first", "")
+				return
+			}
+		}
+	}
+
+	if err := h.roleService.SetRequire2FA(id, req.Require2FA); err != nil {
+		if err.Error() == "role not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Role not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update role", err.Error())
+		return
+	}
+
+	for _, userID := range unenrolled {
+		if err := h.sessionService.RevokeUserSessions(userID, 0); err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+			return
+		}
+	}
+
+	result := h.findRole(w, id)
+	if result == nil {
+		return
+	}
+
+	recordAudit(h.auditService, r, "set_require_2fa", "role", result.ID, result.Nama, existing, result)
+	SendSuccessResponse(w, http.StatusOK, "Role updated successfully", result, nil)
+}
+
+// decodeRole reads and validates a role request, sending an error response
+// and returning nil when it is invalid. Permissions are de-duplicated and
+// must all exist
+func (h *RoleHandler) decodeRole(w http.ResponseWriter, r *http.Request) *models.Role {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return nil
+	}
+
+	// Validate input
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Nama == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama is required", "")
+		return nil
+	}
+	if len(req.Nama) > 50 || strings.ContainsAny(req.Nama, " \t\r\n") {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama must be at most 50 characters without spaces", "")
+		return nil
+	}
+	if len(req.Deskripsi) > 255 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Deskripsi cannot be longer than 255 characters", "")
+		return nil
+	}
+
+	catalog, err := h.roleService.GetPermissions()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get permissions", err.Error())
+		return nil
+	}
+	known := make(map[string]bool, len(catalog))
+	for _, p := range catalog {
+		known[p.Kode] = true
+	}
+
+	seen := make(map[string]bool)
+	permissions := make([]string, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		if !known[p] {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Unknown permission", p)
+			return nil
+		}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+
+	return &models.Role{
+		Nama:        req.Nama,
+		Deskripsi:   req.Deskripsi,
+		Permissions: permissions,
+	}
+}
+
+// findRole sends an error response and returns nil unless role id exists
+func (h *RoleHandler) findRole(w http.ResponseWriter, id int) *models.Role {
+	role, err := h.roleService.GetByID(id)
+	if err != nil {
+		if err.Error() == "role not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Role not found", "")
+			return nil
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get role", err.Error())
+		return nil
+	}
+
+	return role
+}
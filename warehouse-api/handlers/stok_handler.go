@@ -1,23 +1,45 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"warehouse-api/middleware"
 	"warehouse-api/models"
 	"warehouse-api/repositories"
+	"warehouse-api/services"
 
 	"github.com/gorilla/mux"
 )
 
 type StokHandler struct {
-	stokRepo repositories.StokRepository
+	stokRepo    repositories.StokRepository
+	stokService services.StokService
 }
 
-func NewStokHandler(stokRepo repositories.StokRepository) *StokHandler {
-	return &StokHandler{stokRepo: stokRepo}
+func NewStokHandler(stokRepo repositories.StokRepository, stokService services.StokService) *StokHandler {
+	return &StokHandler{stokRepo: stokRepo, stokService: stokService}
 }
 
 func (h *StokHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid format", "format must be json, csv or xlsx")
+		return
+	}
+	if format != "" {
+		columns := []string{"kode_barang", "nama_barang", "satuan", "stok_awal", "stok_masuk", "stok_keluar", "stok_akhir", "updated_at"}
+		SendExportResponse(w, format, "stok", columns, func(write func(values ...interface{}) error) error {
+			return h.stokRepo.ForEach(func(s models.StokWithBarang) error {
+				return write(s.KodeBarang, s.NamaBarang, s.Satuan, s.StokAwal, s.StokMasuk, s.StokKeluar,
+					s.StokAkhir, s.UpdatedAt)
+			})
+		})
+		return
+	}
+
 	stoks, err := h.stokRepo.FindAll()
 	if err != nil {
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get stock", err.Error())
@@ -50,6 +72,22 @@ func (h *StokHandler) GetByBarangID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *StokHandler) GetHistoryAll(w http.ResponseWriter, r *http.Request) {
+	format, ok := exportFormat(r)
+	if !ok {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid format", "format must be json, csv or xlsx")
+		return
+	}
+	if format != "" {
+		columns := []string{"created_at", "kode_barang", "nama_barang", "jenis_transaksi", "qty", "stok_sebelum", "stok_sesudah", "harga", "keterangan", "referensi_tipe", "referensi_id"}
+		SendExportResponse(w, format, "history-stok", columns, func(write func(values ...interface{}) error) error {
+			return h.stokRepo.ForEachHistory(func(hs models.HistoryStokWithBarang) error {
+				return write(hs.CreatedAt, hs.KodeBarang, hs.NamaBarang, hs.JenisTransaksi, hs.Qty, hs.StokSebelum,
+					hs.StokSesudah, hs.Harga, hs.Keterangan, hs.ReferensiTipe, hs.ReferensiID)
+			})
+		})
+		return
+	}
+
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 
@@ -111,3 +149,63 @@ func (h *StokHandler) GetHistoryByBarangID(w http.ResponseWriter, r *http.Reques
 
 	SendSuccessResponse(w, http.StatusOK, "Stock history retrieved successfully", histories, meta)
 }
+
+// ImportStokAwal sets opening balances from a JSON body or from a CSV/XLSX file
+// uploaded as the multipart field "file" (with force as a form field)
+func (h *StokHandler) ImportStokAwal(w http.ResponseWriter, r *http.Request) {
+	var req models.ImportStokAwalRequest
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			SendErrorResponse(w, http.StatusBadRequest, "File is required", err.Error())
+			return
+		}
+		defer file.Close()
+
+		req.Items, err = h.stokService.ReadStokAwalFile(header.Filename, file)
+		if err != nil {
+			if fileErr, ok := err.(*services.ImportFileError); ok {
+				SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid import file", fileErr.Error())
+				return
+			}
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to read file", err.Error())
+			return
+		}
+		req.Force, _ = strconv.ParseBool(r.FormValue("force"))
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Resetting the opening balance of a barang with transactions needs its own permission
+	if req.Force && !middleware.HasPermission(r.Context(), "stok:force_awal") {
+		SendErrorResponse(w, http.StatusForbidden, "Insufficient permissions to force an opening balance", "")
+		return
+	}
+
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	result, err := h.stokService.ImportStokAwal(&req, claims.UserID)
+	if err != nil {
+		if fileErr, ok := err.(*services.ImportFileError); ok {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid opening stock", fileErr.Error())
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to import opening stock", err.Error())
+		return
+	}
+
+	if !result.Committed {
+		SendErrorResponseWithData(w, http.StatusUnprocessableEntity, "Opening stock has invalid rows, nothing was saved",
+			fmt.Sprintf("%d of %d rows have errors", result.ErrorRows, result.TotalRows), result)
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Opening stock imported successfully", result, nil)
+}
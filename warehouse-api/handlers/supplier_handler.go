@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/gorilla/mux"
+)
+
+type SupplierHandler struct {
+	supplierRepo repositories.SupplierRepository
+}
+
+func NewSupplierHandler(supplierRepo repositories.SupplierRepository) *SupplierHandler {
+	return &SupplierHandler{supplierRepo: supplierRepo}
+}
+
+func (h *SupplierHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	suppliers, total, err := h.supplierRepo.FindAll(search, limit, offset)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get supplier", err.Error())
+		return
+	}
+
+	meta := &models.Meta{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Supplier retrieved successfully", suppliers, meta)
+}
+
+func (h *SupplierHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	supplier, err := h.supplierRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Supplier not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get supplier", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Supplier retrieved successfully", supplier, nil)
+}
+
+func (h *SupplierHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateSupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input - kode supplier auto-generate jika kosong
+	if req.NamaSupplier == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama supplier is required", "")
+		return
+	}
+	if req.TerminPembayaran < 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Termin pembayaran cannot be negative", "")
+		return
+	}
+
+	supplier := &models.Supplier{
+		KodeSupplier:     req.KodeSupplier,
+		NamaSupplier:     req.NamaSupplier,
+		Alamat:           req.Alamat,
+		Telepon:          req.Telepon,
+		NPWP:             req.NPWP,
+		TerminPembayaran: req.TerminPembayaran,
+	}
+
+	if err := h.supplierRepo.Create(supplier); err != nil {
+		if err.Error() == "supplier already exists" {
+			SendErrorResponse(w, http.StatusConflict, "Supplier already exists", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to create supplier", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusCreated, "Supplier created successfully", supplier, nil)
+}
+
+func (h *SupplierHandler) Update(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.UpdateSupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.NamaSupplier == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama supplier is required", "")
+		return
+	}
+	if req.TerminPembayaran < 0 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Termin pembayaran cannot be negative", "")
+		return
+	}
+
+	// Check if supplier exists
+	existing, err := h.supplierRepo.FindByID(id)
+	if err != nil {
+		if err.Error() == "supplier not found" {
+			SendErrorResponse(w, http.StatusNotFound, "Supplier not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get supplier", err.Error())
+		return
+	}
+
+	supplier := &models.Supplier{
+		ID:               id,
+		KodeSupplier:     existing.KodeSupplier,
+		NamaSupplier:     req.NamaSupplier,
+		Alamat:           req.Alamat,
+		Telepon:          req.Telepon,
+		NPWP:             req.NPWP,
+		TerminPembayaran: req.TerminPembayaran,
+		CreatedAt:        existing.CreatedAt,
+	}
+
+	if err := h.supplierRepo.Update(supplier); err != nil {
+		if err.Error() == "supplier already exists" {
+			SendErrorResponse(w, http.StatusConflict, "Supplier already exists", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update supplier", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Supplier updated successfully", supplier, nil)
+}
+
+func (h *SupplierHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if err := h.supplierRepo.Delete(id); err != nil {
+		switch err.Error() {
+		case "supplier not found":
+			SendErrorResponse(w, http.StatusNotFound, "Supplier not found", "")
+		case "supplier is used by pembelian":
+			SendErrorResponse(w, http.StatusConflict, "Supplier is used by pembelian and cannot be deleted", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete supplier", err.Error())
+		}
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Supplier deleted successfully", nil, nil)
+}
@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/services"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoginTwoFactor is the second step of a login for users with 2FA: the
+// challenge token from Login plus a code or a recovery code
+func (h *UserHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Code or recovery code is required", "")
+		return
+	}
+
+	challenge, user, ip := h.findChallenge(w, r, req.ChallengeToken, "verify")
+	if challenge == nil {
+		return
+	}
+	reservation := h.reserveLogin(w, user.Username, ip)
+	if reservation == nil {
+		return
+	}
+	defer h.releaseLogin(reservation)
+
+	if err := h.twoFactorService.Verify(user, req.Code, req.RecoveryCode); err != nil {
+		switch err.Error() {
+		case "invalid two-factor code":
+			h.challengeFailed(w, r, challenge, user, reservation)
+		case "two-factor not enabled":
+			// 2FA was reset since the password step; start over
+			h.twoFactorService.CompleteChallenge(challenge)
+			SendErrorResponse(w, http.StatusUnauthorized, "Invalid or expired login challenge", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to verify code", err.Error())
+		}
+		return
+	}
+
+	if err := h.twoFactorService.CompleteChallenge(challenge); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	if req.Code == "" {
+		recordAudit(h.auditService, r, "use_recovery_code", "user", user.ID, user.Username, nil, nil)
+	}
+	h.completeLogin(w, r, user, ip, nil)
+}
+
+// LoginTwoFactorSetup starts enrolment for a user whose role requires 2FA
+// but who has not set it up, using the challenge token from Login
+func (h *UserHandler) LoginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	challenge, user, _ := h.findChallenge(w, r, req.ChallengeToken, "enroll")
+	if challenge == nil {
+		return
+	}
+
+	h.sendTwoFactorSetup(w, user)
+}
+
+// LoginTwoFactorEnable confirms the enrolment started by
+// LoginTwoFactorSetup and completes the login; the response includes the
+// recovery codes
+func (h *UserHandler) LoginTwoFactorEnable(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Code == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Code is required", "")
+		return
+	}
+
+	challenge, user, ip := h.findChallenge(w, r, req.ChallengeToken, "enroll")
+	if challenge == nil {
+		return
+	}
+	reservation := h.reserveLogin(w, user.Username, ip)
+	if reservation == nil {
+		return
+	}
+	defer h.releaseLogin(reservation)
+
+	codes, err := h.twoFactorService.Enable(user.ID, req.Code)
+	if err != nil {
+		switch err.Error() {
+		case "invalid two-factor code":
+			h.challengeFailed(w, r, challenge, user, reservation)
+		case "two-factor setup not started":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Start two-factor setup first", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err.Error())
+		}
+		return
+	}
+
+	if err := h.twoFactorService.CompleteChallenge(challenge); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "enable_2fa", "user", user.ID, user.Username, nil, nil)
+	h.completeLogin(w, r, user, ip, codes)
+}
+
+// findChallenge loads the login challenge for token and its user, sending
+// an error response and returning nil unless the challenge is valid, has
+// the given purpose, and the user may still log in
+func (h *UserHandler) findChallenge(w http.ResponseWriter, r *http.Request, token, purpose string) (*models.LoginChallenge, *models.User, string) {
+	if token == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Challenge token is required", "")
+		return nil, nil, ""
+	}
+
+	challenge, err := h.twoFactorService.FindChallenge(token)
+	if err != nil {
+		if err.Error() == "invalid login challenge" {
+			SendErrorResponse(w, http.StatusUnauthorized, "Invalid or expired login challenge", "")
+			return nil, nil, ""
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return nil, nil, ""
+	}
+	if challenge.Purpose != purpose {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Login challenge is for "+challenge.Purpose, "")
+		return nil, nil, ""
+	}
+
+	user, err := h.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return nil, nil, ""
+	}
+	if user == nil || !user.IsActive {
+		SendErrorResponse(w, http.StatusForbidden, "User is disabled", "")
+		return nil, nil, ""
+	}
+
+	// Wrong codes count as failed logins, so the same backoff applies
+	ip := middleware.ClientIP(r)
+	wait, err := h.loginLimiter.Check(user.Username, ip)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return nil, nil, ""
+	}
+	if wait > 0 {
+		sendLoginBlocked(w, wait)
+		return nil, nil, ""
+	}
+
+	return challenge, user, ip
+}
+
+// challengeFailed counts a wrong code against the challenge and keeps it
+// counted as a failed login, records it in the audit log and sends 401
+func (h *UserHandler) challengeFailed(w http.ResponseWriter, r *http.Request, challenge *models.LoginChallenge,
+	user *models.User, reservation *services.LoginReservation) {
+	block, err := h.loginLimiter.RecordFailure(reservation)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+	if err := h.twoFactorService.ChallengeFailed(challenge); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "login_2fa_failed", "user", user.ID, user.Username, nil, block)
+	SendErrorResponse(w, http.StatusUnauthorized, "Invalid two-factor code", "")
+}
+
+// GetTwoFactor shows whether the current user has 2FA, whether their role
+// requires it and how many recovery codes are left
+func (h *UserHandler) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := h.currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	status, err := h.twoFactorService.Status(user)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get two-factor status", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Two-factor status retrieved successfully", status, nil)
+}
+
+// SetupTwoFactor returns a new secret for the current user to scan; 2FA is
+// enabled once a code is confirmed with EnableTwoFactor
+func (h *UserHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := h.currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	h.sendTwoFactorSetup(w, user)
+}
+
+func (h *UserHandler) sendTwoFactorSetup(w http.ResponseWriter, user *models.User) {
+	setup, err := h.twoFactorService.Setup(user)
+	if err != nil {
+		if err.Error() == "two-factor already enabled" {
+			SendErrorResponse(w, http.StatusConflict, "Two-factor authentication is already enabled", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to set up two-factor authentication", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Scan the QR code and confirm with a code", setup, nil)
+}
+
+// EnableTwoFactor confirms the setup with a code and returns the recovery
+// codes, which are only shown this once
+func (h *UserHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := h.currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Code == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Code is required", "")
+		return
+	}
+
+	codes, err := h.twoFactorService.Enable(user.ID, req.Code)
+	if err != nil {
+		switch err.Error() {
+		case "invalid two-factor code":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid two-factor code", "")
+		case "two-factor setup not started":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Start two-factor setup first", "")
+		case "two-factor already enabled":
+			SendErrorResponse(w, http.StatusConflict, "Two-factor authentication is already enabled", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err.Error())
+		}
+		return
+	}
+
+	recordAudit(h.auditService, r, "enable_2fa", "user", user.ID, user.Username, nil, nil)
+	SendSuccessResponse(w, http.StatusOK, "Two-factor authentication enabled; store the recovery codes now",
+		models.RecoveryCodesResponse{RecoveryCodes: codes}, nil)
+}
+
+// DisableTwoFactor turns 2FA off for the current user after confirming
+// their password and a code; not allowed when their role requires 2FA
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := h.currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	var req models.TwoFactorDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Two-factor authentication is not enabled", "")
+		return
+	}
+	required, err := h.twoFactorService.IsRequired(user.Role)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to check role", err.Error())
+		return
+	}
+	if required {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Two-factor authentication is required for your role", "")
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Password is incorrect", "")
+		return
+	}
+	if !h.verifyCode(w, user, req.Code, req.RecoveryCode) {
+		return
+	}
+
+	if err := h.twoFactorService.Disable(user.ID); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "disable_2fa", "user", user.ID, user.Username, nil, nil)
+	SendSuccessResponse(w, http.StatusOK, "Two-factor authentication disabled", nil, nil)
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes after
+// confirming a code
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := h.currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Two-factor authentication is not enabled", "")
+		return
+	}
+	if !h.verifyCode(w, user, req.Code, req.RecoveryCode) {
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to generate recovery codes", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "regenerate_recovery_codes", "user", user.ID, user.Username, nil, nil)
+	SendSuccessResponse(w, http.StatusOK, "Recovery codes generated; store them now",
+		models.RecoveryCodesResponse{RecoveryCodes: codes}, nil)
+}
+
+// ResetTwoFactor turns 2FA off for a user who lost their device and logs
+// them out; if their role requires 2FA they enrol again at the next login
+func (h *UserHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	user := h.findUser(w, id)
+	if user == nil {
+		return
+	}
+	if !user.TwoFactorEnabled && user.TOTPSecret == nil {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Two-factor authentication is not enabled", "")
+		return
+	}
+
+	if err := h.twoFactorService.Disable(id); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to reset two-factor authentication", err.Error())
+		return
+	}
+	if err := h.sessionService.RevokeUserSessions(id, 0); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "reset_2fa", "user", user.ID, user.Username, nil, nil)
+	SendSuccessResponse(w, http.StatusOK, "Two-factor authentication reset successfully", nil, nil)
+}
+
+// verifyCode checks a code or recovery code of the current user, sending
+// 422 and returning false when it is wrong
+func (h *UserHandler) verifyCode(w http.ResponseWriter, user *models.User, code, recoveryCode string) bool {
+	if err := h.twoFactorService.Verify(user, code, recoveryCode); err != nil {
+		if err.Error() == "invalid two-factor code" {
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Invalid two-factor code", "")
+			return false
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to verify code", err.Error())
+		return false
+	}
+	return true
+}
+
+// currentUser loads the logged-in user for the self-service 2FA endpoints,
+// which are not available to API keys
+func (h *UserHandler) currentUser(w http.ResponseWriter, r *http.Request) *models.User {
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return nil
+	}
+	if claims.APIKeyID != 0 {
+		SendErrorResponse(w, http.StatusForbidden, "Not available with an API key", "")
+		return nil
+	}
+
+	user, err := h.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return nil
+	}
+	if user == nil {
+		SendErrorResponse(w, http.StatusNotFound, "User not found", "")
+		return nil
+	}
+
+	return user
+}
@@ -2,20 +2,40 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 	"warehouse-api/middleware"
 	"warehouse-api/models"
 	"warehouse-api/repositories"
+	"warehouse-api/services"
 
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// minPasswordLength applies to new passwords; existing ones are not rechecked
+const minPasswordLength = 8
+
 type UserHandler struct {
-	userRepo repositories.UserRepository
+	userRepo         repositories.UserRepository
+	sessionService   services.SessionService
+	roleService      services.RoleService
+	twoFactorService services.TwoFactorService
+	loginLimiter     services.LoginLimiter
+	auditService     services.AuditService
 }
 
-func NewUserHandler(userRepo repositories.UserRepository) *UserHandler {
-	return &UserHandler{userRepo: userRepo}
+func NewUserHandler(userRepo repositories.UserRepository, sessionService services.SessionService,
+	roleService services.RoleService, twoFactorService services.TwoFactorService, loginLimiter services.LoginLimiter,
+	auditService services.AuditService) *UserHandler {
+	return &UserHandler{userRepo: userRepo, sessionService: sessionService, roleService: roleService,
+		twoFactorService: twoFactorService, loginLimiter: loginLimiter, auditService: auditService}
 }
 
 func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -31,6 +51,14 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse early while the username or IP is backing off or locked
+	ip := middleware.ClientIP(r)
+	reservation := h.reserveLogin(w, req.Username, ip)
+	if reservation == nil {
+		return
+	}
+	defer h.releaseLogin(reservation)
+
 	// Find user
 	user, err := h.userRepo.FindByUsername(req.Username)
 	if err != nil {
@@ -38,30 +66,251 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if user == nil {
-		SendErrorResponse(w, http.StatusUnauthorized, "Invalid username or password", "")
+	// Verify password
+	if user == nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		h.loginFailed(w, r, reservation, req.Username, user)
 		return
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		SendErrorResponse(w, http.StatusUnauthorized, "Invalid username or password", "")
+	if !user.IsActive {
+		SendErrorResponse(w, http.StatusForbidden, "User is disabled", "")
+		return
+	}
+
+	h.startLogin(w, r, user, ip)
+}
+
+// startLogin continues a login once the user is known. Users with 2FA, or
+// whose role requires it, get a challenge instead of tokens. Failures are
+// only cleared once the second step is passed too
+func (h *UserHandler) startLogin(w http.ResponseWriter, r *http.Request, user *models.User, ip string) {
+	purpose := ""
+	if user.TwoFactorEnabled {
+		purpose = "verify"
+	} else {
+		required, err := h.twoFactorService.IsRequired(user.Role)
+		if err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+			return
+		}
+		if required {
+			purpose = "enroll"
+		}
+	}
+	if purpose != "" {
+		challenge, err := h.twoFactorService.CreateChallenge(user, purpose, ip)
+		if err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to start two-factor login", err.Error())
+			return
+		}
+		SendSuccessResponse(w, http.StatusOK, "Two-factor authentication required", challenge, nil)
+		return
+	}
+
+	h.completeLogin(w, r, user, ip, nil)
+}
+
+// completeLogin clears the user's failed logins, starts a session and sends
+// the tokens. recoveryCodes is set when 2FA was enabled during this login
+func (h *UserHandler) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User, ip string, recoveryCodes []string) {
+	if err := h.loginLimiter.RecordSuccess(user.Username); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
 		return
 	}
 
-	// Generate token
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.Role)
+	// Start a session and generate tokens
+	tokens, err := h.sessionService.CreateSession(user, ip, r.UserAgent())
 	if err != nil {
 		SendErrorResponse(w, http.StatusInternalServerError, "Failed to generate token", err.Error())
 		return
 	}
 
+	h.sendLoginResponse(w, "Login successful", tokens, user, recoveryCodes)
+}
+
+// reserveLogin counts an attempt to log in with a password or code against
+// the username and IP before it is checked, or sends 429 when they are
+// blocked. nil means a response has been sent
+func (h *UserHandler) reserveLogin(w http.ResponseWriter, username, ip string) *services.LoginReservation {
+	reservation, wait, err := h.loginLimiter.Reserve(username, ip)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return nil
+	}
+	if reservation == nil {
+		sendLoginBlocked(w, wait)
+		return nil
+	}
+	return reservation
+}
+
+// releaseLogin takes back the attempt unless it was recorded as a failure
+func (h *UserHandler) releaseLogin(reservation *services.LoginReservation) {
+	if err := h.loginLimiter.Release(reservation); err != nil {
+		log.Printf("Failed to release login attempt: %v", err)
+	}
+}
+
+// loginFailed keeps a wrong username or password counted against the
+// username and the IP, records it in the audit log and sends 401
+func (h *UserHandler) loginFailed(w http.ResponseWriter, r *http.Request, reservation *services.LoginReservation,
+	username string, user *models.User) {
+	block, err := h.loginLimiter.RecordFailure(reservation)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	action := "login_failed"
+	if block.Lockout {
+		action = "login_locked"
+	}
+	recordAudit(h.auditService, r, action, "user", userID, username, nil, block)
+
+	SendErrorResponse(w, http.StatusUnauthorized, "Invalid username or password", "")
+}
+
+// sendLoginBlocked sends 429 with a Retry-After header in whole seconds
+func sendLoginBlocked(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	SendErrorResponseWithCode(w, http.StatusTooManyRequests, "Too many failed login attempts",
+		fmt.Sprintf("try again in %d seconds", seconds), "LOGIN_LOCKED")
+}
+
+// GetLoginLocks lists the usernames and IPs currently blocked from logging in
+func (h *UserHandler) GetLoginLocks(w http.ResponseWriter, r *http.Request) {
+	locks, err := h.loginLimiter.GetLocked()
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get login locks", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Login locks retrieved successfully", locks, nil)
+}
+
+// DeleteLoginLock clears the failures of a username or IP given as
+// {tipe}/{value}, e.g. user/staff1 or ip/10.0.0.5
+func (h *UserHandler) DeleteLoginLock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	key := services.LoginIPKey(vars["value"])
+	if vars["tipe"] == "user" {
+		key = services.LoginUsernameKey(vars["value"])
+	}
+
+	if err := h.loginLimiter.Unlock(key); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to unlock", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "unlock_login", "login_lock", 0, key, nil, nil)
+	SendSuccessResponse(w, http.StatusOK, "Login unlocked successfully", nil, nil)
+}
+
+// Unlock clears the failed logins of a user so they can log in right away
+func (h *UserHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	user := h.findUser(w, id)
+	if user == nil {
+		return
+	}
+
+	if err := h.loginLimiter.Unlock(services.LoginUsernameKey(user.Username)); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to unlock user", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "unlock_login", "user", user.ID, user.Username, nil, nil)
+	SendSuccessResponse(w, http.StatusOK, "User unlocked successfully", user, nil)
+}
+
+// Refresh exchanges a refresh token for a new token pair. The old refresh
+// token stops working; using it again ends the session
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.RefreshToken == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Refresh token is required", "")
+		return
+	}
+
+	tokens, user, err := h.sessionService.Refresh(req.RefreshToken)
+	if err != nil {
+		switch err.Error() {
+		case "invalid refresh token", "refresh token expired", "session revoked":
+			SendErrorResponse(w, http.StatusUnauthorized, "Invalid or expired refresh token", "")
+		case "refresh token reused":
+			SendErrorResponse(w, http.StatusUnauthorized, "Refresh token was already used, session revoked", "")
+		case "user is disabled":
+			SendErrorResponse(w, http.StatusUnauthorized, "User is disabled", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", err.Error())
+		}
+		return
+	}
+
+	h.sendLoginResponse(w, "Token refreshed successfully", tokens, user, nil)
+}
+
+// sendLoginResponse returns the tokens together with the user and the
+// permissions of their role, so the client knows what it may show
+func (h *UserHandler) sendLoginResponse(w http.ResponseWriter, message string, tokens *models.TokenPair, user *models.User,
+	recoveryCodes []string) {
+	granted, err := h.roleService.RolePermissions(user.Role)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to load permissions", err.Error())
+		return
+	}
+
+	permissions := make([]string, 0, len(granted))
+	for p := range granted {
+		permissions = append(permissions, p)
+	}
+	sort.Strings(permissions)
+
 	response := models.LoginResponse{
-		Token: token,
-		User:  *user,
+		TokenPair:     *tokens,
+		User:          *user,
+		Permissions:   permissions,
+		RecoveryCodes: recoveryCodes,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, message, response, nil)
+}
+
+// Logout revokes the current session; its access and refresh tokens stop
+// working immediately
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	if claims.APIKeyID != 0 {
+		SendErrorResponse(w, http.StatusForbidden, "Not available with an API key", "")
+		return
 	}
 
-	SendSuccessResponse(w, http.StatusOK, "Login successful", response, nil)
+	if err := h.sessionService.Revoke(claims.SessionID); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to logout", err.Error())
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Logout successful", nil, nil)
 }
 
 func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
@@ -84,3 +333,382 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 
 	SendSuccessResponse(w, http.StatusOK, "Profile retrieved successfully", user, nil)
 }
+
+// ChangePassword lets the current user set a new password after confirming
+// the old one
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		SendErrorResponse(w, http.StatusUnauthorized, "Unauthorized", err.Error())
+		return
+	}
+
+	if claims.APIKeyID != 0 {
+		SendErrorResponse(w, http.StatusForbidden, "Not available with an API key", "")
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	if req.OldPassword == "" || req.NewPassword == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Old password and new password are required", "")
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "New password must be at least 8 characters", "")
+		return
+	}
+
+	user, err := h.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Internal server error", err.Error())
+		return
+	}
+
+	if user == nil {
+		SendErrorResponse(w, http.StatusNotFound, "User not found", "")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)); err != nil {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Old password is incorrect", "")
+		return
+	}
+	if req.NewPassword == req.OldPassword {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "New password must differ from the old password", "")
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to hash password", err.Error())
+		return
+	}
+
+	if err := h.userRepo.UpdatePassword(user.ID, string(hash)); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to change password", err.Error())
+		return
+	}
+
+	// Log out every other device; the current session stays logged in
+	if err := h.sessionService.RevokeUserSessions(user.ID, claims.SessionID); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "change_password", "user", user.ID, user.Username, nil, nil)
+	SendSuccessResponse(w, http.StatusOK, "Password changed successfully", nil, nil)
+}
+
+// GetAll lists users filtered by search (username or nama) and role
+func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("search")
+	role := r.URL.Query().Get("role")
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	users, total, err := h.userRepo.FindAll(search, role, limit, offset)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get users", err.Error())
+		return
+	}
+
+	meta := &models.Meta{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "Users retrieved successfully", users, meta)
+}
+
+func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	user := h.findUser(w, id)
+	if user == nil {
+		return
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "User retrieved successfully", user, nil)
+}
+
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	req.Username = strings.TrimSpace(req.Username)
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Username == "" || req.Password == "" || req.Nama == "" || req.Role == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Username, password, nama and role are required", "")
+		return
+	}
+	if len(req.Username) > 50 || strings.ContainsAny(req.Username, " \t\r\n") {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Username must be at most 50 characters without spaces", "")
+		return
+	}
+	if !validateUser(w, req.Nama, req.Password) {
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to hash password", err.Error())
+		return
+	}
+
+	user := &models.User{
+		Username: req.Username,
+		Password: string(hash),
+		Nama:     req.Nama,
+		Role:     req.Role,
+	}
+
+	if err := h.userRepo.Create(user); err != nil {
+		switch err.Error() {
+		case "username already exists":
+			SendErrorResponse(w, http.StatusConflict, "Username already exists", "")
+		case "role not found":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Role not found", req.Role)
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to create user", err.Error())
+		}
+		return
+	}
+
+	recordAudit(h.auditService, r, "create", "user", user.ID, user.Username, nil, user)
+	SendSuccessResponse(w, http.StatusCreated, "User created successfully", user, nil)
+}
+
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	// Validate input
+	req.Nama = strings.TrimSpace(req.Nama)
+	if req.Nama == "" || req.Role == "" {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama and role are required", "")
+		return
+	}
+	if !validateUser(w, req.Nama, req.Password) {
+		return
+	}
+
+	existing := h.findUser(w, id)
+	if existing == nil {
+		return
+	}
+
+	// An admin cannot demote themselves and lock everyone out of user management
+	if req.Role != existing.Role && h.isCurrentUser(r, id) {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "You cannot change your own role", "")
+		return
+	}
+
+	user := *existing
+	user.Nama = req.Nama
+	user.Role = req.Role
+
+	if err := h.userRepo.Update(&user); err != nil {
+		switch err.Error() {
+		case "user not found":
+			SendErrorResponse(w, http.StatusNotFound, "User not found", "")
+		case "role not found":
+			SendErrorResponse(w, http.StatusUnprocessableEntity, "Role not found", req.Role)
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to update user", err.Error())
+		}
+		return
+	}
+
+	recordAudit(h.auditService, r, "update", "user", user.ID, user.Username, existing, &user)
+
+	// Existing sessions still carry the old role, so a role change logs the
+	// user out; a role that requires 2FA then makes them enrol on next login
+	if user.Role != existing.Role {
+		if err := h.sessionService.RevokeUserSessions(id, 0); err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+			return
+		}
+	}
+
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to hash password", err.Error())
+			return
+		}
+		if err := h.userRepo.UpdatePassword(id, string(hash)); err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to reset password", err.Error())
+			return
+		}
+		if err := h.sessionService.RevokeUserSessions(id, 0); err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+			return
+		}
+		recordAudit(h.auditService, r, "reset_password", "user", user.ID, user.Username, nil, nil)
+	}
+
+	SendSuccessResponse(w, http.StatusOK, "User updated successfully", user, nil)
+}
+
+// Disable blocks a user from logging in while keeping their history
+func (h *UserHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, false, "disable", "User disabled successfully")
+}
+
+func (h *UserHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, true, "enable", "User enabled successfully")
+}
+
+// setActive enables or disables the user in the id route variable and
+// responds with the updated user
+func (h *UserHandler) setActive(w http.ResponseWriter, r *http.Request, active bool, action, message string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if !active && h.isCurrentUser(r, id) {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "You cannot disable your own account", "")
+		return
+	}
+
+	existing := h.findUser(w, id)
+	if existing == nil {
+		return
+	}
+
+	if err := h.userRepo.SetActive(id, active); err != nil {
+		if err.Error() == "user not found" {
+			SendErrorResponse(w, http.StatusNotFound, "User not found", "")
+			return
+		}
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to update user", err.Error())
+		return
+	}
+
+	// A disabled user is logged out everywhere at once
+	if !active {
+		if err := h.sessionService.RevokeUserSessions(id, 0); err != nil {
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+			return
+		}
+	}
+
+	user := h.findUser(w, id)
+	if user == nil {
+		return
+	}
+
+	recordAudit(h.auditService, r, action, "user", user.ID, user.Username, existing, user)
+	SendSuccessResponse(w, http.StatusOK, message, user, nil)
+}
+
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		SendErrorResponse(w, http.StatusBadRequest, "Invalid ID", err.Error())
+		return
+	}
+
+	if h.isCurrentUser(r, id) {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "You cannot delete your own account", "")
+		return
+	}
+
+	existing := h.findUser(w, id)
+	if existing == nil {
+		return
+	}
+
+	if err := h.userRepo.Delete(id); err != nil {
+		switch err.Error() {
+		case "user not found":
+			SendErrorResponse(w, http.StatusNotFound, "User not found", "")
+		case "user has activity":
+			SendErrorResponse(w, http.StatusConflict, "User has activity and cannot be deleted, disable the user instead", "")
+		default:
+			SendErrorResponse(w, http.StatusInternalServerError, "Failed to delete user", err.Error())
+		}
+		return
+	}
+
+	// The sessions went with the user; forget any cached ones
+	if err := h.sessionService.RevokeUserSessions(id, 0); err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", err.Error())
+		return
+	}
+
+	recordAudit(h.auditService, r, "delete", "user", existing.ID, existing.Username, existing, nil)
+	SendSuccessResponse(w, http.StatusOK, "User deleted successfully", nil, nil)
+}
+
+// findUser sends an error response and returns nil unless user id exists
+func (h *UserHandler) findUser(w http.ResponseWriter, id int) *models.User {
+	user, err := h.userRepo.FindByID(id)
+	if err != nil {
+		SendErrorResponse(w, http.StatusInternalServerError, "Failed to get user", err.Error())
+		return nil
+	}
+
+	if user == nil {
+		SendErrorResponse(w, http.StatusNotFound, "User not found", "")
+		return nil
+	}
+
+	return user
+}
+
+// isCurrentUser reports whether id is the user making the request
+func (h *UserHandler) isCurrentUser(r *http.Request, id int) bool {
+	claims, err := middleware.GetUserFromContext(r.Context())
+	return err == nil && claims.UserID == id
+}
+
+// validateUser checks the fields shared by create and update and sends a 422
+// for the first invalid one. An empty password is left to the caller; the
+// role is checked against the role table on save
+func validateUser(w http.ResponseWriter, nama, password string) bool {
+	if len(nama) > 100 {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Nama cannot be longer than 100 characters", "")
+		return false
+	}
+	if password != "" && len(password) < minPasswordLength {
+		SendErrorResponse(w, http.StatusUnprocessableEntity, "Password must be at least 8 characters", "")
+		return false
+	}
+	return true
+}
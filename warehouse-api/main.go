@@ -3,11 +3,13 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 	"warehouse-api/config"
 	"warehouse-api/handlers"
 	"warehouse-api/middleware"
 	"warehouse-api/repositories"
 	"warehouse-api/services"
+	"warehouse-api/storage"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -22,6 +24,24 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Load the token signing keys; the server refuses to start with a
+	// missing, default or weak secret
+	keys, err := middleware.LoadKeySet(middleware.KeyConfig{
+		Secret:                 cfg.JWTSecret,
+		PreviousSecrets:        cfg.JWTPreviousSecrets,
+		PrivateKeyFile:         cfg.JWTPrivateKeyFile,
+		PreviousPublicKeyFiles: cfg.JWTPreviousPublicKeyFiles,
+	})
+	if err != nil {
+		log.Fatal("Invalid JWT configuration: ", err)
+	}
+	middleware.SetKeySet(keys)
+	log.Printf("Signing tokens with %s key %s", keys.Current().Method.Alg(), keys.Current().ID)
+
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+
 	// Initialize database
 	db, err := config.InitDB(cfg)
 	if err != nil {
@@ -29,23 +49,102 @@ func main() {
 	}
 	defer db.Close()
 
+	// Initialize file storage
+	fileStorage, err := storage.NewLocalStorage(cfg.UploadDir)
+	if err != nil {
+		log.Fatal("Failed to initialize file storage:", err)
+	}
+
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
 	barangRepo := repositories.NewBarangRepository(db)
 	stokRepo := repositories.NewStokRepository(db)
 	pembelianRepo := repositories.NewPembelianRepository(db)
 	penjualanRepo := repositories.NewPenjualanRepository(db)
+	supplierRepo := repositories.NewSupplierRepository(db)
+	customerRepo := repositories.NewCustomerRepository(db)
+	priceListRepo := repositories.NewPriceListRepository(db)
+	hargaBarangRepo := repositories.NewHargaBarangRepository(db)
+	kitRepo := repositories.NewKitRepository(db)
+	bomRepo := repositories.NewBOMRepository(db)
+	produksiRepo := repositories.NewProduksiRepository(db)
+	barcodeRepo := repositories.NewBarcodeRepository(db)
+	templateDokumenRepo := repositories.NewTemplateDokumenRepository(db)
+	cetakDokumenRepo := repositories.NewCetakDokumenRepository(db)
+	kategoriRepo := repositories.NewKategoriRepository(db)
+	barangGambarRepo := repositories.NewBarangGambarRepository(db)
+	lampiranRepo := repositories.NewLampiranRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	roleRepo := repositories.NewRoleRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	twoFactorRepo := repositories.NewTwoFactorRepository(db)
+	ssoRepo := repositories.NewSSORepository(db)
+
+	var loginAttemptRepo repositories.LoginAttemptRepository
+	switch cfg.LoginAttemptStore {
+	case "postgres":
+		loginAttemptRepo = repositories.NewLoginAttemptRepository(db)
+	case "memory":
+		loginAttemptRepo = repositories.NewMemoryLoginAttemptRepository()
+	default:
+		log.Fatalf("Invalid LOGIN_ATTEMPT_STORE %q, use memory or postgres", cfg.LoginAttemptStore)
+	}
 
 	// Initialize services
-	pembelianService := services.NewPembelianService(db, pembelianRepo, barangRepo, stokRepo)
-	penjualanService := services.NewPenjualanService(db, penjualanRepo, barangRepo, stokRepo)
+	barangService := services.NewBarangService(db, barangRepo, hargaBarangRepo, auditRepo)
+	pembelianService := services.NewPembelianService(db, pembelianRepo, barangRepo, stokRepo, supplierRepo)
+	penjualanService := services.NewPenjualanService(db, penjualanRepo, barangRepo, stokRepo, customerRepo, priceListRepo, kitRepo)
+	labelService := services.NewLabelService(barangRepo, barcodeRepo, pembelianRepo)
+	dokumenService := services.NewDokumenService(db, pembelianRepo, penjualanRepo, supplierRepo, customerRepo,
+		templateDokumenRepo, cetakDokumenRepo)
+	stokService := services.NewStokService(db, stokRepo, barangRepo, auditRepo)
+	fileService := services.NewFileService(fileStorage, barangGambarRepo, lampiranRepo, barangRepo, pembelianRepo, penjualanRepo)
+	produksiService := services.NewProduksiService(db, produksiRepo, bomRepo, barangRepo, stokRepo, hargaBarangRepo, auditRepo)
+	auditService := services.NewAuditService(auditRepo)
+	sessionService := services.NewSessionService(db, sessionRepo, userRepo, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	roleService := services.NewRoleService(roleRepo)
+	loginLimiter := services.NewLoginLimiter(loginAttemptRepo)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, roleService)
+	twoFactorService := services.NewTwoFactorService(db, twoFactorRepo, userRepo, roleRepo, cfg.TOTPIssuer)
+	oidcService := services.NewOIDCService(ssoRepo, userRepo, services.OIDCConfig{
+		IssuerURL:     cfg.OIDCIssuerURL,
+		ClientID:      cfg.OIDCClientID,
+		ClientSecret:  cfg.OIDCClientSecret,
+		RedirectURL:   cfg.OIDCRedirectURL,
+		Scopes:        cfg.OIDCScopes,
+		GroupsClaim:   cfg.OIDCGroupsClaim,
+		UsernameClaim: cfg.OIDCUsernameClaim,
+		DefaultRole:   cfg.OIDCDefaultRole,
+	})
 
 	// Initialize handlers
-	userHandler := handlers.NewUserHandler(userRepo)
-	barangHandler := handlers.NewBarangHandler(barangRepo)
-	stokHandler := handlers.NewStokHandler(stokRepo)
-	pembelianHandler := handlers.NewPembelianHandler(pembelianService)
-	penjualanHandler := handlers.NewPenjualanHandler(penjualanService)
+	userHandler := handlers.NewUserHandler(userRepo, sessionService, roleService, twoFactorService, loginLimiter,
+		auditService)
+	barangHandler := handlers.NewBarangHandler(barangRepo, kategoriRepo, barangService, fileService, auditService)
+	stokHandler := handlers.NewStokHandler(stokRepo, stokService)
+	pembelianHandler := handlers.NewPembelianHandler(pembelianService, auditService)
+	penjualanHandler := handlers.NewPenjualanHandler(penjualanService, auditService)
+	supplierHandler := handlers.NewSupplierHandler(supplierRepo)
+	kategoriHandler := handlers.NewKategoriHandler(kategoriRepo)
+	customerHandler := handlers.NewCustomerHandler(customerRepo, priceListRepo)
+	priceListHandler := handlers.NewPriceListHandler(priceListRepo, barangRepo)
+	kitHandler := handlers.NewKitHandler(kitRepo, barangRepo, auditService)
+	bomHandler := handlers.NewBOMHandler(bomRepo, barangRepo)
+	produksiHandler := handlers.NewProduksiHandler(produksiService)
+	barcodeHandler := handlers.NewBarcodeHandler(barcodeRepo, barangRepo, auditService)
+	labelHandler := handlers.NewLabelHandler(labelService)
+	dokumenHandler := handlers.NewDokumenHandler(dokumenService, templateDokumenRepo)
+	fileHandler := handlers.NewFileHandler(fileService, auditService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	jwksHandler := handlers.NewJWKSHandler(keys)
+	roleHandler := handlers.NewRoleHandler(roleService, twoFactorService, sessionService, auditService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, userRepo, roleService, auditService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, twoFactorService, sessionService, auditService,
+		cfg.OIDCFrontendURL)
+
+	// Apply scheduled price changes in the background
+	services.StartPriceScheduler(barangService, time.Minute)
 
 	// Setup router
 	r := mux.NewRouter()
@@ -58,6 +157,15 @@ func main() {
 
 	// Public routes (no authentication)
 	api.HandleFunc("/login", userHandler.Login).Methods("POST", "OPTIONS")
+	api.HandleFunc("/login/2fa", userHandler.LoginTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/login/2fa/setup", userHandler.LoginTwoFactorSetup).Methods("POST", "OPTIONS")
+	api.HandleFunc("/login/2fa/enable", userHandler.LoginTwoFactorEnable).Methods("POST", "OPTIONS")
+	api.HandleFunc("/login/sso", userHandler.LoginSSO).Methods("POST", "OPTIONS")
+	api.HandleFunc("/oidc/config", oidcHandler.GetConfig).Methods("GET", "OPTIONS")
+	api.HandleFunc("/oidc/login", oidcHandler.Login).Methods("GET")
+	api.HandleFunc("/oidc/callback", oidcHandler.Callback).Methods("GET")
+	api.HandleFunc("/refresh", userHandler.Refresh).Methods("POST", "OPTIONS")
+	api.HandleFunc("/.well-known/jwks.json", jwksHandler.Get).Methods("GET", "OPTIONS")
 	api.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ok"}`))
@@ -65,38 +173,163 @@ func main() {
 
 	// Protected routes (require authentication)
 	protected := api.PathPrefix("").Subrouter()
-	protected.Use(middleware.AuthMiddleware)
+	protected.Use(middleware.AuthMiddleware(sessionService, apiKeyService), middleware.LoadPermissions(roleService))
+
+	// route registers a protected route that needs one of permissions
+	route := func(path string, handler http.HandlerFunc, method string, permissions ...string) {
+		protected.Handle(path, middleware.RequirePermission(permissions...)(handler)).Methods(method, "OPTIONS")
+	}
 
-	// User routes
+	// User routes (every authenticated user)
 	protected.HandleFunc("/profile", userHandler.GetProfile).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/profile/password", userHandler.ChangePassword).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/logout", userHandler.Logout).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/profile/2fa", userHandler.GetTwoFactor).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/profile/2fa/setup", userHandler.SetupTwoFactor).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/profile/2fa/enable", userHandler.EnableTwoFactor).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/profile/2fa/disable", userHandler.DisableTwoFactor).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/profile/2fa/recovery-codes", userHandler.RegenerateRecoveryCodes).Methods("POST", "OPTIONS")
 
-	// Barang routes (all authenticated users)
-	protected.HandleFunc("/barang", barangHandler.GetAll).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/barang/stok", barangHandler.GetAllWithStok).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/barang/{id}", barangHandler.GetByID).Methods("GET", "OPTIONS")
+	// User management routes
+	route("/users", userHandler.GetAll, "GET", "user:manage")
+	route("/users/{id}", userHandler.GetByID, "GET", "user:manage")
+	route("/users", userHandler.Create, "POST", "user:manage")
+	route("/users/{id}", userHandler.Update, "PUT", "user:manage")
+	route("/users/{id}", userHandler.Delete, "DELETE", "user:manage")
+	route("/users/{id}/disable", userHandler.Disable, "POST", "user:manage")
+	route("/users/{id}/enable", userHandler.Enable, "POST", "user:manage")
+	route("/users/{id}/unlock", userHandler.Unlock, "POST", "user:manage")
+	route("/users/{id}/2fa/reset", userHandler.ResetTwoFactor, "POST", "user:manage")
+	route("/login-locks", userHandler.GetLoginLocks, "GET", "user:manage")
+	route("/login-locks/{tipe:user|ip}/{value}", userHandler.DeleteLoginLock, "DELETE", "user:manage")
 
-	// Admin only routes for barang create/update
-	adminBarang := protected.PathPrefix("").Subrouter()
-	adminBarang.Use(middleware.RequireRole("admin"))
-	adminBarang.HandleFunc("/barang", barangHandler.Create).Methods("POST", "OPTIONS")
-	adminBarang.HandleFunc("/barang/{id}", barangHandler.Update).Methods("PUT", "OPTIONS")
-	adminBarang.HandleFunc("/barang/{id}", barangHandler.Delete).Methods("DELETE", "OPTIONS")
+	// Role routes; the role list is also needed to assign roles to users and
+	// the permission list to pick API key scopes
+	route("/roles", roleHandler.GetAll, "GET", "role:manage", "user:manage")
+	route("/roles/{id}", roleHandler.GetByID, "GET", "role:manage", "user:manage")
+	route("/permissions", roleHandler.GetPermissions, "GET", "role:manage", "api_key:manage")
+	route("/roles", roleHandler.Create, "POST", "role:manage")
+	route("/roles/{id}", roleHandler.Update, "PUT", "role:manage")
+	route("/roles/{id}", roleHandler.Delete, "DELETE", "role:manage")
+	route("/roles/{id}/2fa", roleHandler.SetRequire2FA, "PUT", "role:manage")
+	route("/sso/group-roles", oidcHandler.GetGroupRoles, "GET", "role:manage")
+	route("/sso/group-roles", oidcHandler.SetGroupRoles, "PUT", "role:manage")
+
+	// API key routes
+	route("/api-keys", apiKeyHandler.GetAll, "GET", "api_key:manage")
+	route("/api-keys/{id}", apiKeyHandler.GetByID, "GET", "api_key:manage")
+	route("/api-keys", apiKeyHandler.Create, "POST", "api_key:manage")
+	route("/api-keys/{id}", apiKeyHandler.Update, "PUT", "api_key:manage")
+	route("/api-keys/{id}", apiKeyHandler.Revoke, "DELETE", "api_key:manage")
+
+	// Barang routes (specific routes BEFORE generic routes)
+	route("/barang", barangHandler.GetAll, "GET", "barang:read")
+	route("/barang/stok", barangHandler.GetAllWithStok, "GET", "barang:read")
+	route("/barang/barcode/{code}", barcodeHandler.Lookup, "GET", "barang:read")
+	route("/barang/label", labelHandler.Print, "POST", "barang:read")
+	route("/barang/import", barangHandler.Import, "POST", "barang:import")
+	route("/barang/{id}", barangHandler.GetByID, "GET", "barang:read")
+	route("/barang/{id}/price-history", barangHandler.GetPriceHistory, "GET", "barang:read")
+	route("/barang/{id}/price-schedule", barangHandler.GetPriceSchedules, "GET", "barang:read")
+	route("/barang/{id}/komponen", kitHandler.GetKomponen, "GET", "barang:read")
+	route("/barang/{id}/barcode", barcodeHandler.GetBarcodes, "GET", "barang:read")
+	route("/barang/{id}/gambar", fileHandler.GetGambar, "GET", "barang:read")
+	route("/barang/{id}/gambar/{gambar_id}", fileHandler.DownloadGambar, "GET", "barang:read")
+
+	route("/barang", barangHandler.Create, "POST", "barang:create")
+	route("/barang/{id}", barangHandler.Update, "PUT", "barang:update")
+	route("/barang/{id}", barangHandler.Delete, "DELETE", "barang:delete")
+	route("/barang/{id}/archive", barangHandler.Archive, "POST", "barang:delete")
+	route("/barang/{id}/restore", barangHandler.Restore, "POST", "barang:delete")
+	route("/barang/{id}/price-schedule", barangHandler.SchedulePriceChange, "POST", "barang:update_price")
+	route("/barang/{id}/price-schedule/{jadwal_id}", barangHandler.CancelPriceSchedule, "DELETE", "barang:update_price")
+	route("/barang/{id}/komponen", kitHandler.SetKomponen, "PUT", "barang:update")
+	route("/barang/{id}/barcode", barcodeHandler.SetBarcodes, "PUT", "barang:update")
+	route("/barang/{id}/gambar", fileHandler.UploadGambar, "POST", "barang:update")
+	route("/barang/{id}/gambar/{gambar_id}", fileHandler.DeleteGambar, "DELETE", "barang:update")
+
+	// Kategori routes
+	route("/kategori", kategoriHandler.GetAll, "GET", "barang:read")
+	route("/kategori/{id}", kategoriHandler.GetByID, "GET", "barang:read")
+	route("/kategori", kategoriHandler.Create, "POST", "kategori:manage")
+	route("/kategori/{id}", kategoriHandler.Update, "PUT", "kategori:manage")
+	route("/kategori/{id}", kategoriHandler.Delete, "DELETE", "kategori:manage")
+
+	// Supplier routes
+	route("/supplier", supplierHandler.GetAll, "GET", "supplier:read")
+	route("/supplier/{id}", supplierHandler.GetByID, "GET", "supplier:read")
+	route("/supplier", supplierHandler.Create, "POST", "supplier:manage")
+	route("/supplier/{id}", supplierHandler.Update, "PUT", "supplier:manage")
+	route("/supplier/{id}", supplierHandler.Delete, "DELETE", "supplier:manage")
+
+	// Customer routes
+	route("/customer", customerHandler.GetAll, "GET", "customer:read")
+	route("/customer/{id}", customerHandler.GetByID, "GET", "customer:read")
+	route("/customer", customerHandler.Create, "POST", "customer:manage")
+	route("/customer/{id}", customerHandler.Update, "PUT", "customer:manage")
+	route("/customer/{id}", customerHandler.Delete, "DELETE", "customer:manage")
+
+	// Price list routes
+	route("/price-list", priceListHandler.GetAll, "GET", "price_list:read")
+	route("/price-list/{id}", priceListHandler.GetByID, "GET", "price_list:read")
+	route("/price-list", priceListHandler.Create, "POST", "price_list:manage")
+	route("/price-list/{id}", priceListHandler.Update, "PUT", "price_list:manage")
+	route("/price-list/{id}", priceListHandler.Delete, "DELETE", "price_list:manage")
+	route("/price-list/{id}/items", priceListHandler.SetItems, "PUT", "price_list:manage")
 
 	// Stok routes (specific routes BEFORE generic routes)
-	protected.HandleFunc("/stok", stokHandler.GetAll).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/stok/history", stokHandler.GetHistoryAll).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/stok/history/{barang_id}", stokHandler.GetHistoryByBarangID).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/stok/{barang_id}", stokHandler.GetByBarangID).Methods("GET", "OPTIONS")
+	route("/stok", stokHandler.GetAll, "GET", "stok:read")
+	route("/stok/history", stokHandler.GetHistoryAll, "GET", "stok:read")
+	route("/stok/awal", stokHandler.ImportStokAwal, "POST", "stok:import_awal")
+	route("/stok/history/{barang_id}", stokHandler.GetHistoryByBarangID, "GET", "stok:read")
+	route("/stok/{barang_id}", stokHandler.GetByBarangID, "GET", "stok:read")
 
 	// Pembelian routes
-	protected.HandleFunc("/pembelian", pembelianHandler.GetAll).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/pembelian/{id}", pembelianHandler.GetByID).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/pembelian", pembelianHandler.Create).Methods("POST", "OPTIONS")
+	route("/pembelian", pembelianHandler.GetAll, "GET", "pembelian:read")
+	route("/pembelian/{id}", pembelianHandler.GetByID, "GET", "pembelian:read")
+	route("/pembelian", pembelianHandler.Create, "POST", "pembelian:create")
+	route("/pembelian/{id}/pdf", dokumenHandler.PenerimaanBarang, "GET", "pembelian:read")
 
 	// Penjualan routes
-	protected.HandleFunc("/penjualan", penjualanHandler.GetAll).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/penjualan/{id}", penjualanHandler.GetByID).Methods("GET", "OPTIONS")
-	protected.HandleFunc("/penjualan", penjualanHandler.Create).Methods("POST", "OPTIONS")
+	route("/penjualan", penjualanHandler.GetAll, "GET", "penjualan:read")
+	route("/penjualan/harga", penjualanHandler.ResolveHarga, "GET", "penjualan:create")
+	route("/penjualan/{id}", penjualanHandler.GetByID, "GET", "penjualan:read")
+	route("/penjualan", penjualanHandler.Create, "POST", "penjualan:create")
+	route("/penjualan/{id}/pembayaran", penjualanHandler.CreatePembayaran, "POST", "penjualan:payment")
+	route("/penjualan/{id}/pdf", dokumenHandler.FakturPenjualan, "GET", "penjualan:read")
+	route("/penjualan/{id}/surat-jalan/pdf", dokumenHandler.SuratJalan, "GET", "penjualan:read")
+
+	// Attachment routes for pembelian and penjualan; the {tipe} variable is
+	// kept so each route needs the permission of its own document type
+	for _, tipe := range []string{"pembelian", "penjualan"} {
+		base := "/{tipe:" + tipe + "}/{id}/lampiran"
+		route(base, fileHandler.GetLampiran, "GET", tipe+":read")
+		route(base, fileHandler.UploadLampiran, "POST", tipe+":create")
+		route(base+"/{lampiran_id}", fileHandler.DownloadLampiran, "GET", tipe+":read")
+		route(base+"/{lampiran_id}", fileHandler.DeleteLampiran, "DELETE", "lampiran:delete")
+	}
+
+	// Document template routes
+	route("/dokumen/template", dokumenHandler.GetTemplates, "GET", "dokumen:template")
+	route("/dokumen/template/{jenis}", dokumenHandler.GetTemplate, "GET", "dokumen:template")
+	route("/dokumen/template/{jenis}", dokumenHandler.UpdateTemplate, "PUT", "dokumen:template")
+
+	// BOM routes
+	route("/bom", bomHandler.GetAll, "GET", "bom:read")
+	route("/bom/{id}", bomHandler.GetByID, "GET", "bom:read")
+	route("/bom", bomHandler.Create, "POST", "bom:manage")
+	route("/bom/{id}", bomHandler.Update, "PUT", "bom:manage")
+	route("/bom/{id}", bomHandler.Delete, "DELETE", "bom:manage")
+
+	// Produksi routes
+	route("/produksi", produksiHandler.GetAll, "GET", "produksi:read")
+	route("/produksi/{id}", produksiHandler.GetByID, "GET", "produksi:read")
+	route("/produksi", produksiHandler.Create, "POST", "produksi:create")
+	route("/produksi/{id}/realisasi", produksiHandler.CreateRealisasi, "POST", "produksi:create")
+	route("/produksi/{id}/batal", produksiHandler.Cancel, "POST", "produksi:create")
+
+	// Audit log routes
+	route("/audit", auditHandler.GetAll, "GET", "audit:read")
 
 	// Start server
 	addr := ":" + cfg.Port
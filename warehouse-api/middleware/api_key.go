@@ -0,0 +1,19 @@
+package middleware
+
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator resolves an API key presented from ip to the claims it
+// acts with and the permissions it is limited to
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(key, ip string) (*Claims, map[string]bool, error)
+}
+
+// APIKeyError is returned by an APIKeyAuthenticator when a key is rejected,
+// as opposed to failing to check it
+type APIKeyError struct {
+	Reason string
+}
+
+func (e *APIKeyError) Error() string {
+	return e.Reason
+}
@@ -3,9 +3,9 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 	"warehouse-api/models"
@@ -18,47 +18,61 @@ type contextKey string
 const UserContextKey contextKey = "user"
 
 type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID int64  `json:"sid"`
+	// APIKeyID is set instead of SessionID when the request uses an API key
+	APIKeyID int `json:"-"`
 	jwt.RegisteredClaims
 }
 
-// Generate JWT token
-func GenerateToken(userID int, username, role string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
+// SessionChecker reports whether the login session behind a token is still
+// active, i.e. not logged out or revoked
+type SessionChecker interface {
+	IsSessionActive(sessionID int64) (bool, error)
+}
+
+// tokenKeys signs and verifies access tokens; set once at startup
+var tokenKeys *KeySet
+
+// SetKeySet installs the keys GenerateToken and VerifyToken use
+func SetKeySet(keys *KeySet) {
+	tokenKeys = keys
+}
+
+// Generate JWT access token for a login session, valid for ttl. It is
+// signed with the current key, named in the kid header
+func GenerateToken(userID int, username, role string, sessionID int64, ttl time.Duration) (string, error) {
+	if tokenKeys == nil {
+		return "", fmt.Errorf("signing keys not configured")
 	}
+	key := tokenKeys.Current()
 
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
 }
 
-// Verify JWT token
+// Verify JWT token against the key named by its kid, so tokens signed with
+// a previous key keep working until they expire
 func VerifyToken(tokenString string) (*Claims, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
+	if tokenKeys == nil {
+		return nil, fmt.Errorf("signing keys not configured")
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
-
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, tokenKeys.keyFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -70,87 +84,90 @@ func VerifyToken(tokenString string) (*Claims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-// Authentication middleware
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("AuthMiddleware - Path: %s, Method: %s\n", r.URL.Path, r.Method)
-
-		authHeader := r.Header.Get("Authorization")
-		fmt.Printf("AuthMiddleware - Authorization Header: %s\n", authHeader)
-
-		if authHeader == "" {
-			fmt.Println("AuthMiddleware - No Authorization header")
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Success: false,
-				Message: "Authorization header required",
-			})
-			return
-		}
+// Authentication middleware; tokens whose session has been revoked are
+// rejected even before they expire. A request may instead carry an API key
+// in the X-API-Key header, which is limited to the key's scopes
+func AuthMiddleware(sessions SessionChecker, apiKeys APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get(APIKeyHeader); key != "" {
+				claims, permissions, err := apiKeys.AuthenticateAPIKey(key, ClientIP(r))
+				if err != nil {
+					var keyErr *APIKeyError
+					if errors.As(err, &keyErr) {
+						sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+							Success: false,
+							Message: "Invalid API key",
+							Error:   keyErr.Reason,
+						})
+						return
+					}
+					sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+						Success: false,
+						Message: "Failed to check API key",
+						Error:   err.Error(),
+					})
+					return
+				}
 
-		// Extract token from "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			fmt.Printf("AuthMiddleware - Invalid format. Parts: %d, First: %s\n", len(parts), parts[0])
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Success: false,
-				Message: "Invalid authorization header format",
-			})
-			return
-		}
+				// The key's scopes take the place of the role's permissions
+				ctx := context.WithValue(r.Context(), UserContextKey, claims)
+				ctx = context.WithValue(ctx, PermissionsContextKey, permissions)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
 
-		token := parts[1]
-		fmt.Printf("AuthMiddleware - Token extracted: %s...\n", token[:20])
-
-		claims, err := VerifyToken(token)
-		if err != nil {
-			fmt.Printf("AuthMiddleware - Token verification failed: %v\n", err)
-			sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
-				Success: false,
-				Message: "Invalid or expired token",
-				Error:   err.Error(),
-			})
-			return
-		}
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+					Success: false,
+					Message: "Authorization header required",
+				})
+				return
+			}
 
-		fmt.Printf("AuthMiddleware - Token verified. User: %s, Role: %s\n", claims.Username, claims.Role)
+			// Extract token from "Bearer <token>"
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+					Success: false,
+					Message: "Invalid authorization header format",
+				})
+				return
+			}
 
-		// Add user info to context
-		ctx := context.WithValue(r.Context(), UserContextKey, claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
-}
+			token := parts[1]
 
-// Role-based authorization middleware
-func RequireRole(roles ...string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, ok := r.Context().Value(UserContextKey).(*Claims)
-			if !ok {
+			claims, err := VerifyToken(token)
+			if err != nil {
 				sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
 					Success: false,
-					Message: "Unauthorized",
+					Message: "Invalid or expired token",
+					Error:   err.Error(),
 				})
 				return
 			}
 
-			// Check if user's role is in allowed roles
-			allowed := false
-			for _, role := range roles {
-				if claims.Role == role {
-					allowed = true
-					break
-				}
+			active, err := sessions.IsSessionActive(claims.SessionID)
+			if err != nil {
+				sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+					Success: false,
+					Message: "Failed to check session",
+					Error:   err.Error(),
+				})
+				return
 			}
-
-			if !allowed {
-				sendJSON(w, http.StatusForbidden, models.ErrorResponse{
+			if !active {
+				sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
 					Success: false,
-					Message: "Insufficient permissions",
+					Message: "Session has been revoked",
 				})
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			// Add user info to context
+			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -169,7 +186,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
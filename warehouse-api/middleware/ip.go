@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustedProxies are the reverse proxies allowed to set X-Forwarded-For
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies installs the addresses or CIDR ranges of the reverse
+// proxies in front of the API. Without any, X-Forwarded-For is ignored
+func SetTrustedProxies(proxies []string) error {
+	nets, err := ParseTrustedProxies(proxies)
+	if err != nil {
+		return err
+	}
+	trustedProxies = nets
+	return nil
+}
+
+// ParseTrustedProxies parses addresses and CIDR ranges; a plain address is
+// a range of one
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is only read
+// when the direct peer is a trusted proxy, and then from the right: every
+// proxy appends the address it got the request from, so the first entry
+// that is not a trusted proxy is the client. Entries further left were sent
+// by the client and could be anything
+func ClientIP(r *http.Request) string {
+	return clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), trustedProxies)
+}
+
+func clientIP(remoteAddr string, forwarded []string, trusted []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	if !isTrusted(net.ParseIP(host), trusted) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range forwarded {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := host
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			// A trusted proxy would not have written this; stop at the last
+			// address it did write
+			break
+		}
+		client = hop
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
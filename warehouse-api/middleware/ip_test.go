@@ -0,0 +1,52 @@
+package middleware
+
+import "testing"
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5", "fd00::/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		trusted    bool
+		want       string
+	}{
+		{"no proxy", "203.0.113.7:5000", nil, true, "203.0.113.7"},
+		{"untrusted peer ignores header", "203.0.113.7:5000", []string{"198.51.100.1"}, true, "203.0.113.7"},
+		{"private peer without config ignores header", "172.17.0.1:5000", []string{"198.51.100.1"}, false, "172.17.0.1"},
+		{"trusted peer", "10.0.0.2:5000", []string{"198.51.100.1"}, true, "198.51.100.1"},
+		{"spoofed leftmost entry", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1"}, true, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1, 192.168.1.5, 10.1.1.1"}, true, "198.51.100.1"},
+		{"several headers", "10.0.0.2:5000", []string{"1.2.3.4", "198.51.100.1, 10.1.1.1"}, true, "198.51.100.1"},
+		{"all hops trusted", "10.0.0.2:5000", []string{"10.9.9.9, 10.1.1.1"}, true, "10.9.9.9"},
+		{"garbage stops the walk", "10.0.0.2:5000", []string{"198.51.100.1, not-an-ip, 10.1.1.1"}, true, "10.1.1.1"},
+		{"empty header", "10.0.0.2:5000", nil, true, "10.0.0.2"},
+		{"single trusted address only", "192.168.1.6:5000", []string{"198.51.100.1"}, true, "192.168.1.6"},
+		{"ipv6", "[fd00::1]:5000", []string{"2001:db8::1"}, true, "2001:db8::1"},
+		{"no port", "10.0.0.2", []string{"198.51.100.1"}, true, "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nets := trusted
+			if !tt.trusted {
+				nets = nil
+			}
+			if got := clientIP(tt.remoteAddr, tt.forwarded, nets); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	for _, proxy := range []string{"10.0.0.0/33", "proxy.local", ""} {
+		if _, err := ParseTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded", proxy)
+		}
+	}
+}
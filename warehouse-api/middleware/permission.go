@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"warehouse-api/models"
+)
+
+const PermissionsContextKey contextKey = "permissions"
+
+// PermissionSource returns the permissions granted to a role
+type PermissionSource interface {
+	RolePermissions(role string) (map[string]bool, error)
+}
+
+// LoadPermissions looks up the permissions of the authenticated user's role
+// once per request; it must run after AuthMiddleware. Requests made with an
+// API key already carry the key's scopes and are left alone
+func LoadPermissions(source PermissionSource) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*Claims)
+			if !ok {
+				sendJSON(w, http.StatusUnauthorized, models.ErrorResponse{
+					Success: false,
+					Message: "Unauthorized",
+				})
+				return
+			}
+			if _, loaded := r.Context().Value(PermissionsContextKey).(map[string]bool); loaded {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			permissions, err := source.RolePermissions(claims.Role)
+			if err != nil {
+				sendJSON(w, http.StatusInternalServerError, models.ErrorResponse{
+					Success: false,
+					Message: "Failed to load permissions",
+					Error:   err.Error(),
+				})
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), PermissionsContextKey, permissions)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Permission-based authorization middleware; any one of permissions is enough
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, permission := range permissions {
+				if HasPermission(r.Context(), permission) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			sendJSON(w, http.StatusForbidden, models.ErrorResponse{
+				Success: false,
+				Message: "Insufficient permissions",
+			})
+		})
+	}
+}
+
+// HasPermission reports whether the current user's role grants permission
+func HasPermission(ctx context.Context, permission string) bool {
+	permissions, _ := ctx.Value(PermissionsContextKey).(map[string]bool)
+	return permissions[permission]
+}
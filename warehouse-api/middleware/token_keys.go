@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minSecretLength is the shortest HMAC secret accepted, in bytes
+const minSecretLength = 32
+
+// placeholderSecrets appear in example configs; a secret containing one was
+// copied rather than generated
+var placeholderSecrets = []string{
+	"your-secret-key",
+	"change-in-production",
+	"changeme",
+	"change-me",
+}
+
+// SigningKey is one key tokens are signed or verified with, identified by
+// the kid header of the token
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signKey is nil for keys that are only kept to verify tokens issued
+	// before a rotation
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from
+type KeySet struct {
+	current *SigningKey
+	keys    map[string]*SigningKey
+	// order lists the key IDs with the current key first, for the JWKS
+	order []string
+}
+
+// KeyConfig describes the keys to load. With PrivateKeyFile set tokens are
+// signed with that RSA or Ed25519 key and Secret, if given, is only used to
+// verify tokens issued before the switch
+type KeyConfig struct {
+	Secret                 string
+	PreviousSecrets        []string
+	PrivateKeyFile         string
+	PreviousPublicKeyFiles []string
+}
+
+// LoadKeySet builds the key set from cfg, refusing missing, default and
+// weak secrets and short RSA keys
+func LoadKeySet(cfg KeyConfig) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*SigningKey)}
+
+	if cfg.PrivateKeyFile != "" {
+		key, err := loadPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := ks.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	secrets := cfg.PreviousSecrets
+	if cfg.Secret != "" {
+		secrets = append([]string{cfg.Secret}, secrets...)
+	}
+	for _, secret := range secrets {
+		if err := ValidateSecret(secret); err != nil {
+			return nil, err
+		}
+		key := &SigningKey{
+			ID:        "hs-" + fingerprint([]byte(secret)),
+			Method:    jwt.SigningMethodHS256,
+			verifyKey: []byte(secret),
+		}
+		if secret == cfg.Secret && cfg.PrivateKeyFile == "" {
+			key.signKey = []byte(secret)
+		}
+		if err := ks.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range cfg.PreviousPublicKeyFiles {
+		key, err := loadPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := ks.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if ks.current == nil {
+		return nil, fmt.Errorf("no JWT signing key: set JWT_SECRET or JWT_PRIVATE_KEY_FILE")
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) add(key *SigningKey) error {
+	if _, ok := ks.keys[key.ID]; ok {
+		return fmt.Errorf("JWT key %s is configured twice", key.ID)
+	}
+	ks.keys[key.ID] = key
+	if key.signKey != nil && ks.current == nil {
+		ks.current = key
+		ks.order = append([]string{key.ID}, ks.order...)
+		return nil
+	}
+	ks.order = append(ks.order, key.ID)
+	return nil
+}
+
+// Current is the key new tokens are signed with
+func (ks *KeySet) Current() *SigningKey {
+	return ks.current
+}
+
+// ValidateSecret refuses HMAC secrets that are too short or are one of the
+// well-known placeholders
+func ValidateSecret(secret string) error {
+	lower := strings.ToLower(secret)
+	for _, placeholder := range placeholderSecrets {
+		if strings.Contains(lower, placeholder) {
+			return fmt.Errorf("JWT secret is a placeholder, generate a random one (e.g. openssl rand -base64 48)")
+		}
+	}
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("JWT secret must be at least %d characters", minSecretLength)
+	}
+
+	distinct := make(map[rune]bool)
+	for _, c := range secret {
+		distinct[c] = true
+	}
+	if len(distinct) < 10 {
+		return fmt.Errorf("JWT secret is too repetitive, generate a random one (e.g. openssl rand -base64 48)")
+	}
+	return nil
+}
+
+// JSONWebKey is a public key as published in the JWKS
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the body of the JWKS endpoint
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS lists the public keys other services can verify tokens with. HMAC
+// secrets are never published, so this is empty unless asymmetric keys are
+// configured
+func (ks *KeySet) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, id := range ks.order {
+		key := ks.keys[id]
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JSONWebKey{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// keyFunc picks the key named by the token's kid and makes sure the token
+// uses that key's algorithm
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+func loadPrivateKey(file string) (*SigningKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported private key", file)
+	}
+	key, err := publicSigningKey(file, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.signKey = parsed
+	return key, nil
+}
+
+func loadPublicKey(file string) (*SigningKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return publicSigningKey(file, parsed)
+}
+
+// publicSigningKey picks the algorithm for a public key; its kid is derived
+// from the key so the same key always has the same ID
+func publicSigningKey(file string, pub interface{}) (*SigningKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA key must be at least 2048 bits", file)
+		}
+		return &SigningKey{ID: "rs-" + fingerprint(der), Method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: "ed-" + fingerprint(der), Method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	default:
+		return nil, fmt.Errorf("%s: only RSA and Ed25519 keys are supported", file)
+	}
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", file)
+	}
+	return block, nil
+}
+
+// fingerprint is a short ID for key material that does not reveal it
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testSecret         = "k8Jd2nQ5vX9pL3mW7zR1tY6bH4cF0gA8sE2uN5iO"
+	testPreviousSecret = "Zx7Cv4Bn1Mq8Wr5Ty2Ui9Op6As3Df0Gh7Jk4Lp1"
+)
+
+// writeRSAKey writes a new RSA key of bits as PEM and returns the key and the
+// file's path
+func writeRSAKey(t *testing.T, bits int) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "jwt.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key, file
+}
+
+func TestValidateSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr string
+	}{
+		{"random", testSecret, ""},
+		{"exactly minimum length", testSecret[:minSecretLength], ""},
+		{"one short of minimum", testSecret[:minSecretLength-1], "at least"},
+		{"empty", "", "at least"},
+		{"placeholder", "your-secret-key-" + testSecret, "placeholder"},
+		{"placeholder in other case", "CHANGE-IN-PRODUCTION-" + testSecret, "placeholder"},
+		{"short placeholder", "changeme", "placeholder"},
+		{"repetitive", strings.Repeat("ab", 20), "repetitive"},
+		{"nine distinct characters", strings.Repeat("123456789", 4), "repetitive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSecret(tt.secret)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateSecret() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateSecret() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	_, rsaFile := writeRSAKey(t, 2048)
+	_, weakFile := writeRSAKey(t, 1024)
+
+	tests := []struct {
+		name        string
+		cfg         KeyConfig
+		wantErr     string
+		wantAlg     string
+		wantKeys    int
+		wantPublish int
+	}{
+		{"nothing configured", KeyConfig{}, "no JWT signing key", "", 0, 0},
+		{"secret", KeyConfig{Secret: testSecret}, "", "HS256", 1, 0},
+		{"secret with previous", KeyConfig{Secret: testSecret, PreviousSecrets: []string{testPreviousSecret}}, "", "HS256", 2, 0},
+		{"weak secret", KeyConfig{Secret: "short"}, "at least", "", 0, 0},
+		{"weak previous secret", KeyConfig{Secret: testSecret, PreviousSecrets: []string{"changeme"}}, "placeholder", "", 0, 0},
+		{"same secret twice", KeyConfig{Secret: testSecret, PreviousSecrets: []string{testSecret}}, "configured twice", "", 0, 0},
+		{"only previous secrets", KeyConfig{PreviousSecrets: []string{testPreviousSecret}}, "no JWT signing key", "", 0, 0},
+		{"rsa key", KeyConfig{PrivateKeyFile: rsaFile}, "", "RS256", 1, 1},
+		{"rsa key keeps secret for verifying", KeyConfig{PrivateKeyFile: rsaFile, Secret: testSecret}, "", "RS256", 2, 1},
+		{"short rsa key", KeyConfig{PrivateKeyFile: weakFile}, "at least 2048 bits", "", 0, 0},
+		{"missing key file", KeyConfig{PrivateKeyFile: filepath.Join(t.TempDir(), "missing.pem")}, "no such file", "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := LoadKeySet(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadKeySet() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeySet() error = %v", err)
+			}
+			if alg := ks.Current().Method.Alg(); alg != tt.wantAlg {
+				t.Errorf("current key alg = %s, want %s", alg, tt.wantAlg)
+			}
+			if len(ks.keys) != tt.wantKeys {
+				t.Errorf("got %d keys, want %d", len(ks.keys), tt.wantKeys)
+			}
+			if n := len(ks.JWKS().Keys); n != tt.wantPublish {
+				t.Errorf("JWKS has %d keys, want %d", n, tt.wantPublish)
+			}
+		})
+	}
+}
+
+func TestKeyFunc(t *testing.T) {
+	rsaKey, rsaFile := writeRSAKey(t, 2048)
+	ks, err := LoadKeySet(KeyConfig{PrivateKeyFile: rsaFile, Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKID := ks.Current().ID
+	hsKID := ""
+	for id := range ks.keys {
+		if id != rsaKID {
+			hsKID = id
+		}
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "1"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"current rsa key", sign(jwt.SigningMethodRS256, rsaKID, rsaKey), false},
+		{"previous hmac secret", sign(jwt.SigningMethodHS256, hsKID, []byte(testSecret)), false},
+		{"missing kid", sign(jwt.SigningMethodRS256, "", rsaKey), true},
+		{"unknown kid", sign(jwt.SigningMethodRS256, "rs-0000000000000000", rsaKey), true},
+		{"hs256 signed with the rsa public key", sign(jwt.SigningMethodHS256, rsaKID, publicKeyBytes), true},
+		{"hs256 with the rsa kid and the secret", sign(jwt.SigningMethodHS256, rsaKID, []byte(testSecret)), true},
+		{"rs256 with the hmac kid", sign(jwt.SigningMethodRS256, hsKID, rsaKey), true},
+		{"wrong secret", sign(jwt.SigningMethodHS256, hsKID, []byte(testPreviousSecret)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Parse(tt.token, ks.keyFunc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("jwt.Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
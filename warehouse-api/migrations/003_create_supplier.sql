@@ -0,0 +1,49 @@
+-- Migration: Supplier master data
+-- Description: Replace free-text beli_header.supplier with a supplier master table.
+-- Existing free-text values are deduplicated (case, dots, commas and extra spaces
+-- are ignored) so "PT Maju" and "PT. Maju" become a single supplier record.
+
+CREATE TABLE supplier (
+    id SERIAL PRIMARY KEY,
+    kode_supplier VARCHAR(50) UNIQUE NOT NULL,
+    nama_supplier VARCHAR(200) NOT NULL,
+    alamat TEXT,
+    telepon VARCHAR(50),
+    npwp VARCHAR(30),
+    termin_pembayaran INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Prevent the same supplier from being registered twice under a different spelling
+CREATE UNIQUE INDEX idx_supplier_nama_key ON supplier (
+    LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(nama_supplier), '[.,]', '', 'g'), '\s+', ' ', 'g'))
+);
+
+CREATE TRIGGER update_supplier_updated_at BEFORE UPDATE ON supplier
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Deduplicate existing free-text suppliers
+CREATE TEMP TABLE supplier_dedup AS
+SELECT LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(supplier), '[.,]', '', 'g'), '\s+', ' ', 'g')) AS nama_key,
+       MIN(BTRIM(supplier)) AS nama_supplier
+FROM beli_header
+GROUP BY 1;
+
+INSERT INTO supplier (kode_supplier, nama_supplier)
+SELECT 'SUP' || LPAD(ROW_NUMBER() OVER (ORDER BY nama_key)::TEXT, 3, '0'), nama_supplier
+FROM supplier_dedup;
+
+-- Link purchases to the supplier master
+ALTER TABLE beli_header ADD COLUMN supplier_id INT REFERENCES supplier(id);
+
+UPDATE beli_header h SET supplier_id = s.id, supplier = s.nama_supplier
+FROM supplier_dedup d
+JOIN supplier s ON s.nama_supplier = d.nama_supplier
+WHERE LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(h.supplier), '[.,]', '', 'g'), '\s+', ' ', 'g')) = d.nama_key;
+
+ALTER TABLE beli_header ALTER COLUMN supplier_id SET NOT NULL;
+
+DROP TABLE supplier_dedup;
+
+CREATE INDEX idx_beli_header_supplier_id ON beli_header(supplier_id);
@@ -0,0 +1,67 @@
+-- Migration: Customer master data and receivables
+-- Description: Replace free-text jual_header.customer with a customer master table,
+-- track payments per penjualan so outstanding receivables can be checked against
+-- each customer's credit limit.
+
+CREATE TABLE customer (
+    id SERIAL PRIMARY KEY,
+    kode_customer VARCHAR(50) UNIQUE NOT NULL,
+    nama_customer VARCHAR(200) NOT NULL,
+    alamat TEXT,
+    telepon VARCHAR(50),
+    npwp VARCHAR(30),
+    limit_kredit DECIMAL(15, 2) NOT NULL DEFAULT 0 CHECK (limit_kredit >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Prevent the same customer from being registered twice under a different spelling
+CREATE UNIQUE INDEX idx_customer_nama_key ON customer (
+    LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(nama_customer), '[.,]', '', 'g'), '\s+', ' ', 'g'))
+);
+
+CREATE TRIGGER update_customer_updated_at BEFORE UPDATE ON customer
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Deduplicate existing free-text customers
+CREATE TEMP TABLE customer_dedup AS
+SELECT LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(customer), '[.,]', '', 'g'), '\s+', ' ', 'g')) AS nama_key,
+       MIN(BTRIM(customer)) AS nama_customer
+FROM jual_header
+GROUP BY 1;
+
+INSERT INTO customer (kode_customer, nama_customer)
+SELECT 'CUS' || LPAD(ROW_NUMBER() OVER (ORDER BY nama_key)::TEXT, 3, '0'), nama_customer
+FROM customer_dedup;
+
+-- Link sales to the customer master
+ALTER TABLE jual_header ADD COLUMN customer_id INT REFERENCES customer(id);
+ALTER TABLE jual_header ADD COLUMN terbayar DECIMAL(15, 2) NOT NULL DEFAULT 0;
+
+-- Payments were not tracked before, so existing sales are taken as settled;
+-- otherwise the whole sales history would count against the credit limit
+UPDATE jual_header SET terbayar = total;
+
+UPDATE jual_header h SET customer_id = c.id, customer = c.nama_customer
+FROM customer_dedup d
+JOIN customer c ON c.nama_customer = d.nama_customer
+WHERE LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(h.customer), '[.,]', '', 'g'), '\s+', ' ', 'g')) = d.nama_key;
+
+ALTER TABLE jual_header ALTER COLUMN customer_id SET NOT NULL;
+
+DROP TABLE customer_dedup;
+
+CREATE INDEX idx_jual_header_customer_id ON jual_header(customer_id);
+
+-- Payments received for a penjualan (reduce outstanding receivables)
+CREATE TABLE pembayaran_penjualan (
+    id SERIAL PRIMARY KEY,
+    jual_header_id INT NOT NULL REFERENCES jual_header(id) ON DELETE CASCADE,
+    tanggal DATE NOT NULL,
+    jumlah DECIMAL(15, 2) NOT NULL CHECK (jumlah > 0),
+    keterangan TEXT,
+    created_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_pembayaran_penjualan_header_id ON pembayaran_penjualan(jual_header_id);
@@ -0,0 +1,37 @@
+-- Migration: Price lists and customer pricing tiers
+-- Description: Named price lists with per-barang prices and quantity breaks.
+-- Each customer may be assigned one price list; penjualan resolves the price
+-- from that list (largest min_qty not above the sold qty) before falling back
+-- to master_barang.harga_jual.
+
+CREATE TABLE price_list (
+    id SERIAL PRIMARY KEY,
+    kode_price_list VARCHAR(50) UNIQUE NOT NULL,
+    nama_price_list VARCHAR(100) NOT NULL,
+    keterangan TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE price_list_item (
+    id SERIAL PRIMARY KEY,
+    price_list_id INT NOT NULL REFERENCES price_list(id) ON DELETE CASCADE,
+    barang_id INT NOT NULL REFERENCES master_barang(id) ON DELETE CASCADE,
+    min_qty INT NOT NULL DEFAULT 1 CHECK (min_qty >= 1),
+    harga DECIMAL(15, 2) NOT NULL CHECK (harga >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(price_list_id, barang_id, min_qty)
+);
+
+CREATE INDEX idx_price_list_item_lookup ON price_list_item(price_list_id, barang_id, min_qty);
+
+CREATE TRIGGER update_price_list_updated_at BEFORE UPDATE ON price_list
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE customer ADD COLUMN price_list_id INT REFERENCES price_list(id);
+
+-- Default price lists
+INSERT INTO price_list (kode_price_list, nama_price_list, keterangan) VALUES
+('RETAIL', 'Retail', 'Harga eceran'),
+('WHOLESALE', 'Wholesale', 'Harga grosir'),
+('RESELLER', 'Reseller', 'Harga reseller');
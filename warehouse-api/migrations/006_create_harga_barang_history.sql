@@ -0,0 +1,34 @@
+-- Migration: Price change history and scheduled price changes
+-- Description: Every change of harga_beli/harga_jual on master_barang is recorded
+-- with old/new values, the user and a timestamp. Future price changes can be
+-- scheduled and are applied by the background price scheduler once due.
+
+CREATE TABLE history_harga_barang (
+    id SERIAL PRIMARY KEY,
+    barang_id INT NOT NULL REFERENCES master_barang(id) ON DELETE CASCADE,
+    harga_beli_lama DECIMAL(15, 2) NOT NULL,
+    harga_beli_baru DECIMAL(15, 2) NOT NULL,
+    harga_jual_lama DECIMAL(15, 2) NOT NULL,
+    harga_jual_baru DECIMAL(15, 2) NOT NULL,
+    keterangan TEXT,
+    jadwal_id INT,
+    changed_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_history_harga_barang_barang_id ON history_harga_barang(barang_id, created_at);
+
+CREATE TABLE jadwal_harga_barang (
+    id SERIAL PRIMARY KEY,
+    barang_id INT NOT NULL REFERENCES master_barang(id) ON DELETE CASCADE,
+    harga_beli DECIMAL(15, 2) NOT NULL CHECK (harga_beli >= 0),
+    harga_jual DECIMAL(15, 2) NOT NULL CHECK (harga_jual >= 0),
+    berlaku_mulai TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'applied', 'cancelled')),
+    created_by INT REFERENCES users(id),
+    applied_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_jadwal_harga_barang_due ON jadwal_harga_barang(status, berlaku_mulai);
+CREATE INDEX idx_jadwal_harga_barang_barang_id ON jadwal_harga_barang(barang_id);
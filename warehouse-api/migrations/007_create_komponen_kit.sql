@@ -0,0 +1,18 @@
+-- Migration: Bundles / kits
+-- Description: A kit barang (e.g. a workstation package) is defined by a bill of
+-- components. Selling a kit decrements the stock of its components; the kit has
+-- no stock of its own and its availability is derived from the components.
+
+ALTER TABLE master_barang ADD COLUMN is_kit BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE komponen_kit (
+    id SERIAL PRIMARY KEY,
+    kit_barang_id INT NOT NULL REFERENCES master_barang(id) ON DELETE CASCADE,
+    komponen_barang_id INT NOT NULL REFERENCES master_barang(id),
+    qty INT NOT NULL CHECK (qty > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(kit_barang_id, komponen_barang_id),
+    CHECK (kit_barang_id <> komponen_barang_id)
+);
+
+CREATE INDEX idx_komponen_kit_kit_barang_id ON komponen_kit(kit_barang_id);
@@ -0,0 +1,64 @@
+-- Migration: Assembly / production orders
+-- Description: Bills of materials for barang assembled in-house and production
+-- orders that consume components (keluar) and produce the finished barang
+-- (masuk) in one transaction. Orders can be completed in several partial
+-- realisasi steps, each with its own scrap quantity.
+
+CREATE TABLE bom (
+    id SERIAL PRIMARY KEY,
+    kode_bom VARCHAR(50) UNIQUE NOT NULL,
+    barang_id INT NOT NULL REFERENCES master_barang(id),
+    keterangan TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE bom_detail (
+    id SERIAL PRIMARY KEY,
+    bom_id INT NOT NULL REFERENCES bom(id) ON DELETE CASCADE,
+    komponen_barang_id INT NOT NULL REFERENCES master_barang(id),
+    qty INT NOT NULL CHECK (qty > 0),
+    UNIQUE(bom_id, komponen_barang_id)
+);
+
+CREATE INDEX idx_bom_barang_id ON bom(barang_id);
+CREATE INDEX idx_bom_detail_bom_id ON bom_detail(bom_id);
+
+CREATE TRIGGER update_bom_updated_at BEFORE UPDATE ON bom
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE produksi_header (
+    id SERIAL PRIMARY KEY,
+    no_produksi VARCHAR(50) UNIQUE NOT NULL,
+    tanggal DATE NOT NULL,
+    bom_id INT NOT NULL REFERENCES bom(id),
+    barang_id INT NOT NULL REFERENCES master_barang(id),
+    qty_rencana INT NOT NULL CHECK (qty_rencana > 0),
+    qty_selesai INT NOT NULL DEFAULT 0,
+    qty_scrap INT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'proses' CHECK (status IN ('proses', 'selesai', 'batal')),
+    keterangan TEXT,
+    created_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE produksi_realisasi (
+    id SERIAL PRIMARY KEY,
+    produksi_header_id INT NOT NULL REFERENCES produksi_header(id) ON DELETE CASCADE,
+    tanggal DATE NOT NULL,
+    qty_selesai INT NOT NULL CHECK (qty_selesai >= 0),
+    qty_scrap INT NOT NULL CHECK (qty_scrap >= 0),
+    biaya_komponen DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    biaya_per_unit DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    keterangan TEXT,
+    created_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (qty_selesai + qty_scrap > 0)
+);
+
+CREATE INDEX idx_produksi_header_no_produksi ON produksi_header(no_produksi);
+CREATE INDEX idx_produksi_realisasi_header_id ON produksi_realisasi(produksi_header_id);
+
+CREATE TRIGGER update_produksi_header_updated_at BEFORE UPDATE ON produksi_header
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,12 @@
+-- Migration: Barcodes per barang
+-- Description: A barang can carry several scanner codes (EAN-13, Code128, supplier
+-- codes). Each barcode identifies exactly one barang.
+
+CREATE TABLE barcode_barang (
+    id SERIAL PRIMARY KEY,
+    barang_id INT NOT NULL REFERENCES master_barang(id) ON DELETE CASCADE,
+    barcode VARCHAR(50) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_barcode_barang_barang_id ON barcode_barang(barang_id);
@@ -0,0 +1,33 @@
+-- Migration: Printable document templates
+-- Description: Company header, notes and signature block used when rendering
+-- documents such as the sales invoice. One row per document kind (jenis);
+-- admins edit them through the API.
+
+CREATE TABLE template_dokumen (
+    jenis VARCHAR(50) PRIMARY KEY,
+    judul VARCHAR(100) NOT NULL,
+    nama_perusahaan VARCHAR(100) NOT NULL,
+    alamat TEXT,
+    telepon VARCHAR(50),
+    email VARCHAR(100),
+    npwp VARCHAR(30),
+    catatan TEXT,
+    label_ttd_kiri VARCHAR(50),
+    label_ttd_kanan VARCHAR(50),
+    nama_ttd_kanan VARCHAR(100),
+    ukuran_kertas VARCHAR(10) NOT NULL DEFAULT 'A4' CHECK (ukuran_kertas IN ('A4', 'A5', 'Letter')),
+    tampilkan_terbilang BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_template_dokumen_updated_at BEFORE UPDATE ON template_dokumen
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO template_dokumen (jenis, judul, nama_perusahaan, alamat, telepon, email, catatan,
+    label_ttd_kiri, label_ttd_kanan, nama_ttd_kanan) VALUES
+('faktur_penjualan', 'FAKTUR PENJUALAN', 'PT Gudang Sejahtera', 'Jl. Industri No. 1, Jakarta',
+ '(021) 555-0100', 'sales@gudang.local',
+ 'Pembayaran ditransfer ke rekening BCA 123-456-7890 a.n. PT Gudang Sejahtera.',
+ 'Penerima', 'Hormat kami', 'Bagian Penjualan');
@@ -0,0 +1,21 @@
+-- Migration: Goods receipt / delivery note templates and print counter
+-- Description: Templates for the pembelian receiving note and the penjualan
+-- delivery note (surat jalan), plus a per-document print counter. Every print
+-- after the first is marked as a COPY.
+
+INSERT INTO template_dokumen (jenis, judul, nama_perusahaan, alamat, telepon, email,
+    label_ttd_kiri, label_ttd_kanan, tampilkan_terbilang) VALUES
+('penerimaan_barang', 'BUKTI PENERIMAAN BARANG', 'PT Gudang Sejahtera', 'Jl. Industri No. 1, Jakarta',
+ '(021) 555-0100', 'gudang@gudang.local', 'Pengirim', 'Diterima oleh', FALSE),
+('surat_jalan', 'SURAT JALAN', 'PT Gudang Sejahtera', 'Jl. Industri No. 1, Jakarta',
+ '(021) 555-0100', 'gudang@gudang.local', 'Penerima', 'Pengirim', FALSE);
+
+CREATE TABLE cetak_dokumen (
+    jenis VARCHAR(50) NOT NULL REFERENCES template_dokumen(jenis),
+    referensi_id INT NOT NULL,
+    jumlah_cetak INT NOT NULL DEFAULT 1,
+    pertama_dicetak_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    terakhir_dicetak_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    terakhir_dicetak_oleh INT REFERENCES users(id),
+    PRIMARY KEY (jenis, referensi_id)
+);
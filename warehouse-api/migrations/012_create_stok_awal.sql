@@ -0,0 +1,23 @@
+-- Migration: Opening stock ledger entries
+-- Description: Opening balances (stok_awal) are written to history_stok as
+-- jenis_transaksi 'awal' with their opening cost per unit, so the ledger explains
+-- every mstok balance. Seeded 'INITIAL' movements become 'awal' rows.
+
+ALTER TABLE history_stok DROP CONSTRAINT IF EXISTS history_stok_jenis_transaksi_check;
+ALTER TABLE history_stok ADD CONSTRAINT history_stok_jenis_transaksi_check
+    CHECK (jenis_transaksi IN ('awal', 'masuk', 'keluar'));
+
+-- Opening cost per unit, only set on 'awal' rows
+ALTER TABLE history_stok ADD COLUMN harga DECIMAL(15, 2);
+
+UPDATE history_stok h SET jenis_transaksi = 'awal', referensi_tipe = 'stok_awal', harga = b.harga_beli
+FROM master_barang b
+WHERE b.id = h.barang_id AND h.referensi_tipe = 'INITIAL';
+
+-- Opening balances without a ledger entry
+INSERT INTO history_stok (barang_id, jenis_transaksi, qty, stok_sebelum, stok_sesudah, keterangan, referensi_tipe, harga)
+SELECT s.barang_id, 'awal', s.stok_awal, 0, s.stok_awal, 'Stok awal', 'stok_awal', b.harga_beli
+FROM mstok s
+JOIN master_barang b ON b.id = s.barang_id
+WHERE s.stok_awal <> 0
+  AND NOT EXISTS (SELECT 1 FROM history_stok h WHERE h.barang_id = s.barang_id AND h.jenis_transaksi = 'awal');
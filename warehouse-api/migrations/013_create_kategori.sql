@@ -0,0 +1,35 @@
+-- Migration: Hierarchical product categories
+-- Description: Categories become managed data with a parent/child hierarchy.
+-- Every distinct master_barang.kategori string becomes a top-level kategori and
+-- the barang is linked to it through kategori_id. The kategori text column is
+-- kept in sync with the name of the linked kategori.
+
+CREATE TABLE kategori (
+    id SERIAL PRIMARY KEY,
+    nama VARCHAR(100) NOT NULL,
+    parent_id INT REFERENCES kategori(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+-- Names are unique among siblings
+CREATE UNIQUE INDEX idx_kategori_parent_nama ON kategori (COALESCE(parent_id, 0), LOWER(nama));
+CREATE INDEX idx_kategori_parent_id ON kategori(parent_id);
+
+CREATE TRIGGER update_kategori_updated_at BEFORE UPDATE ON kategori
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE master_barang ADD COLUMN kategori_id INT REFERENCES kategori(id);
+CREATE INDEX idx_master_barang_kategori_id ON master_barang(kategori_id);
+
+INSERT INTO kategori (nama)
+SELECT MIN(TRIM(kategori))
+FROM master_barang
+WHERE kategori IS NOT NULL AND TRIM(kategori) <> ''
+GROUP BY LOWER(TRIM(kategori))
+ORDER BY 1;
+
+UPDATE master_barang b SET kategori_id = k.id, kategori = k.nama
+FROM kategori k
+WHERE k.parent_id IS NULL AND LOWER(k.nama) = LOWER(TRIM(b.kategori));
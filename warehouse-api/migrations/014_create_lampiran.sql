@@ -0,0 +1,36 @@
+-- Migration: Barang images and document attachments
+-- Description: Photos of a barang and files (e.g. scanned supplier invoices)
+-- attached to a pembelian or penjualan. The files themselves live in the file
+-- storage under file_key; images also get a JPEG thumbnail.
+
+CREATE TABLE barang_gambar (
+    id SERIAL PRIMARY KEY,
+    barang_id INT NOT NULL REFERENCES master_barang(id) ON DELETE CASCADE,
+    nama_file VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    ukuran BIGINT NOT NULL,
+    file_key VARCHAR(255) NOT NULL UNIQUE,
+    thumbnail_key VARCHAR(255) NOT NULL,
+    uploaded_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_barang_gambar_barang_id ON barang_gambar(barang_id);
+
+CREATE TABLE lampiran (
+    id SERIAL PRIMARY KEY,
+    beli_header_id INT REFERENCES beli_header(id) ON DELETE CASCADE,
+    jual_header_id INT REFERENCES jual_header(id) ON DELETE CASCADE,
+    nama_file VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    ukuran BIGINT NOT NULL,
+    keterangan TEXT,
+    file_key VARCHAR(255) NOT NULL UNIQUE,
+    thumbnail_key VARCHAR(255),
+    uploaded_by INT REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((beli_header_id IS NULL) <> (jual_header_id IS NULL))
+);
+
+CREATE INDEX idx_lampiran_beli_header_id ON lampiran(beli_header_id);
+CREATE INDEX idx_lampiran_jual_header_id ON lampiran(jual_header_id);
@@ -0,0 +1,15 @@
+-- Migration: Archive barang instead of deleting them
+-- Description: Archived barang are hidden from lists and pickers and cannot be
+-- bought or sold, but their stock, ledger and documents stay intact. Only a
+-- barang that was never transacted may still be deleted, and the stock ledger
+-- no longer cascades so it can never be erased by a delete.
+
+ALTER TABLE master_barang
+    ADD COLUMN archived_at TIMESTAMP,
+    ADD COLUMN archived_by INT REFERENCES users(id);
+
+CREATE INDEX idx_master_barang_archived_at ON master_barang(archived_at);
+
+ALTER TABLE history_stok DROP CONSTRAINT history_stok_barang_id_fkey;
+ALTER TABLE history_stok ADD CONSTRAINT history_stok_barang_id_fkey
+    FOREIGN KEY (barang_id) REFERENCES master_barang(id);
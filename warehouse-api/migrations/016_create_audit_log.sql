@@ -0,0 +1,22 @@
+-- Migration: Audit log
+-- Description: One row per change to barang, users, pembelian and penjualan:
+-- who made it, from which IP, and the entity before and after as JSON.
+-- user_id is NULL for changes made by the system (e.g. scheduled prices).
+
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    username VARCHAR(50),
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id INT,
+    entity_label VARCHAR(100),
+    before_data JSONB,
+    after_data JSONB,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_user_id ON audit_log(user_id);
+CREATE INDEX idx_audit_log_entity ON audit_log(entity_type, entity_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
@@ -0,0 +1,7 @@
+-- Migration: User management
+-- Description: Users can be disabled instead of deleted. A disabled user
+-- cannot log in, but keeps their name on every document they created.
+
+ALTER TABLE users
+    ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN disabled_at TIMESTAMP;
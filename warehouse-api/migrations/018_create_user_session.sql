@@ -0,0 +1,29 @@
+-- Migration: Login sessions and refresh tokens
+-- Description: Every login starts a session. Access tokens are short-lived
+-- JWTs carrying the session id; refresh tokens are stored only as SHA-256
+-- hashes and rotate on every use. Presenting a refresh token that was already
+-- used revokes the whole session, as it means the token was copied.
+
+CREATE TABLE user_session (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_user_session_user_id ON user_session(user_id);
+
+CREATE TABLE refresh_token (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES user_session(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_token_session_id ON refresh_token(session_id);
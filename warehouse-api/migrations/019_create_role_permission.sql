@@ -0,0 +1,111 @@
+-- Migration: Roles and permissions
+-- Description: Roles are rows instead of a hard-coded admin/staff check, each
+-- granted a set of permissions such as 'pembelian:create'. The admin role is a
+-- system role: it always has every permission and cannot be changed or
+-- deleted. users.role now references role(nama).
+
+CREATE TABLE role (
+    id SERIAL PRIMARY KEY,
+    nama VARCHAR(50) UNIQUE NOT NULL,
+    deskripsi VARCHAR(255),
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_role_updated_at BEFORE UPDATE ON role
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE permission (
+    kode VARCHAR(50) PRIMARY KEY,
+    deskripsi VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE role_permission (
+    role_id INT NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permission(kode) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission)
+);
+
+INSERT INTO permission (kode, deskripsi) VALUES
+('barang:read', 'View barang, kategori, kits, barcodes and images; print labels'),
+('barang:create', 'Create barang'),
+('barang:update', 'Update barang, kits, barcodes and images'),
+('barang:update_price', 'Change harga beli/jual and schedule price changes'),
+('barang:delete', 'Archive, restore and delete barang'),
+('barang:import', 'Bulk import barang'),
+('kategori:manage', 'Create, update and delete kategori'),
+('supplier:read', 'View suppliers'),
+('supplier:manage', 'Create, update and delete suppliers'),
+('customer:read', 'View customers'),
+('customer:manage', 'Create, update and delete customers'),
+('price_list:read', 'View price lists'),
+('price_list:manage', 'Create, update and delete price lists'),
+('stok:read', 'View stock and stock history'),
+('stok:import_awal', 'Import opening stock'),
+('stok:force_awal', 'Reset the opening stock of barang with transactions'),
+('pembelian:read', 'View purchases and print goods receipts'),
+('pembelian:create', 'Create purchases and attach files to them'),
+('penjualan:read', 'View sales and print invoices and delivery notes'),
+('penjualan:create', 'Create sales and attach files to them'),
+('penjualan:override_credit_limit', 'Create sales over a customer''s credit limit'),
+('penjualan:payment', 'Record payments for sales'),
+('lampiran:delete', 'Delete purchase and sale attachments'),
+('bom:read', 'View bills of materials'),
+('bom:manage', 'Create, update and delete bills of materials'),
+('produksi:read', 'View production orders'),
+('produksi:create', 'Create, realise and cancel production orders'),
+('dokumen:template', 'Edit document templates'),
+('audit:read', 'View the audit log'),
+('user:manage', 'Manage users'),
+('role:manage', 'Manage roles and their permissions');
+
+INSERT INTO role (nama, deskripsi, is_system) VALUES
+('admin', 'Full access', TRUE),
+('staff', 'Day-to-day purchasing, sales and warehouse work', FALSE),
+('purchasing', 'Suppliers and purchases', FALSE),
+('sales', 'Customers and sales', FALSE),
+('warehouse', 'Stock and production', FALSE),
+('finance', 'Payments, prices and credit limits', FALSE),
+('viewer', 'Read-only access', FALSE);
+
+-- staff keeps what it could do before roles were configurable
+INSERT INTO role_permission (role_id, permission)
+SELECT r.id, p.kode FROM role r, permission p
+WHERE r.nama = 'staff' AND p.kode IN (
+    'barang:read', 'supplier:read', 'customer:read', 'price_list:read', 'stok:read',
+    'stok:import_awal', 'pembelian:read', 'pembelian:create', 'penjualan:read',
+    'penjualan:create', 'penjualan:payment', 'bom:read', 'produksi:read', 'produksi:create');
+
+INSERT INTO role_permission (role_id, permission)
+SELECT r.id, p.kode FROM role r, permission p
+WHERE r.nama = 'purchasing' AND p.kode IN (
+    'barang:read', 'supplier:read', 'supplier:manage', 'price_list:read', 'stok:read',
+    'pembelian:read', 'pembelian:create');
+
+INSERT INTO role_permission (role_id, permission)
+SELECT r.id, p.kode FROM role r, permission p
+WHERE r.nama = 'sales' AND p.kode IN (
+    'barang:read', 'customer:read', 'customer:manage', 'price_list:read', 'stok:read',
+    'penjualan:read', 'penjualan:create', 'penjualan:payment');
+
+INSERT INTO role_permission (role_id, permission)
+SELECT r.id, p.kode FROM role r, permission p
+WHERE r.nama = 'warehouse' AND p.kode IN (
+    'barang:read', 'stok:read', 'stok:import_awal', 'pembelian:read', 'penjualan:read',
+    'bom:read', 'produksi:read', 'produksi:create');
+
+INSERT INTO role_permission (role_id, permission)
+SELECT r.id, p.kode FROM role r, permission p
+WHERE r.nama = 'finance' AND p.kode IN (
+    'barang:read', 'barang:update_price', 'supplier:read', 'customer:read', 'price_list:read',
+    'price_list:manage', 'stok:read', 'pembelian:read', 'penjualan:read', 'penjualan:payment',
+    'penjualan:override_credit_limit');
+
+INSERT INTO role_permission (role_id, permission)
+SELECT r.id, p.kode FROM role r, permission p
+WHERE r.nama = 'viewer' AND p.kode LIKE '%:read' AND p.kode <> 'audit:read';
+
+ALTER TABLE users DROP CONSTRAINT users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey
+    FOREIGN KEY (role) REFERENCES role(nama) ON UPDATE CASCADE;
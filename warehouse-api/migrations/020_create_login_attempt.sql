@@ -0,0 +1,14 @@
+-- Migration: Failed login tracking
+-- Description: Failed logins counted per username ('user:<name>') and per
+-- client IP ('ip:<address>'), shared by every API instance when
+-- LOGIN_ATTEMPT_STORE=postgres. A key is locked until locked_until; the count
+-- starts over once no failure has been seen for a while.
+
+CREATE TABLE login_attempt (
+    key VARCHAR(150) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+CREATE INDEX idx_login_attempt_locked_until ON login_attempt(locked_until);
@@ -0,0 +1,33 @@
+-- Migration: API keys
+-- Description: Keys for machine integrations, sent in the X-API-Key header.
+-- Only the SHA-256 hash of a key is stored, plus its first characters so it
+-- can be recognised in lists. A key acts as its owner user (for created_by
+-- and the audit log) but only has the permissions listed as its scopes.
+
+INSERT INTO permission (kode, deskripsi) VALUES
+('api_key:manage', 'Create, change and revoke API keys');
+
+CREATE TABLE api_key (
+    id SERIAL PRIMARY KEY,
+    nama VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    allowed_ips TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    last_used_ip VARCHAR(45),
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE TRIGGER update_api_key_updated_at BEFORE UPDATE ON api_key
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE api_key_scope (
+    api_key_id INT NOT NULL REFERENCES api_key(id) ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permission(kode) ON DELETE CASCADE,
+    PRIMARY KEY (api_key_id, permission)
+);
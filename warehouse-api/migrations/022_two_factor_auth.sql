@@ -0,0 +1,39 @@
+-- Migration: Two-factor authentication
+-- Description: Optional TOTP (authenticator app) codes on top of the password.
+-- totp_secret is set when a user starts enrolling and only takes effect once
+-- totp_enabled is set after a code has been verified. totp_last_step is the
+-- time step of the last accepted code, so a code cannot be used twice.
+-- Recovery codes are single-use and stored as SHA-256 hashes. A role can
+-- require its users to use 2FA; they are then asked to enrol when they log in.
+
+ALTER TABLE users
+    ADD COLUMN totp_secret VARCHAR(64),
+    ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN totp_enabled_at TIMESTAMP,
+    ADD COLUMN totp_last_step BIGINT;
+
+ALTER TABLE role ADD COLUMN require_2fa BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE user_recovery_code (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+-- Login challenges bridge the password and the code step of a login. They
+-- live for a few minutes and allow a handful of wrong codes
+CREATE TABLE login_challenge (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    purpose VARCHAR(10) NOT NULL CHECK (purpose IN ('verify', 'enroll')),
+    attempts INT NOT NULL DEFAULT 0,
+    ip_address VARCHAR(45),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_challenge_expires ON login_challenge(expires_at);
@@ -0,0 +1,33 @@
+-- Migration: OpenID Connect single sign-on
+-- Description: Users can log in through the company identity provider. An SSO
+-- user is linked by the issuer and subject of their ID token and created on
+-- their first login. Their role comes from their IdP groups through
+-- sso_group_role: the mapping with the lowest priority that matches one of the
+-- groups wins, and it is applied again on every SSO login.
+
+ALTER TABLE users
+    ADD COLUMN sso_issuer VARCHAR(255),
+    ADD COLUMN sso_subject VARCHAR(255),
+    ADD CONSTRAINT users_sso_identity_key UNIQUE (sso_issuer, sso_subject);
+
+CREATE TABLE sso_group_role (
+    group_name VARCHAR(255) PRIMARY KEY,
+    role VARCHAR(50) NOT NULL REFERENCES role(nama) ON UPDATE CASCADE ON DELETE CASCADE,
+    priority INT NOT NULL
+);
+
+-- State of a login that was sent to the IdP and has not come back yet
+CREATE TABLE sso_login_state (
+    id SERIAL PRIMARY KEY,
+    state_hash CHAR(64) UNIQUE NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- After the IdP callback the browser gets a one-time code for an 'sso' login
+-- challenge, which it exchanges for tokens like a password login
+ALTER TABLE login_challenge DROP CONSTRAINT login_challenge_purpose_check;
+ALTER TABLE login_challenge ADD CONSTRAINT login_challenge_purpose_check
+    CHECK (purpose IN ('verify', 'enroll', 'sso'));
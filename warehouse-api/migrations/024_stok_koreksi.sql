@@ -0,0 +1,10 @@
+-- Migration: Opening stock corrections
+-- Description: Forcing a new opening balance on a barang that already has
+-- movements no longer rewrites its 'awal' ledger row. The difference is written
+-- as a separate 'koreksi' row whose qty is signed (negative lowers the stock),
+-- so every row's stok_sebelum is the previous row's stok_sesudah. Who made the
+-- correction is in the audit log (action 'stok_awal').
+
+ALTER TABLE history_stok DROP CONSTRAINT IF EXISTS history_stok_jenis_transaksi_check;
+ALTER TABLE history_stok ADD CONSTRAINT history_stok_jenis_transaksi_check
+    CHECK (jenis_transaksi IN ('awal', 'masuk', 'keluar', 'koreksi'));
@@ -0,0 +1,21 @@
+-- Migration: Production order components
+-- Description: A production order keeps its own copy of the BOM lines taken
+-- when it is created, so editing the BOM later does not change what the
+-- remaining realisasi of an order already in progress consume. Existing orders
+-- get the BOM lines as they are now.
+
+CREATE TABLE produksi_komponen (
+    id SERIAL PRIMARY KEY,
+    produksi_header_id INT NOT NULL REFERENCES produksi_header(id) ON DELETE CASCADE,
+    komponen_barang_id INT NOT NULL REFERENCES master_barang(id),
+    qty INT NOT NULL CHECK (qty > 0),
+    UNIQUE(produksi_header_id, komponen_barang_id)
+);
+
+CREATE INDEX idx_produksi_komponen_header_id ON produksi_komponen(produksi_header_id);
+
+INSERT INTO produksi_komponen (produksi_header_id, komponen_barang_id, qty)
+SELECT p.id, d.komponen_barang_id, d.qty
+FROM produksi_header p
+JOIN bom_detail d ON d.bom_id = p.bom_id
+ORDER BY p.id, d.id;
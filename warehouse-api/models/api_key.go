@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// APIKey lets an integration call the API as its owner user, limited to the
+// permissions in Scopes
+type APIKey struct {
+	ID         int        `json:"id"`
+	Nama       string     `json:"nama"`
+	KeyPrefix  string     `json:"key_prefix"`
+	KeyHash    string     `json:"-"`
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"username"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip"`
+	CreatedBy  *int       `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// NewAPIKey is returned once when a key is created; the key itself cannot
+// be retrieved again
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyRequest creates or changes a key. allowed_ips holds addresses or
+// CIDR ranges; empty allows any IP
+type APIKeyRequest struct {
+	Nama       string     `json:"nama"`
+	UserID     int        `json:"user_id"`
+	Scopes     []string   `json:"scopes"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
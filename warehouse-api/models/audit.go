@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditLog records one change: the actor, the entity and its state before and
+// after as JSON. EntityLabel is the human readable key such as kode_barang or
+// no_faktur
+type AuditLog struct {
+	ID          int64           `json:"id"`
+	UserID      *int            `json:"user_id"`
+	Username    *string         `json:"username"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entity_type"`
+	EntityID    *int            `json:"entity_id"`
+	EntityLabel *string         `json:"entity_label"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	IPAddress   *string         `json:"ip_address"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows the audit list; zero values match everything. From and
+// To are inclusive YYYY-MM-DD dates
+type AuditFilter struct {
+	UserID      int
+	EntityType  string
+	EntityID    int
+	EntityLabel string
+	Action      string
+	From        string
+	To          string
+}
@@ -3,17 +3,29 @@ package models
 import "time"
 
 type Barang struct {
-	ID         int       `json:"id"`
-	KodeBarang string    `json:"kode_barang"`
-	NamaBarang string    `json:"nama_barang"`
-	Kategori   string    `json:"kategori"`
-	Satuan     string    `json:"satuan"`
-	HargaBeli  float64   `json:"harga_beli"`
-	HargaJual  float64   `json:"harga_jual"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         int     `json:"id"`
+	KodeBarang string  `json:"kode_barang"`
+	NamaBarang string  `json:"nama_barang"`
+	Kategori   string  `json:"kategori"`
+	KategoriID *int    `json:"kategori_id"`
+	Satuan     string  `json:"satuan"`
+	HargaBeli  float64 `json:"harga_beli"`
+	HargaJual  float64 `json:"harga_jual"`
+	IsKit      bool    `json:"is_kit"`
+	// ArchivedAt is set once the barang is archived; archived barang are hidden
+	// from lists by default and cannot be bought or sold
+	ArchivedAt *time.Time `json:"archived_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
 }
 
+// Status filters for barang lists
+const (
+	BarangStatusActive   = "active"
+	BarangStatusArchived = "archived"
+	BarangStatusAll      = "all"
+)
+
 type BarangWithStok struct {
 	Barang
 	QtyMasuk  int `json:"qty_masuk"`
@@ -21,18 +33,29 @@ type BarangWithStok struct {
 	StokAkhir int `json:"qty_akhir"`
 }
 
+// BarangScan is the result of a barcode lookup
+type BarangScan struct {
+	BarangWithStok
+	Barcodes []BarcodeBarang `json:"barcodes"`
+}
+
 type CreateBarangRequest struct {
-	KodeBarang string  `json:"kode_barang"`
-	NamaBarang string  `json:"nama_barang"`
-	Kategori   string  `json:"kategori"`
+	KodeBarang string `json:"kode_barang"`
+	NamaBarang string `json:"nama_barang"`
+	Kategori   string `json:"kategori"`
+	// KategoriID takes precedence over the kategori name
+	KategoriID *int    `json:"kategori_id"`
 	Satuan     string  `json:"satuan"`
 	HargaBeli  float64 `json:"harga_beli"`
 	HargaJual  float64 `json:"harga_jual"`
+	IsKit      bool    `json:"is_kit"`
 }
 
 type UpdateBarangRequest struct {
-	NamaBarang string  `json:"nama_barang"`
-	Kategori   string  `json:"kategori"`
+	NamaBarang string `json:"nama_barang"`
+	Kategori   string `json:"kategori"`
+	// KategoriID takes precedence over the kategori name
+	KategoriID *int    `json:"kategori_id"`
 	Satuan     string  `json:"satuan"`
 	HargaBeli  float64 `json:"harga_beli"`
 	HargaJual  float64 `json:"harga_jual"`
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type BarcodeBarang struct {
+	ID        int       `json:"id"`
+	BarangID  int       `json:"barang_id"`
+	Barcode   string    `json:"barcode"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SetBarcodeRequest struct {
+	Barcodes []string `json:"barcodes"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type Customer struct {
+	ID           int       `json:"id"`
+	KodeCustomer string    `json:"kode_customer"`
+	NamaCustomer string    `json:"nama_customer"`
+	Alamat       string    `json:"alamat"`
+	Telepon      string    `json:"telepon"`
+	NPWP         string    `json:"npwp"`
+	LimitKredit  float64   `json:"limit_kredit"`
+	PriceListID  *int      `json:"price_list_id"`
+	Piutang      float64   `json:"piutang"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type CreateCustomerRequest struct {
+	KodeCustomer string  `json:"kode_customer"`
+	NamaCustomer string  `json:"nama_customer"`
+	Alamat       string  `json:"alamat"`
+	Telepon      string  `json:"telepon"`
+	NPWP         string  `json:"npwp"`
+	LimitKredit  float64 `json:"limit_kredit"`
+	PriceListID  *int    `json:"price_list_id"`
+}
+
+type UpdateCustomerRequest struct {
+	NamaCustomer string  `json:"nama_customer"`
+	Alamat       string  `json:"alamat"`
+	Telepon      string  `json:"telepon"`
+	NPWP         string  `json:"npwp"`
+	LimitKredit  float64 `json:"limit_kredit"`
+	PriceListID  *int    `json:"price_list_id"`
+}
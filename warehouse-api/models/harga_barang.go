@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type HistoryHargaBarang struct {
+	ID            int       `json:"id"`
+	BarangID      int       `json:"barang_id"`
+	HargaBeliLama float64   `json:"harga_beli_lama"`
+	HargaBeliBaru float64   `json:"harga_beli_baru"`
+	HargaJualLama float64   `json:"harga_jual_lama"`
+	HargaJualBaru float64   `json:"harga_jual_baru"`
+	Keterangan    string    `json:"keterangan"`
+	JadwalID      *int      `json:"jadwal_id"`
+	ChangedBy     *int      `json:"changed_by"`
+	ChangedByNama string    `json:"changed_by_nama"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type JadwalHargaBarang struct {
+	ID           int        `json:"id"`
+	BarangID     int        `json:"barang_id"`
+	HargaBeli    float64    `json:"harga_beli"`
+	HargaJual    float64    `json:"harga_jual"`
+	BerlakuMulai time.Time  `json:"berlaku_mulai"`
+	Status       string     `json:"status"`
+	CreatedBy    *int       `json:"created_by"`
+	AppliedAt    *time.Time `json:"applied_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type CreateJadwalHargaRequest struct {
+	HargaBeli    float64 `json:"harga_beli"`
+	HargaJual    float64 `json:"harga_jual"`
+	BerlakuMulai string  `json:"berlaku_mulai"`
+}
@@ -3,16 +3,18 @@ package models
 import "time"
 
 type HistoryStok struct {
-	ID             int       `json:"id"`
-	BarangID       int       `json:"barang_id"`
-	JenisTransaksi string    `json:"jenis_transaksi"`
-	Qty            int       `json:"qty"`
-	StokSebelum    int       `json:"stok_sebelum"`
-	StokSesudah    int       `json:"stok_sesudah"`
-	Keterangan     string    `json:"keterangan"`
-	ReferensiID    *int      `json:"referensi_id"`
-	ReferensiTipe  string    `json:"referensi_tipe"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID             int    `json:"id"`
+	BarangID       int    `json:"barang_id"`
+	JenisTransaksi string `json:"jenis_transaksi"`
+	Qty            int    `json:"qty"`
+	StokSebelum    int    `json:"stok_sebelum"`
+	StokSesudah    int    `json:"stok_sesudah"`
+	Keterangan     string `json:"keterangan"`
+	ReferensiID    *int   `json:"referensi_id"`
+	ReferensiTipe  string `json:"referensi_tipe"`
+	// Harga is the opening cost per unit of an 'awal' row
+	Harga     *float64  `json:"harga"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type HistoryStokWithBarang struct {
@@ -0,0 +1,29 @@
+package models
+
+// ImportBarangRow is one data row of a barang import file with the outcome
+// of its validation
+type ImportBarangRow struct {
+	// Row is the 1-based line or sheet row number, counting the header
+	Row        int     `json:"row"`
+	KodeBarang string  `json:"kode_barang"`
+	NamaBarang string  `json:"nama_barang"`
+	Kategori   string  `json:"kategori"`
+	Satuan     string  `json:"satuan"`
+	HargaBeli  float64 `json:"harga_beli"`
+	HargaJual  float64 `json:"harga_jual"`
+	// Action is "create" or "update" (an existing kode_barang)
+	Action string   `json:"action"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+type ImportBarangResult struct {
+	// Mode is "dry_run" or "commit"
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	TotalRows int               `json:"total_rows"`
+	ValidRows int               `json:"valid_rows"`
+	ErrorRows int               `json:"error_rows"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Rows      []ImportBarangRow `json:"rows"`
+}
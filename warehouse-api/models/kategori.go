@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type Kategori struct {
+	ID       int    `json:"id"`
+	Nama     string `json:"nama"`
+	ParentID *int   `json:"parent_id"`
+	// Path is the full name from the root, e.g. "Elektronik / Laptop"
+	Path         string    `json:"path"`
+	Depth        int       `json:"depth"`
+	JumlahBarang int       `json:"jumlah_barang"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// KategoriTree is a kategori with its sub-categories
+type KategoriTree struct {
+	Kategori
+	Children []*KategoriTree `json:"children"`
+}
+
+type CreateKategoriRequest struct {
+	Nama     string `json:"nama"`
+	ParentID *int   `json:"parent_id"`
+}
+
+type UpdateKategoriRequest struct {
+	Nama     string `json:"nama"`
+	ParentID *int   `json:"parent_id"`
+}
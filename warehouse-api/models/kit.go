@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type KomponenKit struct {
+	ID               int    `json:"id"`
+	KitBarangID      int    `json:"kit_barang_id"`
+	KomponenBarangID int    `json:"komponen_barang_id"`
+	Qty              int    `json:"qty"`
+	KodeBarang       string `json:"kode_barang"`
+	NamaBarang       string `json:"nama_barang"`
+	Satuan           string `json:"satuan"`
+	StokAkhir        int    `json:"stok_akhir"`
+	// ArchivedAt is set when the komponen was archived after it was added
+	ArchivedAt *time.Time `json:"archived_at"`
+}
+
+type SetKomponenKitRequest struct {
+	Komponen []KomponenKitRequest `json:"komponen"`
+}
+
+type KomponenKitRequest struct {
+	BarangID int `json:"barang_id"`
+	Qty      int `json:"qty"`
+}
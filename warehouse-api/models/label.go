@@ -0,0 +1,31 @@
+package models
+
+// LabelLayout describes a label sheet in millimetres
+type LabelLayout struct {
+	PageWidth   float64 `json:"page_width"`
+	PageHeight  float64 `json:"page_height"`
+	MarginTop   float64 `json:"margin_top"`
+	MarginLeft  float64 `json:"margin_left"`
+	LabelWidth  float64 `json:"label_width"`
+	LabelHeight float64 `json:"label_height"`
+	GapX        float64 `json:"gap_x"`
+	GapY        float64 `json:"gap_y"`
+	Columns     int     `json:"columns"`
+	Rows        int     `json:"rows"`
+}
+
+type LabelItemRequest struct {
+	BarangID int `json:"barang_id"`
+	Qty      int `json:"qty"`
+}
+
+type CreateLabelRequest struct {
+	// Symbology is "code128" (default) or "ean13"
+	Symbology string             `json:"symbology"`
+	Items     []LabelItemRequest `json:"items"`
+	// PembelianID prints one label per received unit of every line of a pembelian
+	PembelianID int          `json:"pembelian_id"`
+	Layout      *LabelLayout `json:"layout"`
+	// StartPosition skips labels already used on the first sheet (1-based)
+	StartPosition int `json:"start_position"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// BarangGambar is a photo of a barang. URL and ThumbnailURL are the
+// authenticated download endpoints
+type BarangGambar struct {
+	ID           int       `json:"id"`
+	BarangID     int       `json:"barang_id"`
+	NamaFile     string    `json:"nama_file"`
+	ContentType  string    `json:"content_type"`
+	Ukuran       int64     `json:"ukuran"`
+	FileKey      string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	UploadedBy   *int      `json:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Lampiran is a file attached to a pembelian or penjualan; ReferensiTipe is
+// "pembelian" or "penjualan". Only image attachments have a thumbnail
+type Lampiran struct {
+	ID            int       `json:"id"`
+	ReferensiTipe string    `json:"referensi_tipe"`
+	ReferensiID   int       `json:"referensi_id"`
+	NamaFile      string    `json:"nama_file"`
+	ContentType   string    `json:"content_type"`
+	Ukuran        int64     `json:"ukuran"`
+	Keterangan    *string   `json:"keterangan"`
+	FileKey       string    `json:"-"`
+	ThumbnailKey  *string   `json:"-"`
+	URL           string    `json:"url"`
+	ThumbnailURL  *string   `json:"thumbnail_url"`
+	UploadedBy    *int      `json:"uploaded_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -6,6 +6,7 @@ type BeliHeader struct {
 	ID         int       `json:"id"`
 	NoFaktur   string    `json:"no_faktur"`
 	Tanggal    string    `json:"tanggal"`
+	SupplierID int       `json:"supplier_id"`
 	Supplier   string    `json:"supplier"`
 	Total      float64   `json:"total"`
 	Keterangan string    `json:"keterangan"`
@@ -39,13 +40,15 @@ type BeliHeaderWithDetail struct {
 type CreatePembelianRequest struct {
 	NoFaktur   string                  `json:"no_faktur"`
 	Tanggal    string                  `json:"tanggal"`
-	Supplier   string                  `json:"supplier"`
+	SupplierID int                     `json:"supplier_id"`
 	Keterangan string                  `json:"keterangan"`
 	Details    []CreatePembelianDetail `json:"details"`
 }
 
 type CreatePembelianDetail struct {
-	BarangID int     `json:"barang_id"`
-	Qty      int     `json:"qty"`
-	Harga    float64 `json:"harga"`
+	BarangID int `json:"barang_id"`
+	// Barcode identifies the barang by a scanned code when barang_id is 0
+	Barcode string  `json:"barcode"`
+	Qty     int     `json:"qty"`
+	Harga   float64 `json:"harga"`
 }
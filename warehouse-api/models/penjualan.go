@@ -6,8 +6,10 @@ type JualHeader struct {
 	ID         int       `json:"id"`
 	NoFaktur   string    `json:"no_faktur"`
 	Tanggal    string    `json:"tanggal"`
+	CustomerID int       `json:"customer_id"`
 	Customer   string    `json:"customer"`
 	Total      float64   `json:"total"`
+	Terbayar   float64   `json:"terbayar"`
 	Keterangan string    `json:"keterangan"`
 	CreatedBy  int       `json:"created_by"`
 	CreatedAt  time.Time `json:"created_at"`
@@ -33,19 +35,40 @@ type JualDetailWithBarang struct {
 
 type JualHeaderWithDetail struct {
 	JualHeader
-	Details []JualDetailWithBarang `json:"details"`
+	Details    []JualDetailWithBarang `json:"details"`
+	Pembayaran []PembayaranPenjualan  `json:"pembayaran"`
 }
 
 type CreatePenjualanRequest struct {
 	NoFaktur   string                  `json:"no_faktur"`
 	Tanggal    string                  `json:"tanggal"`
-	Customer   string                  `json:"customer"`
+	CustomerID int                     `json:"customer_id"`
 	Keterangan string                  `json:"keterangan"`
 	Details    []CreatePenjualanDetail `json:"details"`
+	// OverrideLimitKredit lets an admin record a sale above the customer's credit limit
+	OverrideLimitKredit bool `json:"override_limit_kredit"`
 }
 
 type CreatePenjualanDetail struct {
-	BarangID int     `json:"barang_id"`
-	Qty      int     `json:"qty"`
-	Harga    float64 `json:"harga"`
+	BarangID int `json:"barang_id"`
+	// Barcode identifies the barang by a scanned code when barang_id is 0
+	Barcode string  `json:"barcode"`
+	Qty     int     `json:"qty"`
+	Harga   float64 `json:"harga"`
+}
+
+type PembayaranPenjualan struct {
+	ID           int       `json:"id"`
+	JualHeaderID int       `json:"jual_header_id"`
+	Tanggal      string    `json:"tanggal"`
+	Jumlah       float64   `json:"jumlah"`
+	Keterangan   string    `json:"keterangan"`
+	CreatedBy    int       `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CreatePembayaranRequest struct {
+	Tanggal    string  `json:"tanggal"`
+	Jumlah     float64 `json:"jumlah"`
+	Keterangan string  `json:"keterangan"`
 }
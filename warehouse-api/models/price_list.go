@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type PriceList struct {
+	ID            int       `json:"id"`
+	KodePriceList string    `json:"kode_price_list"`
+	NamaPriceList string    `json:"nama_price_list"`
+	Keterangan    string    `json:"keterangan"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type PriceListItem struct {
+	ID          int     `json:"id"`
+	PriceListID int     `json:"price_list_id"`
+	BarangID    int     `json:"barang_id"`
+	MinQty      int     `json:"min_qty"`
+	Harga       float64 `json:"harga"`
+	KodeBarang  string  `json:"kode_barang"`
+	NamaBarang  string  `json:"nama_barang"`
+}
+
+type PriceListWithItems struct {
+	PriceList
+	Items []PriceListItem `json:"items"`
+}
+
+type CreatePriceListRequest struct {
+	KodePriceList string `json:"kode_price_list"`
+	NamaPriceList string `json:"nama_price_list"`
+	Keterangan    string `json:"keterangan"`
+}
+
+type UpdatePriceListRequest struct {
+	NamaPriceList string `json:"nama_price_list"`
+	Keterangan    string `json:"keterangan"`
+}
+
+type SetPriceListItemsRequest struct {
+	Items []PriceListItemRequest `json:"items"`
+}
+
+type PriceListItemRequest struct {
+	BarangID int     `json:"barang_id"`
+	MinQty   int     `json:"min_qty"`
+	Harga    float64 `json:"harga"`
+}
+
+type ResolvedHarga struct {
+	BarangID    int     `json:"barang_id"`
+	Qty         int     `json:"qty"`
+	Harga       float64 `json:"harga"`
+	PriceListID *int    `json:"price_list_id"`
+	MinQty      int     `json:"min_qty"`
+}
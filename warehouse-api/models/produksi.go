@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+type BOM struct {
+	ID         int       `json:"id"`
+	KodeBOM    string    `json:"kode_bom"`
+	BarangID   int       `json:"barang_id"`
+	KodeBarang string    `json:"kode_barang"`
+	NamaBarang string    `json:"nama_barang"`
+	Keterangan string    `json:"keterangan"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type BOMDetail struct {
+	ID               int     `json:"id"`
+	BomID            int     `json:"bom_id"`
+	KomponenBarangID int     `json:"komponen_barang_id"`
+	Qty              int     `json:"qty"`
+	KodeBarang       string  `json:"kode_barang"`
+	NamaBarang       string  `json:"nama_barang"`
+	Satuan           string  `json:"satuan"`
+	HargaBeli        float64 `json:"harga_beli"`
+	// ArchivedAt is set when the komponen was archived after it was added
+	ArchivedAt *time.Time `json:"archived_at"`
+}
+
+type BOMWithDetail struct {
+	BOM
+	Details []BOMDetail `json:"details"`
+}
+
+type CreateBOMRequest struct {
+	KodeBOM    string                   `json:"kode_bom"`
+	BarangID   int                      `json:"barang_id"`
+	Keterangan string                   `json:"keterangan"`
+	Details    []CreateBOMDetailRequest `json:"details"`
+}
+
+type UpdateBOMRequest struct {
+	Keterangan string                   `json:"keterangan"`
+	Details    []CreateBOMDetailRequest `json:"details"`
+}
+
+type CreateBOMDetailRequest struct {
+	BarangID int `json:"barang_id"`
+	Qty      int `json:"qty"`
+}
+
+type ProduksiHeader struct {
+	ID         int       `json:"id"`
+	NoProduksi string    `json:"no_produksi"`
+	Tanggal    string    `json:"tanggal"`
+	BomID      int       `json:"bom_id"`
+	BarangID   int       `json:"barang_id"`
+	KodeBarang string    `json:"kode_barang"`
+	NamaBarang string    `json:"nama_barang"`
+	QtyRencana int       `json:"qty_rencana"`
+	QtySelesai int       `json:"qty_selesai"`
+	QtyScrap   int       `json:"qty_scrap"`
+	Status     string    `json:"status"`
+	Keterangan string    `json:"keterangan"`
+	CreatedBy  int       `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ProduksiRealisasi struct {
+	ID               int       `json:"id"`
+	ProduksiHeaderID int       `json:"produksi_header_id"`
+	Tanggal          string    `json:"tanggal"`
+	QtySelesai       int       `json:"qty_selesai"`
+	QtyScrap         int       `json:"qty_scrap"`
+	BiayaKomponen    float64   `json:"biaya_komponen"`
+	BiayaPerUnit     float64   `json:"biaya_per_unit"`
+	Keterangan       string    `json:"keterangan"`
+	CreatedBy        int       `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type ProduksiWithDetail struct {
+	ProduksiHeader
+	Komponen  []BOMDetail         `json:"komponen"`
+	Realisasi []ProduksiRealisasi `json:"realisasi"`
+}
+
+type CreateProduksiRequest struct {
+	NoProduksi string `json:"no_produksi"`
+	Tanggal    string `json:"tanggal"`
+	BomID      int    `json:"bom_id"`
+	QtyRencana int    `json:"qty_rencana"`
+	Keterangan string `json:"keterangan"`
+}
+
+type CreateRealisasiRequest struct {
+	Tanggal    string `json:"tanggal"`
+	QtySelesai int    `json:"qty_selesai"`
+	QtyScrap   int    `json:"qty_scrap"`
+	Keterangan string `json:"keterangan"`
+}
@@ -18,4 +18,6 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 	Error   string `json:"error,omitempty"`
 	Code    string `json:"code,omitempty"`
+	// Data carries a report explaining the error, such as per-row import errors
+	Data interface{} `json:"data,omitempty"`
 }
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Role is a named set of permissions assigned to users. A system role has
+// every permission and cannot be changed
+type Role struct {
+	ID          int       `json:"id"`
+	Nama        string    `json:"nama"`
+	Deskripsi   string    `json:"deskripsi"`
+	IsSystem    bool      `json:"is_system"`
+	Require2FA  bool      `json:"require_2fa"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Permission struct {
+	Kode      string `json:"kode"`
+	Deskripsi string `json:"deskripsi"`
+}
+
+type RoleRequest struct {
+	Nama        string   `json:"nama"`
+	Deskripsi   string   `json:"deskripsi"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleTwoFactorRequest turns the 2FA requirement of a role on or off
+type RoleTwoFactorRequest struct {
+	Require2FA bool `json:"require_2fa"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// UserSession is one login; it ends when revoked or when its last refresh
+// token expires
+type UserSession struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	IPAddress  *string    `json:"ip_address"`
+	UserAgent  *string    `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// RefreshToken is stored by hash only; the raw token is returned to the
+// client once
+type RefreshToken struct {
+	ID        int64
+	SessionID int64
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TokenPair is issued on login and on every refresh
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// SSOGroupRole gives users in an IdP group a local role; with several
+// matching groups the lowest priority wins
+type SSOGroupRole struct {
+	GroupName string `json:"group"`
+	Role      string `json:"role"`
+	Priority  int    `json:"priority"`
+}
+
+// SSOGroupRoleRequest replaces every mapping; their order is their priority
+type SSOGroupRoleRequest struct {
+	Mappings []SSOGroupRole `json:"mappings"`
+}
+
+// SSOLoginState is kept while the browser is at the IdP
+type SSOLoginState struct {
+	ID           int
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// SSOIdentity is what the IdP says about the user who logged in
+type SSOIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Nama     string
+	Groups   []string
+}
+
+type SSOConfigResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// LoginSSORequest exchanges the one-time code from the SSO callback
+type LoginSSORequest struct {
+	Code string `json:"code"`
+}
+
+// SSOLogin is the local user an SSO login resolved to
+type SSOLogin struct {
+	User    *User
+	Created bool
+	// PreviousRole is set when the user's groups moved them to another role
+	PreviousRole string
+}
@@ -19,3 +19,40 @@ type StokWithBarang struct {
 	NamaBarang string `json:"nama_barang"`
 	Satuan     string `json:"satuan"`
 }
+
+// StokAwalItem is one opening balance, identified by barang_id or kode_barang
+type StokAwalItem struct {
+	// Row is the file row of an uploaded item; JSON items are numbered from 1
+	Row        int    `json:"-"`
+	BarangID   int    `json:"barang_id"`
+	KodeBarang string `json:"kode_barang"`
+	Qty        int    `json:"qty"`
+	// Harga is the opening cost per unit; the barang's harga_beli when empty
+	Harga *float64 `json:"harga"`
+}
+
+type ImportStokAwalRequest struct {
+	Items []StokAwalItem `json:"items"`
+	// Force lets an admin reset the opening balance of a barang that already has transactions
+	Force bool `json:"force"`
+}
+
+type StokAwalRow struct {
+	// Row is the 1-based item number, or the file row counting the header
+	Row        int      `json:"row"`
+	BarangID   int      `json:"barang_id"`
+	KodeBarang string   `json:"kode_barang"`
+	NamaBarang string   `json:"nama_barang"`
+	Qty        int      `json:"qty"`
+	Harga      float64  `json:"harga"`
+	StokAkhir  int      `json:"stok_akhir"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+type ImportStokAwalResult struct {
+	Committed bool          `json:"committed"`
+	Forced    bool          `json:"forced"`
+	TotalRows int           `json:"total_rows"`
+	ErrorRows int           `json:"error_rows"`
+	Rows      []StokAwalRow `json:"rows"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type Supplier struct {
+	ID               int       `json:"id"`
+	KodeSupplier     string    `json:"kode_supplier"`
+	NamaSupplier     string    `json:"nama_supplier"`
+	Alamat           string    `json:"alamat"`
+	Telepon          string    `json:"telepon"`
+	NPWP             string    `json:"npwp"`
+	TerminPembayaran int       `json:"termin_pembayaran"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type CreateSupplierRequest struct {
+	KodeSupplier     string `json:"kode_supplier"`
+	NamaSupplier     string `json:"nama_supplier"`
+	Alamat           string `json:"alamat"`
+	Telepon          string `json:"telepon"`
+	NPWP             string `json:"npwp"`
+	TerminPembayaran int    `json:"termin_pembayaran"`
+}
+
+type UpdateSupplierRequest struct {
+	NamaSupplier     string `json:"nama_supplier"`
+	Alamat           string `json:"alamat"`
+	Telepon          string `json:"telepon"`
+	NPWP             string `json:"npwp"`
+	TerminPembayaran int    `json:"termin_pembayaran"`
+}
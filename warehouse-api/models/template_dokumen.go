@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type TemplateDokumen struct {
+	Jenis              string    `json:"jenis"`
+	Judul              string    `json:"judul"`
+	NamaPerusahaan     string    `json:"nama_perusahaan"`
+	Alamat             string    `json:"alamat"`
+	Telepon            string    `json:"telepon"`
+	Email              string    `json:"email"`
+	NPWP               string    `json:"npwp"`
+	Catatan            string    `json:"catatan"`
+	LabelTtdKiri       string    `json:"label_ttd_kiri"`
+	LabelTtdKanan      string    `json:"label_ttd_kanan"`
+	NamaTtdKanan       string    `json:"nama_ttd_kanan"`
+	UkuranKertas       string    `json:"ukuran_kertas"`
+	TampilkanTerbilang bool      `json:"tampilkan_terbilang"`
+	UpdatedBy          *int      `json:"updated_by"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type UpdateTemplateDokumenRequest struct {
+	Judul              string `json:"judul"`
+	NamaPerusahaan     string `json:"nama_perusahaan"`
+	Alamat             string `json:"alamat"`
+	Telepon            string `json:"telepon"`
+	Email              string `json:"email"`
+	NPWP               string `json:"npwp"`
+	Catatan            string `json:"catatan"`
+	LabelTtdKiri       string `json:"label_ttd_kiri"`
+	LabelTtdKanan      string `json:"label_ttd_kanan"`
+	NamaTtdKanan       string `json:"nama_ttd_kanan"`
+	UkuranKertas       string `json:"ukuran_kertas"`
+	TampilkanTerbilang bool   `json:"tampilkan_terbilang"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// TwoFactorStatus is the 2FA state of the current user
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetup is what an authenticator app needs to enrol: the secret,
+// the otpauth:// URI and the same URI as a QR code PNG data URI
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+// TwoFactorCodeRequest carries either a code from the authenticator app or
+// one of the recovery codes
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginChallenge is the state between a correct password and a correct
+// code. Purpose is "verify" for users with 2FA and "enroll" for users whose
+// role requires 2FA but who have not set it up yet
+type LoginChallenge struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	Purpose   string
+	Attempts  int
+	IPAddress *string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// LoginChallengeResponse is returned by login instead of tokens when a
+// second step is needed
+type LoginChallengeResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+	Purpose        string `json:"purpose"`
+	ExpiresIn      int    `json:"expires_in"`
+}
+
+// LoginTwoFactorRequest completes a login challenge
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
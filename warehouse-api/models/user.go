@@ -3,13 +3,17 @@ package models
 import "time"
 
 type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	Nama      string    `json:"nama"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID               int        `json:"id"`
+	Username         string     `json:"username"`
+	Password         string     `json:"-"`
+	Nama             string     `json:"nama"`
+	Role             string     `json:"role"`
+	IsActive         bool       `json:"is_active"`
+	DisabledAt       *time.Time `json:"disabled_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	TOTPSecret       *string    `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
 type LoginRequest struct {
@@ -18,6 +22,29 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	TokenPair
+	User        User     `json:"user"`
+	Permissions []string `json:"permissions"`
+	// RecoveryCodes is only set when 2FA was enabled as part of the login
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Nama     string `json:"nama"`
+	Role     string `json:"role"`
+}
+
+// UpdateUserRequest changes a user's name and role; a non-empty password
+// resets the password as well
+type UpdateUserRequest struct {
+	Nama     string `json:"nama"`
+	Role     string `json:"role"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
 }
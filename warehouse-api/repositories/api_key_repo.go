@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	FindAll() ([]models.APIKey, error)
+	FindByID(id int) (*models.APIKey, error)
+	FindByHash(keyHash string) (*models.APIKey, error)
+	Create(key *models.APIKey) error
+	Update(key *models.APIKey) error
+	Revoke(id int) error
+	Touch(id int, ip string) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeySelect = `SELECT k.id, k.nama, k.key_prefix, k.key_hash, k.user_id, u.username,
+	          COALESCE((SELECT array_agg(s.permission ORDER BY s.permission) FROM api_key_scope s
+	          WHERE s.api_key_id = k.id), '{}'),
+	          k.allowed_ips, k.expires_at, k.last_used_at, k.last_used_ip, k.created_by,
+	          k.created_at, k.updated_at, k.revoked_at
+	          FROM api_key k JOIN users u ON u.id = k.user_id`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }, key *models.APIKey) error {
+	return row.Scan(&key.ID, &key.Nama, &key.KeyPrefix, &key.KeyHash, &key.UserID, &key.Username,
+		pq.Array(&key.Scopes), pq.Array(&key.AllowedIPs), &key.ExpiresAt, &key.LastUsedAt, &key.LastUsedIP,
+		&key.CreatedBy, &key.CreatedAt, &key.UpdatedAt, &key.RevokedAt)
+}
+
+func (r *apiKeyRepository) FindAll() ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+
+	rows, err := r.db.Query(apiKeySelect + ` ORDER BY k.revoked_at IS NOT NULL, k.nama ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) FindByID(id int) (*models.APIKey, error) {
+	key := &models.APIKey{}
+
+	err := scanAPIKey(r.db.QueryRow(apiKeySelect+` WHERE k.id = $1`, id), key)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	key := &models.APIKey{}
+
+	err := scanAPIKey(r.db.QueryRow(apiKeySelect+` WHERE k.key_hash = $1`, keyHash), key)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO api_key (nama, key_prefix, key_hash, user_id, allowed_ips, expires_at, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
+	if err := tx.QueryRow(query, key.Nama, key.KeyPrefix, key.KeyHash, key.UserID, pq.Array(key.AllowedIPs),
+		key.ExpiresAt, key.CreatedBy).Scan(&key.ID, &key.CreatedAt, &key.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := setAPIKeyScopes(tx, key.ID, key.Scopes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update changes everything but the key itself, its owner and its usage
+func (r *apiKeyRepository) Update(key *models.APIKey) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE api_key SET nama = $1, allowed_ips = $2, expires_at = $3
+	          WHERE id = $4 AND revoked_at IS NULL RETURNING updated_at`
+	err = tx.QueryRow(query, key.Nama, pq.Array(key.AllowedIPs), key.ExpiresAt, key.ID).Scan(&key.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM api_key_scope WHERE api_key_id = $1`, key.ID); err != nil {
+		return err
+	}
+	if err := setAPIKeyScopes(tx, key.ID, key.Scopes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setAPIKeyScopes(tx *sql.Tx, apiKeyID int, scopes []string) error {
+	query := `INSERT INTO api_key_scope (api_key_id, permission) SELECT $1, UNNEST($2::text[])`
+	_, err := tx.Exec(query, apiKeyID, pq.Array(scopes))
+	return err
+}
+
+// Revoke stops a key from working; it stays listed with its usage
+func (r *apiKeyRepository) Revoke(id int) error {
+	result, err := r.db.Exec(`UPDATE api_key SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	return nil
+}
+
+// Touch records that the key was just used from ip
+func (r *apiKeyRepository) Touch(id int, ip string) error {
+	_, err := r.db.Exec(`UPDATE api_key SET last_used_at = CURRENT_TIMESTAMP, last_used_ip = $1 WHERE id = $2`, ip, id)
+	return err
+}
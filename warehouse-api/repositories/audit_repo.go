@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"database/sql"
+	"warehouse-api/models"
+)
+
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	CreateTx(tx *sql.Tx, entry *models.AuditLog) error
+	FindAll(filter *models.AuditFilter, limit, offset int) ([]models.AuditLog, int, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+const auditInsert = `INSERT INTO audit_log (user_id, username, action, entity_type, entity_id, entity_label,
+	          before_data, after_data, ip_address)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          RETURNING id, created_at`
+
+// auditJSON passes a JSON document to a JSONB column, or NULL when empty
+func auditJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+func (r *auditRepository) Create(entry *models.AuditLog) error {
+	return r.db.QueryRow(auditInsert, entry.UserID, entry.Username, entry.Action, entry.EntityType,
+		entry.EntityID, entry.EntityLabel, auditJSON(entry.Before), auditJSON(entry.After), entry.IPAddress).Scan(
+		&entry.ID, &entry.CreatedAt,
+	)
+}
+
+// CreateTx records an entry inside tx so it is only kept if the change commits
+func (r *auditRepository) CreateTx(tx *sql.Tx, entry *models.AuditLog) error {
+	return tx.QueryRow(auditInsert, entry.UserID, entry.Username, entry.Action, entry.EntityType,
+		entry.EntityID, entry.EntityLabel, auditJSON(entry.Before), auditJSON(entry.After), entry.IPAddress).Scan(
+		&entry.ID, &entry.CreatedAt,
+	)
+}
+
+// auditFilter applies models.AuditFilter bound to $1..$7
+const auditFilter = `($1 = 0 OR a.user_id = $1)
+	          AND ($2 = '' OR a.entity_type = $2)
+	          AND ($3 = 0 OR a.entity_id = $3)
+	          AND ($4 = '' OR LOWER(a.entity_label) = LOWER($4))
+	          AND ($5 = '' OR a.action = $5)
+	          AND ($6 = '' OR a.created_at >= $6::date)
+	          AND ($7 = '' OR a.created_at < $7::date + 1)`
+
+func (r *auditRepository) FindAll(filter *models.AuditFilter, limit, offset int) ([]models.AuditLog, int, error) {
+	entries := []models.AuditLog{}
+	var total int
+
+	args := []interface{}{filter.UserID, filter.EntityType, filter.EntityID, filter.EntityLabel,
+		filter.Action, filter.From, filter.To}
+
+	// Count total
+	countQuery := `SELECT COUNT(*) FROM audit_log a WHERE ` + auditFilter
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// Get data with pagination; entries written by services inside a
+	// transaction only carry the user id, so the username is looked up
+	query := `SELECT a.id, a.user_id, COALESCE(a.username, u.username), a.action, a.entity_type,
+	          a.entity_id, a.entity_label, a.before_data, a.after_data, a.ip_address, a.created_at
+	          FROM audit_log a
+	          LEFT JOIN users u ON u.id = a.user_id
+	          WHERE ` + auditFilter + `
+	          ORDER BY a.id DESC LIMIT $8 OFFSET $9`
+
+	rows, err := r.db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditLog
+		var before, after []byte
+		err := rows.Scan(&e.ID, &e.UserID, &e.Username, &e.Action, &e.EntityType, &e.EntityID,
+			&e.EntityLabel, &before, &after, &e.IPAddress, &e.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		e.Before, e.After = before, after
+		entries = append(entries, e)
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+type BarangGambarRepository interface {
+	FindByBarangID(barangID int) ([]models.BarangGambar, error)
+	FindByID(barangID, id int) (*models.BarangGambar, error)
+	Create(gambar *models.BarangGambar) error
+	Delete(barangID, id int) error
+}
+
+type barangGambarRepository struct {
+	db *sql.DB
+}
+
+func NewBarangGambarRepository(db *sql.DB) BarangGambarRepository {
+	return &barangGambarRepository{db: db}
+}
+
+const barangGambarColumns = `id, barang_id, nama_file, content_type, ukuran, file_key, thumbnail_key,
+	          uploaded_by, created_at`
+
+func scanBarangGambar(row interface{ Scan(...interface{}) error }, g *models.BarangGambar) error {
+	return row.Scan(&g.ID, &g.BarangID, &g.NamaFile, &g.ContentType, &g.Ukuran, &g.FileKey,
+		&g.ThumbnailKey, &g.UploadedBy, &g.CreatedAt)
+}
+
+func (r *barangGambarRepository) FindByBarangID(barangID int) ([]models.BarangGambar, error) {
+	gambars := []models.BarangGambar{}
+
+	query := `SELECT ` + barangGambarColumns + ` FROM barang_gambar WHERE barang_id = $1 ORDER BY id`
+	rows, err := r.db.Query(query, barangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var g models.BarangGambar
+		if err := scanBarangGambar(rows, &g); err != nil {
+			return nil, err
+		}
+		gambars = append(gambars, g)
+	}
+
+	return gambars, nil
+}
+
+func (r *barangGambarRepository) FindByID(barangID, id int) (*models.BarangGambar, error) {
+	gambar := &models.BarangGambar{}
+
+	query := `SELECT ` + barangGambarColumns + ` FROM barang_gambar WHERE id = $1 AND barang_id = $2`
+	err := scanBarangGambar(r.db.QueryRow(query, id, barangID), gambar)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("gambar not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return gambar, nil
+}
+
+func (r *barangGambarRepository) Create(gambar *models.BarangGambar) error {
+	query := `INSERT INTO barang_gambar (barang_id, nama_file, content_type, ukuran, file_key, thumbnail_key, uploaded_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)
+	          RETURNING id, created_at`
+
+	return r.db.QueryRow(query, gambar.BarangID, gambar.NamaFile, gambar.ContentType, gambar.Ukuran,
+		gambar.FileKey, gambar.ThumbnailKey, gambar.UploadedBy).Scan(&gambar.ID, &gambar.CreatedAt)
+}
+
+func (r *barangGambarRepository) Delete(barangID, id int) error {
+	result, err := r.db.Exec(`DELETE FROM barang_gambar WHERE id = $1 AND barang_id = $2`, id, barangID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("gambar not found")
+	}
+
+	return nil
+}
@@ -7,11 +7,20 @@ import (
 )
 
 type BarangRepository interface {
-	FindAll(search string, limit, offset int) ([]models.Barang, int, error)
+	FindAll(search string, kategoriID int, status string, limit, offset int) ([]models.Barang, int, error)
 	FindByID(id int) (*models.Barang, error)
-	FindAllWithStok(search string, limit, offset int) ([]models.BarangWithStok, int, error)
+	FindAllWithStok(search string, kategoriID int, status string, limit, offset int) ([]models.BarangWithStok, int, error)
+	ForEach(search string, kategoriID int, status string, fn func(models.Barang) error) error
+	ForEachWithStok(search string, kategoriID int, status string, fn func(models.BarangWithStok) error) error
+	FindWithStokByID(id int) (*models.BarangWithStok, error)
+	FindByBarcode(code string) (*models.Barang, error)
+	FindByKodeBarang(kode string) (*models.Barang, error)
 	Create(barang *models.Barang) error
-	Update(barang *models.Barang) error
+	CreateTx(tx *sql.Tx, barang *models.Barang) error
+	FindByIDForUpdate(tx *sql.Tx, id int) (*models.Barang, error)
+	Update(tx *sql.Tx, barang *models.Barang) error
+	Archive(id, userID int) error
+	Restore(id int) error
 	Delete(id int) error
 	GenerateKodeBarang() (string, error)
 }
@@ -24,27 +33,49 @@ func NewBarangRepository(db *sql.DB) BarangRepository {
 	return &barangRepository{db: db}
 }
 
-func (r *barangRepository) FindAll(search string, limit, offset int) ([]models.Barang, int, error) {
+// kategoriFilter matches barang in kategori $2 or any of its descendants, or
+// every barang when $2 is 0
+const kategoriFilter = `($2 = 0 OR kategori_id IN (
+	              WITH RECURSIVE sub AS (
+	                  SELECT id FROM kategori WHERE id = $2
+	                  UNION ALL
+	                  SELECT k.id FROM kategori k JOIN sub ON k.parent_id = sub.id
+	              ) SELECT id FROM sub))`
+
+// statusFilter matches active barang, archived barang or both for $3 =
+// 'active', 'archived' or 'all'
+const statusFilter = `($3 = 'all' OR (archived_at IS NOT NULL) = ($3 = 'archived'))`
+
+// kategoriValues yields the kategori name and kategori_id columns: the name of
+// kategori idParam when set, or else the text of nameParam with the id of the
+// one kategori carrying that name (if exactly one does)
+func kategoriValues(nameParam, idParam string) string {
+	return `COALESCE((SELECT nama FROM kategori WHERE id = ` + idParam + `::int), ` + nameParam + `),
+	          COALESCE(` + idParam + `::int, (SELECT MIN(id) FROM kategori WHERE LOWER(nama) = LOWER(` + nameParam + `)
+	                   HAVING COUNT(*) = 1))`
+}
+
+func (r *barangRepository) FindAll(search string, kategoriID int, status string, limit, offset int) ([]models.Barang, int, error) {
 	var barangs []models.Barang
 	var total int
 
 	// Count total
 	countQuery := `SELECT COUNT(*) FROM master_barang WHERE 
-	               nama_barang ILIKE $1 OR kode_barang ILIKE $1`
+	               (nama_barang ILIKE $1 OR kode_barang ILIKE $1) AND ` + kategoriFilter + ` AND ` + statusFilter
 	searchPattern := "%" + search + "%"
-	err := r.db.QueryRow(countQuery, searchPattern).Scan(&total)
+	err := r.db.QueryRow(countQuery, searchPattern, kategoriID, status).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get data with pagination
-	query := `SELECT id, kode_barang, nama_barang, kategori, satuan, 
-	          harga_beli, harga_jual, created_at, updated_at 
+	query := `SELECT id, kode_barang, nama_barang, kategori, kategori_id, satuan, 
+	          harga_beli, harga_jual, is_kit, archived_at, created_at, updated_at 
 	          FROM master_barang 
-	          WHERE nama_barang ILIKE $1 OR kode_barang ILIKE $1
-	          ORDER BY id DESC LIMIT $2 OFFSET $3`
+	          WHERE (nama_barang ILIKE $1 OR kode_barang ILIKE $1) AND ` + kategoriFilter + ` AND ` + statusFilter + `
+	          ORDER BY id DESC LIMIT $4 OFFSET $5`
 
-	rows, err := r.db.Query(query, searchPattern, limit, offset)
+	rows, err := r.db.Query(query, searchPattern, kategoriID, status, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -52,8 +83,8 @@ func (r *barangRepository) FindAll(search string, limit, offset int) ([]models.B
 
 	for rows.Next() {
 		var b models.Barang
-		err := rows.Scan(&b.ID, &b.KodeBarang, &b.NamaBarang, &b.Kategori,
-			&b.Satuan, &b.HargaBeli, &b.HargaJual, &b.CreatedAt, &b.UpdatedAt)
+		err := rows.Scan(&b.ID, &b.KodeBarang, &b.NamaBarang, &b.Kategori, &b.KategoriID,
+			&b.Satuan, &b.HargaBeli, &b.HargaJual, &b.IsKit, &b.ArchivedAt, &b.CreatedAt, &b.UpdatedAt)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -63,15 +94,45 @@ func (r *barangRepository) FindAll(search string, limit, offset int) ([]models.B
 	return barangs, total, nil
 }
 
+// ForEach calls fn for every barang matching search, kategoriID and status without
+// loading them all into memory, stopping at the first error
+func (r *barangRepository) ForEach(search string, kategoriID int, status string, fn func(models.Barang) error) error {
+	query := `SELECT id, kode_barang, nama_barang, kategori, kategori_id, satuan, 
+	          harga_beli, harga_jual, is_kit, archived_at, created_at, updated_at 
+	          FROM master_barang 
+	          WHERE (nama_barang ILIKE $1 OR kode_barang ILIKE $1) AND ` + kategoriFilter + ` AND ` + statusFilter + `
+	          ORDER BY id DESC`
+
+	rows, err := r.db.Query(query, "%"+search+"%", kategoriID, status)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.Barang
+		err := rows.Scan(&b.ID, &b.KodeBarang, &b.NamaBarang, &b.Kategori, &b.KategoriID,
+			&b.Satuan, &b.HargaBeli, &b.HargaJual, &b.IsKit, &b.ArchivedAt, &b.CreatedAt, &b.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func (r *barangRepository) FindByID(id int) (*models.Barang, error) {
 	barang := &models.Barang{}
-	query := `SELECT id, kode_barang, nama_barang, kategori, satuan, 
-	          harga_beli, harga_jual, created_at, updated_at 
+	query := `SELECT id, kode_barang, nama_barang, kategori, kategori_id, satuan, 
+	          harga_beli, harga_jual, is_kit, archived_at, created_at, updated_at 
 	          FROM master_barang WHERE id = $1`
 
 	err := r.db.QueryRow(query, id).Scan(
-		&barang.ID, &barang.KodeBarang, &barang.NamaBarang, &barang.Kategori,
-		&barang.Satuan, &barang.HargaBeli, &barang.HargaJual,
+		&barang.ID, &barang.KodeBarang, &barang.NamaBarang, &barang.Kategori, &barang.KategoriID,
+		&barang.Satuan, &barang.HargaBeli, &barang.HargaJual, &barang.IsKit, &barang.ArchivedAt,
 		&barang.CreatedAt, &barang.UpdatedAt,
 	)
 
@@ -85,32 +146,40 @@ func (r *barangRepository) FindByID(id int) (*models.Barang, error) {
 	return barang, nil
 }
 
-func (r *barangRepository) FindAllWithStok(search string, limit, offset int) ([]models.BarangWithStok, int, error) {
+// barangWithStokColumns selects a barang with its stock movement totals; a kit's
+// stok_akhir is the number of complete kits its component stock can build
+const barangWithStokColumns = `b.id, b.kode_barang, b.nama_barang, b.kategori, b.kategori_id, b.satuan,
+	          b.harga_beli, b.harga_jual, b.is_kit, b.archived_at, b.created_at, b.updated_at,
+	          COALESCE((SELECT SUM(h.qty) FROM history_stok h WHERE h.barang_id = b.id AND h.jenis_transaksi = 'masuk'), 0) as qty_masuk,
+	          COALESCE((SELECT SUM(h.qty) FROM history_stok h WHERE h.barang_id = b.id AND h.jenis_transaksi = 'keluar'), 0) as qty_keluar,
+	          CASE WHEN b.is_kit THEN
+	              COALESCE((SELECT MIN(COALESCE(ks.stok_akhir, 0) / k.qty) FROM komponen_kit k
+	                        LEFT JOIN mstok ks ON ks.barang_id = k.komponen_barang_id
+	                        WHERE k.kit_barang_id = b.id), 0)
+	          ELSE COALESCE(s.stok_akhir, 0) END as stok_akhir`
+
+func (r *barangRepository) FindAllWithStok(search string, kategoriID int, status string, limit, offset int) ([]models.BarangWithStok, int, error) {
 	var barangs []models.BarangWithStok
 	var total int
 
 	// Count total
 	countQuery := `SELECT COUNT(*) FROM master_barang b
 	               LEFT JOIN mstok s ON b.id = s.barang_id
-	               WHERE b.nama_barang ILIKE $1 OR b.kode_barang ILIKE $1`
+	               WHERE (b.nama_barang ILIKE $1 OR b.kode_barang ILIKE $1) AND ` + kategoriFilter + ` AND ` + statusFilter
 	searchPattern := "%" + search + "%"
-	err := r.db.QueryRow(countQuery, searchPattern).Scan(&total)
+	err := r.db.QueryRow(countQuery, searchPattern, kategoriID, status).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get data with pagination
-	query := `SELECT b.id, b.kode_barang, b.nama_barang, b.kategori, b.satuan,
-	          b.harga_beli, b.harga_jual, b.created_at, b.updated_at,
-	          COALESCE((SELECT SUM(h.qty) FROM history_stok h WHERE h.barang_id = b.id AND h.jenis_transaksi = 'masuk'), 0) as qty_masuk,
-	          COALESCE((SELECT SUM(h.qty) FROM history_stok h WHERE h.barang_id = b.id AND h.jenis_transaksi = 'keluar'), 0) as qty_keluar,
-	          COALESCE(s.stok_akhir, 0) as stok_akhir
+	query := `SELECT ` + barangWithStokColumns + `
 	          FROM master_barang b
 	          LEFT JOIN mstok s ON b.id = s.barang_id
-	          WHERE b.nama_barang ILIKE $1 OR b.kode_barang ILIKE $1
-	          ORDER BY b.id DESC LIMIT $2 OFFSET $3`
+	          WHERE (b.nama_barang ILIKE $1 OR b.kode_barang ILIKE $1) AND ` + kategoriFilter + ` AND ` + statusFilter + `
+	          ORDER BY b.id DESC LIMIT $4 OFFSET $5`
 
-	rows, err := r.db.Query(query, searchPattern, limit, offset)
+	rows, err := r.db.Query(query, searchPattern, kategoriID, status, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -118,8 +187,8 @@ func (r *barangRepository) FindAllWithStok(search string, limit, offset int) ([]
 
 	for rows.Next() {
 		var b models.BarangWithStok
-		err := rows.Scan(&b.ID, &b.KodeBarang, &b.NamaBarang, &b.Kategori,
-			&b.Satuan, &b.HargaBeli, &b.HargaJual, &b.CreatedAt, &b.UpdatedAt,
+		err := rows.Scan(&b.ID, &b.KodeBarang, &b.NamaBarang, &b.Kategori, &b.KategoriID,
+			&b.Satuan, &b.HargaBeli, &b.HargaJual, &b.IsKit, &b.ArchivedAt, &b.CreatedAt, &b.UpdatedAt,
 			&b.QtyMasuk, &b.QtyKeluar, &b.StokAkhir)
 		if err != nil {
 			return nil, 0, err
@@ -130,12 +199,121 @@ func (r *barangRepository) FindAllWithStok(search string, limit, offset int) ([]
 	return barangs, total, nil
 }
 
+// ForEachWithStok calls fn for every barang with stock matching search,
+// kategoriID and status, stopping at the first error
+func (r *barangRepository) ForEachWithStok(search string, kategoriID int, status string, fn func(models.BarangWithStok) error) error {
+	query := `SELECT ` + barangWithStokColumns + `
+	          FROM master_barang b
+	          LEFT JOIN mstok s ON b.id = s.barang_id
+	          WHERE (b.nama_barang ILIKE $1 OR b.kode_barang ILIKE $1) AND ` + kategoriFilter + ` AND ` + statusFilter + `
+	          ORDER BY b.id DESC`
+
+	rows, err := r.db.Query(query, "%"+search+"%", kategoriID, status)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.BarangWithStok
+		err := rows.Scan(&b.ID, &b.KodeBarang, &b.NamaBarang, &b.Kategori, &b.KategoriID,
+			&b.Satuan, &b.HargaBeli, &b.HargaJual, &b.IsKit, &b.ArchivedAt, &b.CreatedAt, &b.UpdatedAt,
+			&b.QtyMasuk, &b.QtyKeluar, &b.StokAkhir)
+		if err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *barangRepository) FindWithStokByID(id int) (*models.BarangWithStok, error) {
+	b := &models.BarangWithStok{}
+	query := `SELECT ` + barangWithStokColumns + `
+	          FROM master_barang b
+	          LEFT JOIN mstok s ON b.id = s.barang_id
+	          WHERE b.id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(&b.ID, &b.KodeBarang, &b.NamaBarang, &b.Kategori, &b.KategoriID,
+		&b.Satuan, &b.HargaBeli, &b.HargaJual, &b.IsKit, &b.ArchivedAt, &b.CreatedAt, &b.UpdatedAt,
+		&b.QtyMasuk, &b.QtyKeluar, &b.StokAkhir)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("barang not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// FindByBarcode resolves a scanned code, trying registered barcodes before kode_barang
+func (r *barangRepository) FindByBarcode(code string) (*models.Barang, error) {
+	barang := &models.Barang{}
+	query := `SELECT b.id, b.kode_barang, b.nama_barang, b.kategori, b.kategori_id, b.satuan,
+	          b.harga_beli, b.harga_jual, b.is_kit, b.archived_at, b.created_at, b.updated_at
+	          FROM master_barang b
+	          LEFT JOIN barcode_barang bc ON bc.barang_id = b.id AND bc.barcode = $1
+	          WHERE bc.id IS NOT NULL OR b.kode_barang = $1
+	          ORDER BY bc.id IS NULL
+	          LIMIT 1`
+
+	err := r.db.QueryRow(query, code).Scan(
+		&barang.ID, &barang.KodeBarang, &barang.NamaBarang, &barang.Kategori, &barang.KategoriID,
+		&barang.Satuan, &barang.HargaBeli, &barang.HargaJual, &barang.IsKit, &barang.ArchivedAt,
+		&barang.CreatedAt, &barang.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("barang not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return barang, nil
+}
+
+func (r *barangRepository) FindByKodeBarang(kode string) (*models.Barang, error) {
+	barang := &models.Barang{}
+	query := `SELECT id, kode_barang, nama_barang, kategori, kategori_id, satuan, 
+	          harga_beli, harga_jual, is_kit, archived_at, created_at, updated_at 
+	          FROM master_barang WHERE kode_barang = $1`
+
+	err := r.db.QueryRow(query, kode).Scan(
+		&barang.ID, &barang.KodeBarang, &barang.NamaBarang, &barang.Kategori, &barang.KategoriID,
+		&barang.Satuan, &barang.HargaBeli, &barang.HargaJual, &barang.IsKit, &barang.ArchivedAt,
+		&barang.CreatedAt, &barang.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("barang not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return barang, nil
+}
+
 func (r *barangRepository) GenerateKodeBarang() (string, error) {
+	return generateKodeBarang(r.db)
+}
+
+// generateKodeBarang runs on the db or inside a tx, so codes generated in a
+// tx see the rows it has already inserted
+func generateKodeBarang(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}) (string, error) {
 	var lastNumber int
 	query := `SELECT COALESCE(MAX(CAST(SUBSTRING(kode_barang FROM 4) AS INTEGER)), 0) 
 	          FROM master_barang WHERE kode_barang ~ '^BRG[0-9]+$'`
 
-	err := r.db.QueryRow(query).Scan(&lastNumber)
+	err := q.QueryRow(query).Scan(&lastNumber)
 	if err != nil {
 		return "", err
 	}
@@ -144,6 +322,24 @@ func (r *barangRepository) GenerateKodeBarang() (string, error) {
 	return fmt.Sprintf("BRG%03d", nextNumber), nil
 }
 
+// checkKodeBarang rejects a kode_barang that is already some barang's barcode;
+// scan lookup tries barcodes first, so the new barang could never be scanned
+func checkKodeBarang(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, kode string) error {
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM barcode_barang WHERE barcode = $1)`
+
+	if err := q.QueryRow(query, kode).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("kode barang %s already exists as a barcode", kode)
+	}
+
+	return nil
+}
+
 func (r *barangRepository) Create(barang *models.Barang) error {
 	// Auto-generate kode barang if empty
 	if barang.KodeBarang == "" {
@@ -153,22 +349,87 @@ func (r *barangRepository) Create(barang *models.Barang) error {
 		}
 		barang.KodeBarang = kode
 	}
+	if err := checkKodeBarang(r.db, barang.KodeBarang); err != nil {
+		return err
+	}
 
-	query := `INSERT INTO master_barang (kode_barang, nama_barang, kategori, satuan, harga_beli, harga_jual)
-	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	query := `INSERT INTO master_barang (kode_barang, nama_barang, kategori, kategori_id, satuan, harga_beli, harga_jual, is_kit)
+	          VALUES ($1, $2, ` + kategoriValues("$3", "$8") + `, $4, $5, $6, $7)
+	          RETURNING id, kategori, kategori_id, created_at, updated_at`
 
 	return r.db.QueryRow(query, barang.KodeBarang, barang.NamaBarang, barang.Kategori,
-		barang.Satuan, barang.HargaBeli, barang.HargaJual).Scan(
-		&barang.ID, &barang.CreatedAt, &barang.UpdatedAt,
+		barang.Satuan, barang.HargaBeli, barang.HargaJual, barang.IsKit, barang.KategoriID).Scan(
+		&barang.ID, &barang.Kategori, &barang.KategoriID, &barang.CreatedAt, &barang.UpdatedAt,
+	)
+}
+
+// CreateTx inserts a barang inside tx, generating kode_barang when empty; like
+// Create it refuses a kode_barang that is already a barcode
+func (r *barangRepository) CreateTx(tx *sql.Tx, barang *models.Barang) error {
+	if barang.KodeBarang == "" {
+		kode, err := generateKodeBarang(tx)
+		if err != nil {
+			return err
+		}
+		barang.KodeBarang = kode
+	}
+	if err := checkKodeBarang(tx, barang.KodeBarang); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO master_barang (kode_barang, nama_barang, kategori, kategori_id, satuan, harga_beli, harga_jual, is_kit)
+	          VALUES ($1, $2, ` + kategoriValues("$3", "$8") + `, $4, $5, $6, $7)
+	          RETURNING id, kategori, kategori_id, created_at, updated_at`
+
+	return tx.QueryRow(query, barang.KodeBarang, barang.NamaBarang, barang.Kategori,
+		barang.Satuan, barang.HargaBeli, barang.HargaJual, barang.IsKit, barang.KategoriID).Scan(
+		&barang.ID, &barang.Kategori, &barang.KategoriID, &barang.CreatedAt, &barang.UpdatedAt,
+	)
+}
+
+// FindByIDForUpdate loads a barang and locks its row until tx ends
+func (r *barangRepository) FindByIDForUpdate(tx *sql.Tx, id int) (*models.Barang, error) {
+	barang := &models.Barang{}
+	query := `SELECT id, kode_barang, nama_barang, kategori, kategori_id, satuan, 
+	          harga_beli, harga_jual, is_kit, archived_at, created_at, updated_at 
+	          FROM master_barang WHERE id = $1 FOR UPDATE`
+
+	err := tx.QueryRow(query, id).Scan(
+		&barang.ID, &barang.KodeBarang, &barang.NamaBarang, &barang.Kategori, &barang.KategoriID,
+		&barang.Satuan, &barang.HargaBeli, &barang.HargaJual, &barang.IsKit, &barang.ArchivedAt,
+		&barang.CreatedAt, &barang.UpdatedAt,
 	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("barang not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return barang, nil
 }
 
-func (r *barangRepository) Update(barang *models.Barang) error {
-	query := `UPDATE master_barang SET nama_barang = $1, kategori = $2, satuan = $3,
-	          harga_beli = $4, harga_jual = $5 WHERE id = $6`
+func (r *barangRepository) Update(tx *sql.Tx, barang *models.Barang) error {
+	query := `UPDATE master_barang SET nama_barang = $1, (kategori, kategori_id) = (SELECT ` + kategoriValues("$2", "$7") + `),
+	          satuan = $3, harga_beli = $4, harga_jual = $5 WHERE id = $6
+	          RETURNING kategori, kategori_id`
 
-	result, err := r.db.Exec(query, barang.NamaBarang, barang.Kategori, barang.Satuan,
-		barang.HargaBeli, barang.HargaJual, barang.ID)
+	err := tx.QueryRow(query, barang.NamaBarang, barang.Kategori, barang.Satuan,
+		barang.HargaBeli, barang.HargaJual, barang.ID, barang.KategoriID).Scan(&barang.Kategori, &barang.KategoriID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("barang not found")
+	}
+
+	return err
+}
+
+// Archive hides a barang from lists and blocks new transactions on it while
+// keeping its stock, history and documents
+func (r *barangRepository) Archive(id, userID int) error {
+	query := `UPDATE master_barang SET archived_at = CURRENT_TIMESTAMP, archived_by = $2
+	          WHERE id = $1 AND archived_at IS NULL`
+	result, err := r.db.Exec(query, id, userID)
 	if err != nil {
 		return err
 	}
@@ -179,14 +440,15 @@ func (r *barangRepository) Update(barang *models.Barang) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("barang not found")
+		return fmt.Errorf("barang is already archived")
 	}
 
 	return nil
 }
 
-func (r *barangRepository) Delete(id int) error {
-	query := `DELETE FROM master_barang WHERE id = $1`
+func (r *barangRepository) Restore(id int) error {
+	query := `UPDATE master_barang SET archived_at = NULL, archived_by = NULL
+	          WHERE id = $1 AND archived_at IS NOT NULL`
 	result, err := r.db.Exec(query, id)
 	if err != nil {
 		return err
@@ -197,6 +459,46 @@ func (r *barangRepository) Delete(id int) error {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return fmt.Errorf("barang is not archived")
+	}
+
+	return nil
+}
+
+// Delete removes a barang that has never been transacted. Anything with stock
+// history, purchases, sales or production must be archived instead
+func (r *barangRepository) Delete(id int) error {
+	var transacted, referenced bool
+	query := `SELECT
+	          EXISTS (SELECT 1 FROM history_stok WHERE barang_id = $1)
+	          OR EXISTS (SELECT 1 FROM beli_detail WHERE barang_id = $1)
+	          OR EXISTS (SELECT 1 FROM jual_detail WHERE barang_id = $1)
+	          OR EXISTS (SELECT 1 FROM produksi_header WHERE barang_id = $1)
+	          OR EXISTS (SELECT 1 FROM produksi_komponen WHERE komponen_barang_id = $1),
+	          EXISTS (SELECT 1 FROM komponen_kit WHERE komponen_barang_id = $1)
+	          OR EXISTS (SELECT 1 FROM bom WHERE barang_id = $1)
+	          OR EXISTS (SELECT 1 FROM bom_detail WHERE komponen_barang_id = $1)`
+	if err := r.db.QueryRow(query, id).Scan(&transacted, &referenced); err != nil {
+		return err
+	}
+	if transacted {
+		return fmt.Errorf("barang has transactions")
+	}
+	if referenced {
+		return fmt.Errorf("barang is used by a kit or bom")
+	}
+
+	result, err := r.db.Exec(`DELETE FROM master_barang WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
 	if rowsAffected == 0 {
 		return fmt.Errorf("barang not found")
 	}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+
+	"github.com/lib/pq"
+)
+
+type BarcodeRepository interface {
+	FindByBarangID(barangID int) ([]models.BarcodeBarang, error)
+	SetBarcodes(barangID int, barcodes []string) ([]models.BarcodeBarang, error)
+}
+
+type barcodeRepository struct {
+	db *sql.DB
+}
+
+func NewBarcodeRepository(db *sql.DB) BarcodeRepository {
+	return &barcodeRepository{db: db}
+}
+
+func (r *barcodeRepository) FindByBarangID(barangID int) ([]models.BarcodeBarang, error) {
+	var barcodes []models.BarcodeBarang
+
+	query := `SELECT id, barang_id, barcode, created_at
+	          FROM barcode_barang WHERE barang_id = $1
+	          ORDER BY id`
+
+	rows, err := r.db.Query(query, barangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.BarcodeBarang
+		if err := rows.Scan(&b.ID, &b.BarangID, &b.Barcode, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		barcodes = append(barcodes, b)
+	}
+
+	return barcodes, nil
+}
+
+// SetBarcodes replaces every barcode of a barang in a single transaction. A
+// barcode may not belong to another barang or equal another barang's kode_barang,
+// since scan lookup falls back to kode_barang.
+func (r *barcodeRepository) SetBarcodes(barangID int, barcodes []string) ([]models.BarcodeBarang, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var taken string
+	checkQuery := `SELECT barcode FROM barcode_barang WHERE barcode = ANY($1) AND barang_id <> $2
+	               UNION ALL
+	               SELECT kode_barang FROM master_barang WHERE kode_barang = ANY($1) AND id <> $2
+	               LIMIT 1`
+	err = tx.QueryRow(checkQuery, pq.Array(barcodes), barangID).Scan(&taken)
+	if err == nil {
+		return nil, fmt.Errorf("barcode %s already exists", taken)
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM barcode_barang WHERE barang_id = $1`, barangID); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO barcode_barang (barang_id, barcode)
+	          VALUES ($1, $2) RETURNING id, created_at`
+
+	result := make([]models.BarcodeBarang, len(barcodes))
+	for i, code := range barcodes {
+		result[i].BarangID = barangID
+		result[i].Barcode = code
+		if err := tx.QueryRow(query, barangID, code).Scan(&result[i].ID, &result[i].CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
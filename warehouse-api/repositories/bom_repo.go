@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+type BOMRepository interface {
+	FindAll(barangID int) ([]models.BOM, error)
+	FindByID(id int) (*models.BOMWithDetail, error)
+	Create(bom *models.BOMWithDetail) error
+	Update(bom *models.BOMWithDetail) error
+	Delete(id int) error
+	GenerateKodeBOM() (string, error)
+}
+
+type bomRepository struct {
+	db *sql.DB
+}
+
+func NewBOMRepository(db *sql.DB) BOMRepository {
+	return &bomRepository{db: db}
+}
+
+// FindAll lists BOMs, optionally only those producing barangID (0 for all)
+func (r *bomRepository) FindAll(barangID int) ([]models.BOM, error) {
+	var boms []models.BOM
+
+	query := `SELECT m.id, m.kode_bom, m.barang_id, b.kode_barang, b.nama_barang,
+	          COALESCE(m.keterangan, ''), m.created_at, m.updated_at
+	          FROM bom m
+	          JOIN master_barang b ON m.barang_id = b.id
+	          WHERE $1 = 0 OR m.barang_id = $1
+	          ORDER BY m.kode_bom`
+
+	rows, err := r.db.Query(query, barangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.BOM
+		err := rows.Scan(&m.ID, &m.KodeBOM, &m.BarangID, &m.KodeBarang, &m.NamaBarang,
+			&m.Keterangan, &m.CreatedAt, &m.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		boms = append(boms, m)
+	}
+
+	return boms, nil
+}
+
+func (r *bomRepository) FindByID(id int) (*models.BOMWithDetail, error) {
+	// Get header
+	bom := &models.BOMWithDetail{}
+	query := `SELECT m.id, m.kode_bom, m.barang_id, b.kode_barang, b.nama_barang,
+	          COALESCE(m.keterangan, ''), m.created_at, m.updated_at
+	          FROM bom m
+	          JOIN master_barang b ON m.barang_id = b.id
+	          WHERE m.id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(
+		&bom.ID, &bom.KodeBOM, &bom.BarangID, &bom.KodeBarang, &bom.NamaBarang,
+		&bom.Keterangan, &bom.CreatedAt, &bom.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("bom not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Get details
+	queryDetail := `SELECT d.id, d.bom_id, d.komponen_barang_id, d.qty,
+	                b.kode_barang, b.nama_barang, b.satuan, b.harga_beli, b.archived_at
+	                FROM bom_detail d
+	                JOIN master_barang b ON d.komponen_barang_id = b.id
+	                WHERE d.bom_id = $1
+	                ORDER BY d.id`
+
+	rows, err := r.db.Query(queryDetail, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var details []models.BOMDetail
+	for rows.Next() {
+		var d models.BOMDetail
+		err := rows.Scan(&d.ID, &d.BomID, &d.KomponenBarangID, &d.Qty,
+			&d.KodeBarang, &d.NamaBarang, &d.Satuan, &d.HargaBeli, &d.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, d)
+	}
+
+	bom.Details = details
+	return bom, nil
+}
+
+func (r *bomRepository) GenerateKodeBOM() (string, error) {
+	var lastNumber int
+	query := `SELECT COALESCE(MAX(CAST(SUBSTRING(kode_bom FROM 4) AS INTEGER)), 0)
+	          FROM bom WHERE kode_bom ~ '^BOM[0-9]+$'`
+
+	err := r.db.QueryRow(query).Scan(&lastNumber)
+	if err != nil {
+		return "", err
+	}
+
+	nextNumber := lastNumber + 1
+	return fmt.Sprintf("BOM%03d", nextNumber), nil
+}
+
+func (r *bomRepository) Create(bom *models.BOMWithDetail) error {
+	// Auto-generate kode BOM if empty
+	if bom.KodeBOM == "" {
+		kode, err := r.GenerateKodeBOM()
+		if err != nil {
+			return err
+		}
+		bom.KodeBOM = kode
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO bom (kode_bom, barang_id, keterangan)
+	          VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(query, bom.KodeBOM, bom.BarangID, bom.Keterangan).Scan(
+		&bom.ID, &bom.CreatedAt, &bom.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := r.insertDetails(tx, bom); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update replaces the keterangan and all details of a BOM
+func (r *bomRepository) Update(bom *models.BOMWithDetail) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE bom SET keterangan = $1 WHERE id = $2`, bom.Keterangan, bom.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("bom not found")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM bom_detail WHERE bom_id = $1`, bom.ID); err != nil {
+		return err
+	}
+
+	if err := r.insertDetails(tx, bom); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *bomRepository) insertDetails(tx *sql.Tx, bom *models.BOMWithDetail) error {
+	query := `INSERT INTO bom_detail (bom_id, komponen_barang_id, qty)
+	          VALUES ($1, $2, $3) RETURNING id`
+
+	for i := range bom.Details {
+		bom.Details[i].BomID = bom.ID
+		err := tx.QueryRow(query, bom.ID, bom.Details[i].KomponenBarangID,
+			bom.Details[i].Qty).Scan(&bom.Details[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *bomRepository) Delete(id int) error {
+	// BOMs referenced by production orders must be kept for traceability
+	var used int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM produksi_header WHERE bom_id = $1`, id).Scan(&used); err != nil {
+		return err
+	}
+	if used > 0 {
+		return fmt.Errorf("bom is used by produksi")
+	}
+
+	query := `DELETE FROM bom WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("bom not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"database/sql"
+)
+
+type CetakDokumenRepository interface {
+	RecordCetak(tx *sql.Tx, jenis string, referensiID, userID int) (int, error)
+}
+
+type cetakDokumenRepository struct {
+	db *sql.DB
+}
+
+func NewCetakDokumenRepository(db *sql.DB) CetakDokumenRepository {
+	return &cetakDokumenRepository{db: db}
+}
+
+// RecordCetak counts one more print of a document and returns its print number
+// (1 for the original)
+func (r *cetakDokumenRepository) RecordCetak(tx *sql.Tx, jenis string, referensiID, userID int) (int, error) {
+	var cetakanKe int
+	query := `INSERT INTO cetak_dokumen (jenis, referensi_id, terakhir_dicetak_oleh)
+	          VALUES ($1, $2, $3)
+	          ON CONFLICT (jenis, referensi_id) DO UPDATE SET
+	          jumlah_cetak = cetak_dokumen.jumlah_cetak + 1,
+	          terakhir_dicetak_at = CURRENT_TIMESTAMP,
+	          terakhir_dicetak_oleh = EXCLUDED.terakhir_dicetak_oleh
+	          RETURNING jumlah_cetak`
+
+	err := tx.QueryRow(query, jenis, referensiID, userID).Scan(&cetakanKe)
+	return cetakanKe, err
+}
@@ -0,0 +1,226 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+// customerNameKey normalizes a customer name the same way as idx_customer_nama_key
+const customerNameKey = `LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(%s), '[.,]', '', 'g'), '\s+', ' ', 'g'))`
+
+type CustomerRepository interface {
+	FindAll(search string, limit, offset int) ([]models.Customer, int, error)
+	FindByID(id int) (*models.Customer, error)
+	FindByIDForUpdate(tx *sql.Tx, id int) (*models.Customer, error)
+	Create(customer *models.Customer) error
+	Update(customer *models.Customer) error
+	Delete(id int) error
+	GenerateKodeCustomer() (string, error)
+}
+
+type customerRepository struct {
+	db *sql.DB
+}
+
+func NewCustomerRepository(db *sql.DB) CustomerRepository {
+	return &customerRepository{db: db}
+}
+
+func (r *customerRepository) FindAll(search string, limit, offset int) ([]models.Customer, int, error) {
+	var customers []models.Customer
+	var total int
+
+	// Count total
+	countQuery := `SELECT COUNT(*) FROM customer WHERE 
+	               nama_customer ILIKE $1 OR kode_customer ILIKE $1`
+	searchPattern := "%" + search + "%"
+	err := r.db.QueryRow(countQuery, searchPattern).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get data with pagination
+	query := `SELECT c.id, c.kode_customer, c.nama_customer, COALESCE(c.alamat, ''), COALESCE(c.telepon, ''),
+	          COALESCE(c.npwp, ''), c.limit_kredit, c.price_list_id,
+	          COALESCE((SELECT SUM(h.total - h.terbayar) FROM jual_header h WHERE h.customer_id = c.id), 0) as piutang,
+	          c.created_at, c.updated_at
+	          FROM customer c
+	          WHERE c.nama_customer ILIKE $1 OR c.kode_customer ILIKE $1
+	          ORDER BY c.nama_customer ASC LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, searchPattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.Customer
+		err := rows.Scan(&c.ID, &c.KodeCustomer, &c.NamaCustomer, &c.Alamat, &c.Telepon,
+			&c.NPWP, &c.LimitKredit, &c.PriceListID, &c.Piutang, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		customers = append(customers, c)
+	}
+
+	return customers, total, nil
+}
+
+// customerByIDQuery loads one customer with their outstanding piutang
+const customerByIDQuery = `SELECT c.id, c.kode_customer, c.nama_customer, COALESCE(c.alamat, ''), COALESCE(c.telepon, ''),
+	          COALESCE(c.npwp, ''), c.limit_kredit, c.price_list_id,
+	          COALESCE((SELECT SUM(h.total - h.terbayar) FROM jual_header h WHERE h.customer_id = c.id), 0) as piutang,
+	          c.created_at, c.updated_at
+	          FROM customer c WHERE c.id = $1`
+
+func scanCustomer(row interface{ Scan(...interface{}) error }) (*models.Customer, error) {
+	customer := &models.Customer{}
+	err := row.Scan(
+		&customer.ID, &customer.KodeCustomer, &customer.NamaCustomer, &customer.Alamat,
+		&customer.Telepon, &customer.NPWP, &customer.LimitKredit, &customer.PriceListID, &customer.Piutang,
+		&customer.CreatedAt, &customer.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("customer not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+func (r *customerRepository) FindByID(id int) (*models.Customer, error) {
+	return scanCustomer(r.db.QueryRow(customerByIDQuery, id))
+}
+
+// FindByIDForUpdate locks the customer until tx ends, so sales to them are
+// checked against the credit limit one at a time. Piutang is read by a
+// separate statement after the lock is granted, so it includes every sale
+// committed while waiting
+func (r *customerRepository) FindByIDForUpdate(tx *sql.Tx, id int) (*models.Customer, error) {
+	var lockedID int
+	err := tx.QueryRow(`SELECT id FROM customer WHERE id = $1 FOR UPDATE`, id).Scan(&lockedID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("customer not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCustomer(tx.QueryRow(customerByIDQuery, id))
+}
+
+func (r *customerRepository) GenerateKodeCustomer() (string, error) {
+	var lastNumber int
+	query := `SELECT COALESCE(MAX(CAST(SUBSTRING(kode_customer FROM 4) AS INTEGER)), 0) 
+	          FROM customer WHERE kode_customer ~ '^CUS[0-9]+$'`
+
+	err := r.db.QueryRow(query).Scan(&lastNumber)
+	if err != nil {
+		return "", err
+	}
+
+	nextNumber := lastNumber + 1
+	return fmt.Sprintf("CUS%03d", nextNumber), nil
+}
+
+// nameTaken reports whether another customer already uses an equivalent name
+func (r *customerRepository) nameTaken(nama string, excludeID int) (bool, error) {
+	var count int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM customer WHERE %s = %s AND id <> $2`,
+		fmt.Sprintf(customerNameKey, "nama_customer"), fmt.Sprintf(customerNameKey, "$1"))
+
+	if err := r.db.QueryRow(query, nama, excludeID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *customerRepository) Create(customer *models.Customer) error {
+	taken, err := r.nameTaken(customer.NamaCustomer, 0)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("customer already exists")
+	}
+
+	// Auto-generate kode customer if empty
+	if customer.KodeCustomer == "" {
+		kode, err := r.GenerateKodeCustomer()
+		if err != nil {
+			return err
+		}
+		customer.KodeCustomer = kode
+	}
+
+	query := `INSERT INTO customer (kode_customer, nama_customer, alamat, telepon, npwp, limit_kredit, price_list_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query, customer.KodeCustomer, customer.NamaCustomer, customer.Alamat,
+		customer.Telepon, customer.NPWP, customer.LimitKredit, customer.PriceListID).Scan(
+		&customer.ID, &customer.CreatedAt, &customer.UpdatedAt,
+	)
+}
+
+func (r *customerRepository) Update(customer *models.Customer) error {
+	taken, err := r.nameTaken(customer.NamaCustomer, customer.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("customer already exists")
+	}
+
+	query := `UPDATE customer SET nama_customer = $1, alamat = $2, telepon = $3,
+	          npwp = $4, limit_kredit = $5, price_list_id = $6 WHERE id = $7`
+
+	result, err := r.db.Exec(query, customer.NamaCustomer, customer.Alamat, customer.Telepon,
+		customer.NPWP, customer.LimitKredit, customer.PriceListID, customer.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("customer not found")
+	}
+
+	return nil
+}
+
+func (r *customerRepository) Delete(id int) error {
+	// Customers referenced by penjualan must be kept for reporting
+	var used int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM jual_header WHERE customer_id = $1`, id).Scan(&used); err != nil {
+		return err
+	}
+	if used > 0 {
+		return fmt.Errorf("customer is used by penjualan")
+	}
+
+	query := `DELETE FROM customer WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("customer not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+type HargaBarangRepository interface {
+	InsertHistory(tx *sql.Tx, history *models.HistoryHargaBarang) error
+	GetHistoryByBarangID(barangID int, limit, offset int) ([]models.HistoryHargaBarang, int, error)
+	CreateJadwal(jadwal *models.JadwalHargaBarang) error
+	FindJadwalByBarangID(barangID int) ([]models.JadwalHargaBarang, error)
+	CancelJadwal(barangID, jadwalID int) error
+	LockDueJadwal(tx *sql.Tx) ([]models.JadwalHargaBarang, error)
+	MarkJadwalApplied(tx *sql.Tx, jadwalID int) error
+}
+
+type hargaBarangRepository struct {
+	db *sql.DB
+}
+
+func NewHargaBarangRepository(db *sql.DB) HargaBarangRepository {
+	return &hargaBarangRepository{db: db}
+}
+
+func (r *hargaBarangRepository) InsertHistory(tx *sql.Tx, history *models.HistoryHargaBarang) error {
+	query := `INSERT INTO history_harga_barang (barang_id, harga_beli_lama, harga_beli_baru,
+	          harga_jual_lama, harga_jual_baru, keterangan, jadwal_id, changed_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`
+
+	return tx.QueryRow(query, history.BarangID, history.HargaBeliLama, history.HargaBeliBaru,
+		history.HargaJualLama, history.HargaJualBaru, history.Keterangan, history.JadwalID,
+		history.ChangedBy).Scan(&history.ID, &history.CreatedAt)
+}
+
+func (r *hargaBarangRepository) GetHistoryByBarangID(barangID int, limit, offset int) ([]models.HistoryHargaBarang, int, error) {
+	var histories []models.HistoryHargaBarang
+	var total int
+
+	// Count total
+	countQuery := `SELECT COUNT(*) FROM history_harga_barang WHERE barang_id = $1`
+	err := r.db.QueryRow(countQuery, barangID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get data
+	query := `SELECT h.id, h.barang_id, h.harga_beli_lama, h.harga_beli_baru, h.harga_jual_lama,
+	          h.harga_jual_baru, COALESCE(h.keterangan, ''), h.jadwal_id, h.changed_by,
+	          COALESCE(u.nama, ''), h.created_at
+	          FROM history_harga_barang h
+	          LEFT JOIN users u ON h.changed_by = u.id
+	          WHERE h.barang_id = $1
+	          ORDER BY h.created_at DESC, h.id DESC LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, barangID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.HistoryHargaBarang
+		err := rows.Scan(&h.ID, &h.BarangID, &h.HargaBeliLama, &h.HargaBeliBaru,
+			&h.HargaJualLama, &h.HargaJualBaru, &h.Keterangan, &h.JadwalID, &h.ChangedBy,
+			&h.ChangedByNama, &h.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		histories = append(histories, h)
+	}
+
+	return histories, total, nil
+}
+
+func (r *hargaBarangRepository) CreateJadwal(jadwal *models.JadwalHargaBarang) error {
+	query := `INSERT INTO jadwal_harga_barang (barang_id, harga_beli, harga_jual, berlaku_mulai, created_by)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id, status, created_at`
+
+	return r.db.QueryRow(query, jadwal.BarangID, jadwal.HargaBeli, jadwal.HargaJual,
+		jadwal.BerlakuMulai, jadwal.CreatedBy).Scan(&jadwal.ID, &jadwal.Status, &jadwal.CreatedAt)
+}
+
+func (r *hargaBarangRepository) FindJadwalByBarangID(barangID int) ([]models.JadwalHargaBarang, error) {
+	var jadwals []models.JadwalHargaBarang
+
+	query := `SELECT id, barang_id, harga_beli, harga_jual, berlaku_mulai, status,
+	          created_by, applied_at, created_at
+	          FROM jadwal_harga_barang WHERE barang_id = $1
+	          ORDER BY berlaku_mulai DESC, id DESC`
+
+	rows, err := r.db.Query(query, barangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var j models.JadwalHargaBarang
+		err := rows.Scan(&j.ID, &j.BarangID, &j.HargaBeli, &j.HargaJual, &j.BerlakuMulai,
+			&j.Status, &j.CreatedBy, &j.AppliedAt, &j.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		jadwals = append(jadwals, j)
+	}
+
+	return jadwals, nil
+}
+
+func (r *hargaBarangRepository) CancelJadwal(barangID, jadwalID int) error {
+	query := `UPDATE jadwal_harga_barang SET status = 'cancelled'
+	          WHERE id = $1 AND barang_id = $2 AND status = 'pending'`
+
+	result, err := r.db.Exec(query, jadwalID, barangID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("pending jadwal harga not found")
+	}
+
+	return nil
+}
+
+// LockDueJadwal returns pending schedules whose effective date has passed, oldest
+// first, locking them so concurrent schedulers do not apply them twice
+func (r *hargaBarangRepository) LockDueJadwal(tx *sql.Tx) ([]models.JadwalHargaBarang, error) {
+	var jadwals []models.JadwalHargaBarang
+
+	query := `SELECT id, barang_id, harga_beli, harga_jual, berlaku_mulai, status,
+	          created_by, applied_at, created_at
+	          FROM jadwal_harga_barang
+	          WHERE status = 'pending' AND berlaku_mulai <= CURRENT_TIMESTAMP
+	          ORDER BY berlaku_mulai, id
+	          FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var j models.JadwalHargaBarang
+		err := rows.Scan(&j.ID, &j.BarangID, &j.HargaBeli, &j.HargaJual, &j.BerlakuMulai,
+			&j.Status, &j.CreatedBy, &j.AppliedAt, &j.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		jadwals = append(jadwals, j)
+	}
+
+	return jadwals, rows.Err()
+}
+
+func (r *hargaBarangRepository) MarkJadwalApplied(tx *sql.Tx, jadwalID int) error {
+	query := `UPDATE jadwal_harga_barang SET status = 'applied', applied_at = CURRENT_TIMESTAMP
+	          WHERE id = $1`
+
+	_, err := tx.Exec(query, jadwalID)
+	return err
+}
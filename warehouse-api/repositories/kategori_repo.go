@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+type KategoriRepository interface {
+	FindAll() ([]models.Kategori, error)
+	FindByID(id int) (*models.Kategori, error)
+	Create(kategori *models.Kategori) error
+	Update(kategori *models.Kategori) error
+	Delete(id int) error
+	IsDescendant(id, ancestorID int) (bool, error)
+}
+
+type kategoriRepository struct {
+	db *sql.DB
+}
+
+func NewKategoriRepository(db *sql.DB) KategoriRepository {
+	return &kategoriRepository{db: db}
+}
+
+// kategoriTreeQuery walks the hierarchy from the roots, building each
+// kategori's path and depth; rows are ordered by path
+const kategoriTreeQuery = `WITH RECURSIVE tree AS (
+	              SELECT id, nama::text AS path, 0 AS depth FROM kategori WHERE parent_id IS NULL
+	              UNION ALL
+	              SELECT k.id, tree.path || ' / ' || k.nama, tree.depth + 1
+	              FROM kategori k JOIN tree ON k.parent_id = tree.id
+	          )
+	          SELECT k.id, k.nama, k.parent_id, t.path, t.depth,
+	          (SELECT COUNT(*) FROM master_barang b WHERE b.kategori_id = k.id) as jumlah_barang,
+	          k.created_at, k.updated_at
+	          FROM tree t
+	          JOIN kategori k ON k.id = t.id`
+
+func scanKategori(row interface{ Scan(...interface{}) error }, k *models.Kategori) error {
+	return row.Scan(&k.ID, &k.Nama, &k.ParentID, &k.Path, &k.Depth, &k.JumlahBarang,
+		&k.CreatedAt, &k.UpdatedAt)
+}
+
+func (r *kategoriRepository) FindAll() ([]models.Kategori, error) {
+	kategoris := []models.Kategori{}
+
+	rows, err := r.db.Query(kategoriTreeQuery + ` ORDER BY t.path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k models.Kategori
+		if err := scanKategori(rows, &k); err != nil {
+			return nil, err
+		}
+		kategoris = append(kategoris, k)
+	}
+
+	return kategoris, nil
+}
+
+func (r *kategoriRepository) FindByID(id int) (*models.Kategori, error) {
+	kategori := &models.Kategori{}
+
+	err := scanKategori(r.db.QueryRow(kategoriTreeQuery+` WHERE k.id = $1`, id), kategori)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("kategori not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return kategori, nil
+}
+
+// nameTaken reports whether a sibling (same parent) already uses nama
+func (r *kategoriRepository) nameTaken(nama string, parentID *int, excludeID int) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM kategori
+	          WHERE COALESCE(parent_id, 0) = COALESCE($2::int, 0) AND LOWER(nama) = LOWER($1) AND id <> $3`
+	if err := r.db.QueryRow(query, nama, parentID, excludeID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *kategoriRepository) Create(kategori *models.Kategori) error {
+	taken, err := r.nameTaken(kategori.Nama, kategori.ParentID, 0)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("kategori already exists")
+	}
+
+	query := `INSERT INTO kategori (nama, parent_id) VALUES ($1, $2)
+	          RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query, kategori.Nama, kategori.ParentID).Scan(
+		&kategori.ID, &kategori.CreatedAt, &kategori.UpdatedAt,
+	)
+}
+
+// Update renames or moves a kategori and keeps the kategori text of its barang
+// in sync with the new name
+func (r *kategoriRepository) Update(kategori *models.Kategori) error {
+	taken, err := r.nameTaken(kategori.Nama, kategori.ParentID, kategori.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("kategori already exists")
+	}
+
+	// Begin transaction
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE kategori SET nama = $1, parent_id = $2 WHERE id = $3`
+	result, err := tx.Exec(query, kategori.Nama, kategori.ParentID, kategori.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("kategori not found")
+	}
+
+	_, err = tx.Exec(`UPDATE master_barang SET kategori = $1 WHERE kategori_id = $2 AND kategori IS DISTINCT FROM $1`,
+		kategori.Nama, kategori.ID)
+	if err != nil {
+		return err
+	}
+
+	// Commit transaction
+	return tx.Commit()
+}
+
+func (r *kategoriRepository) Delete(id int) error {
+	var children, barang int
+	query := `SELECT (SELECT COUNT(*) FROM kategori WHERE parent_id = $1),
+	          (SELECT COUNT(*) FROM master_barang WHERE kategori_id = $1)`
+	if err := r.db.QueryRow(query, id).Scan(&children, &barang); err != nil {
+		return err
+	}
+	if children > 0 {
+		return fmt.Errorf("kategori has sub-kategori")
+	}
+	if barang > 0 {
+		return fmt.Errorf("kategori is used by barang")
+	}
+
+	result, err := r.db.Exec(`DELETE FROM kategori WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("kategori not found")
+	}
+
+	return nil
+}
+
+// IsDescendant reports whether id is ancestorID itself or lies below it
+func (r *kategoriRepository) IsDescendant(id, ancestorID int) (bool, error) {
+	var found bool
+	query := `WITH RECURSIVE sub AS (
+	              SELECT id FROM kategori WHERE id = $2
+	              UNION ALL
+	              SELECT k.id FROM kategori k JOIN sub ON k.parent_id = sub.id
+	          )
+	          SELECT EXISTS (SELECT 1 FROM sub WHERE id = $1)`
+
+	err := r.db.QueryRow(query, id, ancestorID).Scan(&found)
+	return found, err
+}
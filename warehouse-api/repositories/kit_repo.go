@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"database/sql"
+	"warehouse-api/models"
+)
+
+type KitRepository interface {
+	FindKomponen(kitBarangID int) ([]models.KomponenKit, error)
+	SetKomponen(kitBarangID int, komponen []models.KomponenKit) error
+}
+
+type kitRepository struct {
+	db *sql.DB
+}
+
+func NewKitRepository(db *sql.DB) KitRepository {
+	return &kitRepository{db: db}
+}
+
+func (r *kitRepository) FindKomponen(kitBarangID int) ([]models.KomponenKit, error) {
+	var komponen []models.KomponenKit
+
+	query := `SELECT k.id, k.kit_barang_id, k.komponen_barang_id, k.qty,
+	          b.kode_barang, b.nama_barang, b.satuan, COALESCE(s.stok_akhir, 0), b.archived_at
+	          FROM komponen_kit k
+	          JOIN master_barang b ON k.komponen_barang_id = b.id
+	          LEFT JOIN mstok s ON s.barang_id = b.id
+	          WHERE k.kit_barang_id = $1
+	          ORDER BY k.id`
+
+	rows, err := r.db.Query(query, kitBarangID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k models.KomponenKit
+		err := rows.Scan(&k.ID, &k.KitBarangID, &k.KomponenBarangID, &k.Qty,
+			&k.KodeBarang, &k.NamaBarang, &k.Satuan, &k.StokAkhir, &k.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		komponen = append(komponen, k)
+	}
+
+	return komponen, nil
+}
+
+// SetKomponen replaces the bill of components of a kit in a single transaction
+func (r *kitRepository) SetKomponen(kitBarangID int, komponen []models.KomponenKit) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM komponen_kit WHERE kit_barang_id = $1`, kitBarangID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO komponen_kit (kit_barang_id, komponen_barang_id, qty)
+	          VALUES ($1, $2, $3) RETURNING id`
+
+	for i := range komponen {
+		komponen[i].KitBarangID = kitBarangID
+		err := tx.QueryRow(query, kitBarangID, komponen[i].KomponenBarangID,
+			komponen[i].Qty).Scan(&komponen[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+// LampiranRepository stores attachments of pembelian and penjualan; tipe is
+// "pembelian" or "penjualan"
+type LampiranRepository interface {
+	FindByReferensi(tipe string, referensiID int) ([]models.Lampiran, error)
+	FindByID(tipe string, referensiID, id int) (*models.Lampiran, error)
+	Create(lampiran *models.Lampiran) error
+	Delete(tipe string, referensiID, id int) error
+}
+
+type lampiranRepository struct {
+	db *sql.DB
+}
+
+func NewLampiranRepository(db *sql.DB) LampiranRepository {
+	return &lampiranRepository{db: db}
+}
+
+// lampiranColumn returns the header column that links a lampiran to tipe
+func lampiranColumn(tipe string) (string, error) {
+	switch tipe {
+	case "pembelian":
+		return "beli_header_id", nil
+	case "penjualan":
+		return "jual_header_id", nil
+	}
+	return "", fmt.Errorf("invalid lampiran tipe %q", tipe)
+}
+
+const lampiranColumns = `id, CASE WHEN beli_header_id IS NOT NULL THEN 'pembelian' ELSE 'penjualan' END,
+	          COALESCE(beli_header_id, jual_header_id), nama_file, content_type, ukuran, keterangan,
+	          file_key, thumbnail_key, uploaded_by, created_at`
+
+func scanLampiran(row interface{ Scan(...interface{}) error }, l *models.Lampiran) error {
+	return row.Scan(&l.ID, &l.ReferensiTipe, &l.ReferensiID, &l.NamaFile, &l.ContentType, &l.Ukuran,
+		&l.Keterangan, &l.FileKey, &l.ThumbnailKey, &l.UploadedBy, &l.CreatedAt)
+}
+
+func (r *lampiranRepository) FindByReferensi(tipe string, referensiID int) ([]models.Lampiran, error) {
+	column, err := lampiranColumn(tipe)
+	if err != nil {
+		return nil, err
+	}
+
+	lampirans := []models.Lampiran{}
+
+	query := `SELECT ` + lampiranColumns + ` FROM lampiran WHERE ` + column + ` = $1 ORDER BY id`
+	rows, err := r.db.Query(query, referensiID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.Lampiran
+		if err := scanLampiran(rows, &l); err != nil {
+			return nil, err
+		}
+		lampirans = append(lampirans, l)
+	}
+
+	return lampirans, nil
+}
+
+func (r *lampiranRepository) FindByID(tipe string, referensiID, id int) (*models.Lampiran, error) {
+	column, err := lampiranColumn(tipe)
+	if err != nil {
+		return nil, err
+	}
+
+	lampiran := &models.Lampiran{}
+
+	query := `SELECT ` + lampiranColumns + ` FROM lampiran WHERE id = $1 AND ` + column + ` = $2`
+	err = scanLampiran(r.db.QueryRow(query, id, referensiID), lampiran)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("lampiran not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return lampiran, nil
+}
+
+func (r *lampiranRepository) Create(lampiran *models.Lampiran) error {
+	column, err := lampiranColumn(lampiran.ReferensiTipe)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO lampiran (` + column + `, nama_file, content_type, ukuran, keterangan, file_key, thumbnail_key, uploaded_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	          RETURNING id, created_at`
+
+	return r.db.QueryRow(query, lampiran.ReferensiID, lampiran.NamaFile, lampiran.ContentType, lampiran.Ukuran,
+		lampiran.Keterangan, lampiran.FileKey, lampiran.ThumbnailKey, lampiran.UploadedBy).Scan(
+		&lampiran.ID, &lampiran.CreatedAt,
+	)
+}
+
+func (r *lampiranRepository) Delete(tipe string, referensiID, id int) error {
+	column, err := lampiranColumn(tipe)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`DELETE FROM lampiran WHERE id = $1 AND `+column+` = $2`, id, referensiID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("lampiran not found")
+	}
+
+	return nil
+}
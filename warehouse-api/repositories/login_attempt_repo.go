@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+	"warehouse-api/models"
+)
+
+// LoginAttemptRepository stores failed login counts. The Postgres store is
+// shared by all API instances; the memory store only sees its own instance
+type LoginAttemptRepository interface {
+	Find(key string) (*models.LoginAttempt, error)
+	FindLocked(now time.Time) ([]models.LoginAttempt, error)
+	// Reserve counts an attempt as a failure before its outcome is known, so
+	// concurrent attempts cannot all slip past the same check. Unless key is
+	// locked, it adds one failure and locks key for delay(failures), all in one
+	// step, and returns the new state and true. A locked key is returned
+	// unchanged with false. The count starts over when the previous failure is
+	// older than resetBefore
+	Reserve(key string, now, resetBefore time.Time, delay func(failures int) time.Duration) (*models.LoginAttempt, bool, error)
+	// Release takes back a reserved failure. The lock is only lifted if it is
+	// still the one the reservation set
+	Release(key string, lockedUntil *time.Time) error
+	Reset(key string) error
+}
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// Find returns nil when key has no recorded failures
+func (r *loginAttemptRepository) Find(key string) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{}
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_attempt WHERE key = $1`
+
+	err := r.db.QueryRow(query, key).Scan(&attempt.Key, &attempt.Failures, &attempt.LastFailureAt, &attempt.LockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
+func (r *loginAttemptRepository) FindLocked(now time.Time) ([]models.LoginAttempt, error) {
+	attempts := []models.LoginAttempt{}
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_attempt
+	          WHERE locked_until > $1 ORDER BY locked_until DESC`
+
+	rows, err := r.db.Query(query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.Key, &a.Failures, &a.LastFailureAt, &a.LockedUntil); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+
+	return attempts, rows.Err()
+}
+
+func (r *loginAttemptRepository) Reserve(key string, now, resetBefore time.Time,
+	delay func(failures int) time.Duration) (*models.LoginAttempt, bool, error) {
+	// Begin transaction
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// Make sure the row exists so it can be locked
+	if _, err := tx.Exec(`INSERT INTO login_attempt (key, failures, last_failure_at) VALUES ($1, 0, $2)
+	          ON CONFLICT (key) DO NOTHING`, key, now); err != nil {
+		return nil, false, err
+	}
+
+	attempt := &models.LoginAttempt{Key: key}
+	var locked, expired bool
+	query := `SELECT failures, last_failure_at, locked_until,
+	          COALESCE(locked_until > $2, false), last_failure_at < $3
+	          FROM login_attempt WHERE key = $1 FOR UPDATE`
+
+	err = tx.QueryRow(query, key, now, resetBefore).Scan(&attempt.Failures, &attempt.LastFailureAt,
+		&attempt.LockedUntil, &locked, &expired)
+	if err != nil {
+		return nil, false, err
+	}
+	if locked {
+		return attempt, false, tx.Commit()
+	}
+
+	if expired {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	attempt.LockedUntil = nil
+	if d := delay(attempt.Failures); d > 0 {
+		until := now.Add(d)
+		attempt.LockedUntil = &until
+	}
+
+	_, err = tx.Exec(`UPDATE login_attempt SET failures = $1, last_failure_at = $2, locked_until = $3
+	          WHERE key = $4`, attempt.Failures, attempt.LastFailureAt, attempt.LockedUntil, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return attempt, true, nil
+}
+
+func (r *loginAttemptRepository) Release(key string, lockedUntil *time.Time) error {
+	query := `UPDATE login_attempt SET failures = GREATEST(failures - 1, 0),
+	          locked_until = CASE WHEN locked_until = $2 THEN NULL ELSE locked_until END
+	          WHERE key = $1`
+
+	_, err := r.db.Exec(query, key, lockedUntil)
+	return err
+}
+
+func (r *loginAttemptRepository) Reset(key string) error {
+	_, err := r.db.Exec(`DELETE FROM login_attempt WHERE key = $1`, key)
+	return err
+}
+
+// memoryLoginAttemptRepository keeps the counts in process memory
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]*models.LoginAttempt
+}
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: make(map[string]*models.LoginAttempt)}
+}
+
+func (r *memoryLoginAttemptRepository) Find(key string) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	result := *attempt
+	return &result, nil
+}
+
+func (r *memoryLoginAttemptRepository) FindLocked(now time.Time) ([]models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := []models.LoginAttempt{}
+	for _, a := range r.attempts {
+		if a.LockedUntil != nil && a.LockedUntil.After(now) {
+			attempts = append(attempts, *a)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool { return attempts[i].LockedUntil.After(*attempts[j].LockedUntil) })
+
+	return attempts, nil
+}
+
+func (r *memoryLoginAttemptRepository) Reserve(key string, now, resetBefore time.Time,
+	delay func(failures int) time.Duration) (*models.LoginAttempt, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Forget keys that would start over anyway so the map does not grow forever
+	if len(r.attempts) >= 10000 {
+		for k, a := range r.attempts {
+			if a.LastFailureAt.Before(resetBefore) && (a.LockedUntil == nil || a.LockedUntil.Before(now)) {
+				delete(r.attempts, k)
+			}
+		}
+	}
+
+	attempt, ok := r.attempts[key]
+	if ok && attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		result := *attempt
+		return &result, false, nil
+	}
+	if !ok || attempt.LastFailureAt.Before(resetBefore) {
+		attempt = &models.LoginAttempt{Key: key}
+		r.attempts[key] = attempt
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	attempt.LockedUntil = nil
+	if d := delay(attempt.Failures); d > 0 {
+		until := now.Add(d)
+		attempt.LockedUntil = &until
+	}
+
+	result := *attempt
+	return &result, true, nil
+}
+
+func (r *memoryLoginAttemptRepository) Release(key string, lockedUntil *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil
+	}
+	if attempt.Failures > 0 {
+		attempt.Failures--
+	}
+	if lockedUntil != nil && attempt.LockedUntil != nil && attempt.LockedUntil.Equal(*lockedUntil) {
+		attempt.LockedUntil = nil
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
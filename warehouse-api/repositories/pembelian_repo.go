@@ -10,6 +10,7 @@ type PembelianRepository interface {
 	CreateHeader(tx *sql.Tx, header *models.BeliHeader) error
 	CreateDetail(tx *sql.Tx, detail *models.BeliDetail) error
 	FindAll(limit, offset int) ([]models.BeliHeader, int, error)
+	ForEach(fn func(models.BeliHeader) error) error
 	FindByID(id int) (*models.BeliHeaderWithDetail, error)
 	GenerateNoFaktur(tanggal string) (string, error)
 }
@@ -23,10 +24,10 @@ func NewPembelianRepository(db *sql.DB) PembelianRepository {
 }
 
 func (r *pembelianRepository) CreateHeader(tx *sql.Tx, header *models.BeliHeader) error {
-	query := `INSERT INTO beli_header (no_faktur, tanggal, supplier, total, keterangan, created_by)
-	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	query := `INSERT INTO beli_header (no_faktur, tanggal, supplier_id, supplier, total, keterangan, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
 
-	return tx.QueryRow(query, header.NoFaktur, header.Tanggal, header.Supplier,
+	return tx.QueryRow(query, header.NoFaktur, header.Tanggal, header.SupplierID, header.Supplier,
 		header.Total, header.Keterangan, header.CreatedBy).Scan(
 		&header.ID, &header.CreatedAt, &header.UpdatedAt,
 	)
@@ -52,7 +53,7 @@ func (r *pembelianRepository) FindAll(limit, offset int) ([]models.BeliHeader, i
 	}
 
 	// Get data
-	query := `SELECT id, no_faktur, tanggal, supplier, total, keterangan, 
+	query := `SELECT id, no_faktur, tanggal, supplier_id, supplier, total, keterangan, 
 	          created_by, created_at, updated_at
 	          FROM beli_header ORDER BY created_at DESC LIMIT $1 OFFSET $2`
 
@@ -64,7 +65,7 @@ func (r *pembelianRepository) FindAll(limit, offset int) ([]models.BeliHeader, i
 
 	for rows.Next() {
 		var h models.BeliHeader
-		err := rows.Scan(&h.ID, &h.NoFaktur, &h.Tanggal, &h.Supplier, &h.Total,
+		err := rows.Scan(&h.ID, &h.NoFaktur, &h.Tanggal, &h.SupplierID, &h.Supplier, &h.Total,
 			&h.Keterangan, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt)
 		if err != nil {
 			return nil, 0, err
@@ -75,15 +76,42 @@ func (r *pembelianRepository) FindAll(limit, offset int) ([]models.BeliHeader, i
 	return headers, total, nil
 }
 
+// ForEach calls fn for every pembelian header, newest first, stopping at the first error
+func (r *pembelianRepository) ForEach(fn func(models.BeliHeader) error) error {
+	query := `SELECT id, no_faktur, tanggal, supplier_id, supplier, total, keterangan, 
+	          created_by, created_at, updated_at
+	          FROM beli_header ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.BeliHeader
+		err := rows.Scan(&h.ID, &h.NoFaktur, &h.Tanggal, &h.SupplierID, &h.Supplier, &h.Total,
+			&h.Keterangan, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := fn(h); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func (r *pembelianRepository) FindByID(id int) (*models.BeliHeaderWithDetail, error) {
 	// Get header
 	header := &models.BeliHeaderWithDetail{}
-	queryHeader := `SELECT id, no_faktur, tanggal, supplier, total, keterangan,
+	queryHeader := `SELECT id, no_faktur, tanggal, supplier_id, supplier, total, keterangan,
 	                created_by, created_at, updated_at
 	                FROM beli_header WHERE id = $1`
 
 	err := r.db.QueryRow(queryHeader, id).Scan(
-		&header.ID, &header.NoFaktur, &header.Tanggal, &header.Supplier,
+		&header.ID, &header.NoFaktur, &header.Tanggal, &header.SupplierID, &header.Supplier,
 		&header.Total, &header.Keterangan, &header.CreatedBy,
 		&header.CreatedAt, &header.UpdatedAt,
 	)
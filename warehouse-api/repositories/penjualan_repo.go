@@ -10,8 +10,11 @@ type PenjualanRepository interface {
 	CreateHeader(tx *sql.Tx, header *models.JualHeader) error
 	CreateDetail(tx *sql.Tx, detail *models.JualDetail) error
 	FindAll(limit, offset int) ([]models.JualHeader, int, error)
+	ForEach(fn func(models.JualHeader) error) error
 	FindByID(id int) (*models.JualHeaderWithDetail, error)
 	GenerateNoFaktur(tanggal string) (string, error)
+	CreatePembayaran(tx *sql.Tx, pembayaran *models.PembayaranPenjualan) error
+	AddTerbayar(tx *sql.Tx, jualHeaderID int, jumlah float64) error
 }
 
 type penjualanRepository struct {
@@ -23,10 +26,10 @@ func NewPenjualanRepository(db *sql.DB) PenjualanRepository {
 }
 
 func (r *penjualanRepository) CreateHeader(tx *sql.Tx, header *models.JualHeader) error {
-	query := `INSERT INTO jual_header (no_faktur, tanggal, customer, total, keterangan, created_by)
-	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+	query := `INSERT INTO jual_header (no_faktur, tanggal, customer_id, customer, total, keterangan, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
 
-	return tx.QueryRow(query, header.NoFaktur, header.Tanggal, header.Customer,
+	return tx.QueryRow(query, header.NoFaktur, header.Tanggal, header.CustomerID, header.Customer,
 		header.Total, header.Keterangan, header.CreatedBy).Scan(
 		&header.ID, &header.CreatedAt, &header.UpdatedAt,
 	)
@@ -52,7 +55,7 @@ func (r *penjualanRepository) FindAll(limit, offset int) ([]models.JualHeader, i
 	}
 
 	// Get data
-	query := `SELECT id, no_faktur, tanggal, customer, total, keterangan,
+	query := `SELECT id, no_faktur, tanggal, customer_id, customer, total, terbayar, keterangan,
 	          created_by, created_at, updated_at
 	          FROM jual_header ORDER BY created_at DESC LIMIT $1 OFFSET $2`
 
@@ -64,8 +67,8 @@ func (r *penjualanRepository) FindAll(limit, offset int) ([]models.JualHeader, i
 
 	for rows.Next() {
 		var h models.JualHeader
-		err := rows.Scan(&h.ID, &h.NoFaktur, &h.Tanggal, &h.Customer, &h.Total,
-			&h.Keterangan, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt)
+		err := rows.Scan(&h.ID, &h.NoFaktur, &h.Tanggal, &h.CustomerID, &h.Customer, &h.Total,
+			&h.Terbayar, &h.Keterangan, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -75,16 +78,43 @@ func (r *penjualanRepository) FindAll(limit, offset int) ([]models.JualHeader, i
 	return headers, total, nil
 }
 
+// ForEach calls fn for every penjualan header, newest first, stopping at the first error
+func (r *penjualanRepository) ForEach(fn func(models.JualHeader) error) error {
+	query := `SELECT id, no_faktur, tanggal, customer_id, customer, total, terbayar, keterangan,
+	          created_by, created_at, updated_at
+	          FROM jual_header ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.JualHeader
+		err := rows.Scan(&h.ID, &h.NoFaktur, &h.Tanggal, &h.CustomerID, &h.Customer, &h.Total,
+			&h.Terbayar, &h.Keterangan, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := fn(h); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func (r *penjualanRepository) FindByID(id int) (*models.JualHeaderWithDetail, error) {
 	// Get header
 	header := &models.JualHeaderWithDetail{}
-	queryHeader := `SELECT id, no_faktur, tanggal, customer, total, keterangan,
+	queryHeader := `SELECT id, no_faktur, tanggal, customer_id, customer, total, terbayar, keterangan,
 	                created_by, created_at, updated_at
 	                FROM jual_header WHERE id = $1`
 
 	err := r.db.QueryRow(queryHeader, id).Scan(
-		&header.ID, &header.NoFaktur, &header.Tanggal, &header.Customer,
-		&header.Total, &header.Keterangan, &header.CreatedBy,
+		&header.ID, &header.NoFaktur, &header.Tanggal, &header.CustomerID, &header.Customer,
+		&header.Total, &header.Terbayar, &header.Keterangan, &header.CreatedBy,
 		&header.CreatedAt, &header.UpdatedAt,
 	)
 
@@ -120,6 +150,31 @@ func (r *penjualanRepository) FindByID(id int) (*models.JualHeaderWithDetail, er
 	}
 
 	header.Details = details
+
+	// Get payments
+	queryPembayaran := `SELECT id, jual_header_id, tanggal, jumlah, COALESCE(keterangan, ''),
+	                    created_by, created_at
+	                    FROM pembayaran_penjualan
+	                    WHERE jual_header_id = $1 ORDER BY tanggal, id`
+
+	pembayaranRows, err := r.db.Query(queryPembayaran, id)
+	if err != nil {
+		return nil, err
+	}
+	defer pembayaranRows.Close()
+
+	var pembayarans []models.PembayaranPenjualan
+	for pembayaranRows.Next() {
+		var p models.PembayaranPenjualan
+		err := pembayaranRows.Scan(&p.ID, &p.JualHeaderID, &p.Tanggal, &p.Jumlah,
+			&p.Keterangan, &p.CreatedBy, &p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		pembayarans = append(pembayarans, p)
+	}
+
+	header.Pembayaran = pembayarans
 	return header, nil
 }
 
@@ -142,3 +197,33 @@ func (r *penjualanRepository) GenerateNoFaktur(tanggal string) (string, error) {
 	nextNumber := lastNumber + 1
 	return fmt.Sprintf("JL/%s/%03d", datePrefix, nextNumber), nil
 }
+
+func (r *penjualanRepository) CreatePembayaran(tx *sql.Tx, pembayaran *models.PembayaranPenjualan) error {
+	query := `INSERT INTO pembayaran_penjualan (jual_header_id, tanggal, jumlah, keterangan, created_by)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	return tx.QueryRow(query, pembayaran.JualHeaderID, pembayaran.Tanggal, pembayaran.Jumlah,
+		pembayaran.Keterangan, pembayaran.CreatedBy).Scan(&pembayaran.ID, &pembayaran.CreatedAt)
+}
+
+// AddTerbayar records a payment against the header, refusing to pay more than the total
+func (r *penjualanRepository) AddTerbayar(tx *sql.Tx, jualHeaderID int, jumlah float64) error {
+	query := `UPDATE jual_header SET terbayar = terbayar + $1
+	          WHERE id = $2 AND terbayar + $1 <= total`
+
+	result, err := tx.Exec(query, jumlah, jualHeaderID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("pembayaran exceeds outstanding amount")
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+type PriceListRepository interface {
+	FindAll() ([]models.PriceList, error)
+	FindByID(id int) (*models.PriceListWithItems, error)
+	Create(priceList *models.PriceList) error
+	Update(priceList *models.PriceList) error
+	Delete(id int) error
+	SetItems(priceListID int, items []models.PriceListItem) error
+	FindHarga(priceListID, barangID, qty int) (*models.PriceListItem, error)
+}
+
+type priceListRepository struct {
+	db *sql.DB
+}
+
+func NewPriceListRepository(db *sql.DB) PriceListRepository {
+	return &priceListRepository{db: db}
+}
+
+func (r *priceListRepository) FindAll() ([]models.PriceList, error) {
+	var priceLists []models.PriceList
+
+	query := `SELECT id, kode_price_list, nama_price_list, COALESCE(keterangan, ''),
+	          created_at, updated_at
+	          FROM price_list ORDER BY nama_price_list ASC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.PriceList
+		err := rows.Scan(&p.ID, &p.KodePriceList, &p.NamaPriceList, &p.Keterangan,
+			&p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		priceLists = append(priceLists, p)
+	}
+
+	return priceLists, nil
+}
+
+func (r *priceListRepository) FindByID(id int) (*models.PriceListWithItems, error) {
+	// Get header
+	priceList := &models.PriceListWithItems{}
+	query := `SELECT id, kode_price_list, nama_price_list, COALESCE(keterangan, ''),
+	          created_at, updated_at
+	          FROM price_list WHERE id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(
+		&priceList.ID, &priceList.KodePriceList, &priceList.NamaPriceList,
+		&priceList.Keterangan, &priceList.CreatedAt, &priceList.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("price list not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Get items
+	queryItems := `SELECT i.id, i.price_list_id, i.barang_id, i.min_qty, i.harga,
+	               b.kode_barang, b.nama_barang
+	               FROM price_list_item i
+	               JOIN master_barang b ON i.barang_id = b.id
+	               WHERE i.price_list_id = $1
+	               ORDER BY b.kode_barang, i.min_qty`
+
+	rows, err := r.db.Query(queryItems, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.PriceListItem
+	for rows.Next() {
+		var i models.PriceListItem
+		err := rows.Scan(&i.ID, &i.PriceListID, &i.BarangID, &i.MinQty, &i.Harga,
+			&i.KodeBarang, &i.NamaBarang)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+
+	priceList.Items = items
+	return priceList, nil
+}
+
+func (r *priceListRepository) Create(priceList *models.PriceList) error {
+	query := `INSERT INTO price_list (kode_price_list, nama_price_list, keterangan)
+	          VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query, priceList.KodePriceList, priceList.NamaPriceList,
+		priceList.Keterangan).Scan(&priceList.ID, &priceList.CreatedAt, &priceList.UpdatedAt)
+}
+
+func (r *priceListRepository) Update(priceList *models.PriceList) error {
+	query := `UPDATE price_list SET nama_price_list = $1, keterangan = $2 WHERE id = $3`
+
+	result, err := r.db.Exec(query, priceList.NamaPriceList, priceList.Keterangan, priceList.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("price list not found")
+	}
+
+	return nil
+}
+
+func (r *priceListRepository) Delete(id int) error {
+	// Price lists assigned to customers must be unassigned first
+	var used int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM customer WHERE price_list_id = $1`, id).Scan(&used); err != nil {
+		return err
+	}
+	if used > 0 {
+		return fmt.Errorf("price list is used by customer")
+	}
+
+	query := `DELETE FROM price_list WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("price list not found")
+	}
+
+	return nil
+}
+
+// SetItems replaces all prices of a price list in a single transaction
+func (r *priceListRepository) SetItems(priceListID int, items []models.PriceListItem) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM price_list_item WHERE price_list_id = $1`, priceListID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO price_list_item (price_list_id, barang_id, min_qty, harga)
+	          VALUES ($1, $2, $3, $4) RETURNING id`
+
+	for i := range items {
+		items[i].PriceListID = priceListID
+		err := tx.QueryRow(query, priceListID, items[i].BarangID, items[i].MinQty,
+			items[i].Harga).Scan(&items[i].ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindHarga returns the quantity break that applies to qty, or nil when the
+// price list has no price for the barang
+func (r *priceListRepository) FindHarga(priceListID, barangID, qty int) (*models.PriceListItem, error) {
+	item := &models.PriceListItem{}
+	query := `SELECT id, price_list_id, barang_id, min_qty, harga
+	          FROM price_list_item
+	          WHERE price_list_id = $1 AND barang_id = $2 AND min_qty <= $3
+	          ORDER BY min_qty DESC LIMIT 1`
+
+	err := r.db.QueryRow(query, priceListID, barangID, qty).Scan(
+		&item.ID, &item.PriceListID, &item.BarangID, &item.MinQty, &item.Harga,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
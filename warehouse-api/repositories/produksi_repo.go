@@ -0,0 +1,258 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+type ProduksiRepository interface {
+	CreateHeader(tx *sql.Tx, header *models.ProduksiHeader) error
+	FindAll(status string, limit, offset int) ([]models.ProduksiHeader, int, error)
+	FindByID(id int) (*models.ProduksiWithDetail, error)
+	FindByIDForUpdate(tx *sql.Tx, id int) (*models.ProduksiHeader, error)
+	CreateKomponen(tx *sql.Tx, produksiID int, details []models.BOMDetail) error
+	FindKomponenTx(tx *sql.Tx, produksiID int) ([]models.BOMDetail, error)
+	CreateRealisasi(tx *sql.Tx, realisasi *models.ProduksiRealisasi) error
+	UpdateProgress(tx *sql.Tx, header *models.ProduksiHeader) error
+	GenerateNoProduksi(tanggal string) (string, error)
+}
+
+type produksiRepository struct {
+	db *sql.DB
+}
+
+func NewProduksiRepository(db *sql.DB) ProduksiRepository {
+	return &produksiRepository{db: db}
+}
+
+const produksiHeaderColumns = `p.id, p.no_produksi, p.tanggal, p.bom_id, p.barang_id,
+	          b.kode_barang, b.nama_barang, p.qty_rencana, p.qty_selesai, p.qty_scrap,
+	          p.status, COALESCE(p.keterangan, ''), p.created_by, p.created_at, p.updated_at`
+
+// produksiKomponenQuery lists the components an order was created with
+const produksiKomponenQuery = `SELECT k.id, p.bom_id, k.komponen_barang_id, k.qty,
+	          b.kode_barang, b.nama_barang, b.satuan, b.harga_beli, b.archived_at
+	          FROM produksi_komponen k
+	          JOIN produksi_header p ON k.produksi_header_id = p.id
+	          JOIN master_barang b ON k.komponen_barang_id = b.id
+	          WHERE k.produksi_header_id = $1
+	          ORDER BY k.id`
+
+func scanProduksiKomponen(rows *sql.Rows) ([]models.BOMDetail, error) {
+	var komponen []models.BOMDetail
+	for rows.Next() {
+		var d models.BOMDetail
+		err := rows.Scan(&d.ID, &d.BomID, &d.KomponenBarangID, &d.Qty,
+			&d.KodeBarang, &d.NamaBarang, &d.Satuan, &d.HargaBeli, &d.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		komponen = append(komponen, d)
+	}
+	return komponen, rows.Err()
+}
+
+func scanProduksiHeader(row interface{ Scan(...interface{}) error }, h *models.ProduksiHeader) error {
+	return row.Scan(&h.ID, &h.NoProduksi, &h.Tanggal, &h.BomID, &h.BarangID,
+		&h.KodeBarang, &h.NamaBarang, &h.QtyRencana, &h.QtySelesai, &h.QtyScrap,
+		&h.Status, &h.Keterangan, &h.CreatedBy, &h.CreatedAt, &h.UpdatedAt)
+}
+
+func (r *produksiRepository) CreateHeader(tx *sql.Tx, header *models.ProduksiHeader) error {
+	query := `INSERT INTO produksi_header (no_produksi, tanggal, bom_id, barang_id, qty_rencana,
+	          keterangan, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, status, created_at, updated_at`
+
+	return tx.QueryRow(query, header.NoProduksi, header.Tanggal, header.BomID, header.BarangID,
+		header.QtyRencana, header.Keterangan, header.CreatedBy).Scan(
+		&header.ID, &header.Status, &header.CreatedAt, &header.UpdatedAt,
+	)
+}
+
+func (r *produksiRepository) FindAll(status string, limit, offset int) ([]models.ProduksiHeader, int, error) {
+	var headers []models.ProduksiHeader
+	var total int
+
+	// Count total
+	countQuery := `SELECT COUNT(*) FROM produksi_header WHERE $1 = '' OR status = $1`
+	err := r.db.QueryRow(countQuery, status).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get data
+	query := `SELECT ` + produksiHeaderColumns + `
+	          FROM produksi_header p
+	          JOIN master_barang b ON p.barang_id = b.id
+	          WHERE $1 = '' OR p.status = $1
+	          ORDER BY p.created_at DESC LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.ProduksiHeader
+		if err := scanProduksiHeader(rows, &h); err != nil {
+			return nil, 0, err
+		}
+		headers = append(headers, h)
+	}
+
+	return headers, total, nil
+}
+
+func (r *produksiRepository) FindByID(id int) (*models.ProduksiWithDetail, error) {
+	// Get header
+	produksi := &models.ProduksiWithDetail{}
+	query := `SELECT ` + produksiHeaderColumns + `
+	          FROM produksi_header p
+	          JOIN master_barang b ON p.barang_id = b.id
+	          WHERE p.id = $1`
+
+	err := scanProduksiHeader(r.db.QueryRow(query, id), &produksi.ProduksiHeader)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("produksi not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the components the order was created with
+	rows, err := r.db.Query(produksiKomponenQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	produksi.Komponen, err = scanProduksiKomponen(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get realisasi
+	queryRealisasi := `SELECT id, produksi_header_id, tanggal, qty_selesai, qty_scrap,
+	                   biaya_komponen, biaya_per_unit, COALESCE(keterangan, ''), created_by, created_at
+	                   FROM produksi_realisasi
+	                   WHERE produksi_header_id = $1
+	                   ORDER BY id`
+
+	realisasiRows, err := r.db.Query(queryRealisasi, id)
+	if err != nil {
+		return nil, err
+	}
+	defer realisasiRows.Close()
+
+	var realisasi []models.ProduksiRealisasi
+	for realisasiRows.Next() {
+		var rl models.ProduksiRealisasi
+		err := realisasiRows.Scan(&rl.ID, &rl.ProduksiHeaderID, &rl.Tanggal, &rl.QtySelesai,
+			&rl.QtyScrap, &rl.BiayaKomponen, &rl.BiayaPerUnit, &rl.Keterangan,
+			&rl.CreatedBy, &rl.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		realisasi = append(realisasi, rl)
+	}
+	produksi.Realisasi = realisasi
+
+	return produksi, nil
+}
+
+// FindByIDForUpdate loads a production order and locks it until tx ends
+func (r *produksiRepository) FindByIDForUpdate(tx *sql.Tx, id int) (*models.ProduksiHeader, error) {
+	header := &models.ProduksiHeader{}
+	query := `SELECT ` + produksiHeaderColumns + `
+	          FROM produksi_header p
+	          JOIN master_barang b ON p.barang_id = b.id
+	          WHERE p.id = $1
+	          FOR UPDATE OF p`
+
+	err := scanProduksiHeader(tx.QueryRow(query, id), header)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("produksi not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// CreateKomponen copies the BOM lines into the order
+func (r *produksiRepository) CreateKomponen(tx *sql.Tx, produksiID int, details []models.BOMDetail) error {
+	query := `INSERT INTO produksi_komponen (produksi_header_id, komponen_barang_id, qty)
+	          VALUES ($1, $2, $3)`
+
+	for _, d := range details {
+		if _, err := tx.Exec(query, produksiID, d.KomponenBarangID, d.Qty); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *produksiRepository) FindKomponenTx(tx *sql.Tx, produksiID int) ([]models.BOMDetail, error) {
+	rows, err := tx.Query(produksiKomponenQuery, produksiID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanProduksiKomponen(rows)
+}
+
+func (r *produksiRepository) CreateRealisasi(tx *sql.Tx, realisasi *models.ProduksiRealisasi) error {
+	query := `INSERT INTO produksi_realisasi (produksi_header_id, tanggal, qty_selesai, qty_scrap,
+	          biaya_komponen, biaya_per_unit, keterangan, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`
+
+	return tx.QueryRow(query, realisasi.ProduksiHeaderID, realisasi.Tanggal, realisasi.QtySelesai,
+		realisasi.QtyScrap, realisasi.BiayaKomponen, realisasi.BiayaPerUnit, realisasi.Keterangan,
+		realisasi.CreatedBy).Scan(&realisasi.ID, &realisasi.CreatedAt)
+}
+
+// UpdateProgress stores the cumulative quantities and status of a production order
+func (r *produksiRepository) UpdateProgress(tx *sql.Tx, header *models.ProduksiHeader) error {
+	query := `UPDATE produksi_header SET qty_selesai = $1, qty_scrap = $2, status = $3
+	          WHERE id = $4`
+
+	result, err := tx.Exec(query, header.QtySelesai, header.QtyScrap, header.Status, header.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("produksi not found")
+	}
+
+	return nil
+}
+
+func (r *produksiRepository) GenerateNoProduksi(tanggal string) (string, error) {
+	// Format: PR/YYYYMMDD/001
+	// Extract date from tanggal (format: YYYY-MM-DD)
+	datePrefix := tanggal[0:4] + tanggal[5:7] + tanggal[8:10]
+
+	var lastNumber int
+	query := `SELECT COALESCE(MAX(CAST(SUBSTRING(no_produksi FROM LENGTH(no_produksi) - 2) AS INTEGER)), 0)
+	          FROM produksi_header
+	          WHERE no_produksi LIKE $1`
+
+	pattern := fmt.Sprintf("PR/%s/%%", datePrefix)
+	err := r.db.QueryRow(query, pattern).Scan(&lastNumber)
+	if err != nil {
+		return "", err
+	}
+
+	nextNumber := lastNumber + 1
+	return fmt.Sprintf("PR/%s/%03d", datePrefix, nextNumber), nil
+}
@@ -0,0 +1,257 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+
+	"github.com/lib/pq"
+)
+
+type RoleRepository interface {
+	FindAll() ([]models.Role, error)
+	FindByID(id int) (*models.Role, error)
+	FindPermissions() ([]models.Permission, error)
+	FindRolePermissions(nama string) ([]string, error)
+	FindRequire2FA(nama string) (bool, error)
+	SetRequire2FA(id int, require bool) error
+	Create(role *models.Role) error
+	Update(role *models.Role) error
+	Delete(id int) error
+}
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// rolePermissions selects the permissions of role r; a system role has them all
+const rolePermissions = `SELECT COALESCE(array_agg(p.kode ORDER BY p.kode), '{}') FROM permission p
+	          WHERE r.is_system OR EXISTS (SELECT 1 FROM role_permission rp
+	          WHERE rp.role_id = r.id AND rp.permission = p.kode)`
+
+func (r *roleRepository) FindAll() ([]models.Role, error) {
+	roles := []models.Role{}
+	query := `SELECT r.id, r.nama, COALESCE(r.deskripsi, ''), r.is_system, r.require_2fa, (` + rolePermissions + `),
+	          r.created_at, r.updated_at
+	          FROM role r ORDER BY r.is_system DESC, r.nama ASC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Nama, &role.Deskripsi, &role.IsSystem, &role.Require2FA,
+			pq.Array(&role.Permissions), &role.CreatedAt, &role.UpdatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *roleRepository) FindByID(id int) (*models.Role, error) {
+	role := &models.Role{}
+	query := `SELECT r.id, r.nama, COALESCE(r.deskripsi, ''), r.is_system, r.require_2fa, (` + rolePermissions + `),
+	          r.created_at, r.updated_at
+	          FROM role r WHERE r.id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(&role.ID, &role.Nama, &role.Deskripsi, &role.IsSystem,
+		&role.Require2FA, pq.Array(&role.Permissions), &role.CreatedAt, &role.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("role not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// FindPermissions lists every permission that can be granted
+func (r *roleRepository) FindPermissions() ([]models.Permission, error) {
+	permissions := []models.Permission{}
+
+	rows, err := r.db.Query(`SELECT kode, deskripsi FROM permission ORDER BY kode`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.Kode, &p.Deskripsi); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+// FindRolePermissions returns the permissions of the role named nama, or
+// none when there is no such role
+func (r *roleRepository) FindRolePermissions(nama string) ([]string, error) {
+	var permissions []string
+	query := `SELECT (` + rolePermissions + `) FROM role r WHERE r.nama = $1`
+
+	err := r.db.QueryRow(query, nama).Scan(pq.Array(&permissions))
+	if err == sql.ErrNoRows {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// FindRequire2FA reports whether the role named nama requires 2FA; false
+// when there is no such role
+func (r *roleRepository) FindRequire2FA(nama string) (bool, error) {
+	var require bool
+
+	err := r.db.QueryRow(`SELECT require_2fa FROM role WHERE nama = $1`, nama).Scan(&require)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return require, nil
+}
+
+// SetRequire2FA works on system roles too, so admins can be made to use 2FA
+func (r *roleRepository) SetRequire2FA(id int, require bool) error {
+	result, err := r.db.Exec(`UPDATE role SET require_2fa = $1 WHERE id = $2`, require, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("role not found")
+	}
+
+	return nil
+}
+
+// nameTaken reports whether another role already uses nama
+func (r *roleRepository) nameTaken(nama string, excludeID int) (bool, error) {
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM role WHERE LOWER(nama) = LOWER($1) AND id <> $2)`
+
+	if err := r.db.QueryRow(query, nama, excludeID).Scan(&taken); err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+func (r *roleRepository) Create(role *models.Role) error {
+	taken, err := r.nameTaken(role.Nama, 0)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("role already exists")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO role (nama, deskripsi) VALUES ($1, $2) RETURNING id, created_at, updated_at`
+	if err := tx.QueryRow(query, role.Nama, role.Deskripsi).Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := setRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update renames a role and replaces its permissions; users follow the new
+// name through the foreign key
+func (r *roleRepository) Update(role *models.Role) error {
+	taken, err := r.nameTaken(role.Nama, role.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("role already exists")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE role SET nama = $1, deskripsi = $2 WHERE id = $3 AND NOT is_system RETURNING updated_at`
+	err = tx.QueryRow(query, role.Nama, role.Deskripsi, role.ID).Scan(&role.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("role not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permission WHERE role_id = $1`, role.ID); err != nil {
+		return err
+	}
+	if err := setRolePermissions(tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func setRolePermissions(tx *sql.Tx, roleID int, permissions []string) error {
+	query := `INSERT INTO role_permission (role_id, permission) SELECT $1, UNNEST($2::text[])`
+	_, err := tx.Exec(query, roleID, pq.Array(permissions))
+	return err
+}
+
+func (r *roleRepository) Delete(id int) error {
+	// Users must be moved to another role first
+	var used int
+	query := `SELECT COUNT(*) FROM users u JOIN role r ON r.nama = u.role WHERE r.id = $1`
+	if err := r.db.QueryRow(query, id).Scan(&used); err != nil {
+		return err
+	}
+	if used > 0 {
+		return fmt.Errorf("role is used by users")
+	}
+
+	result, err := r.db.Exec(`DELETE FROM role WHERE id = $1 AND NOT is_system`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("role not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"warehouse-api/models"
+)
+
+type SessionRepository interface {
+	CreateSession(tx *sql.Tx, session *models.UserSession) error
+	FindSession(id int64) (*models.UserSession, error)
+	ExtendSession(tx *sql.Tx, id int64, expiresAt time.Time) error
+	RevokeSession(id int64) error
+	RevokeUserSessions(userID int, exceptSessionID int64) error
+	DeleteExpiredSessions(userID int) error
+	IsSessionActive(id int64) (bool, error)
+	CreateRefreshToken(tx *sql.Tx, token *models.RefreshToken) error
+	FindRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(tx *sql.Tx, id int64) (bool, error)
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) CreateSession(tx *sql.Tx, session *models.UserSession) error {
+	query := `INSERT INTO user_session (user_id, ip_address, user_agent, expires_at)
+	          VALUES ($1, $2, $3, $4) RETURNING id, created_at, last_used_at`
+
+	return tx.QueryRow(query, session.UserID, session.IPAddress, session.UserAgent, session.ExpiresAt).Scan(
+		&session.ID, &session.CreatedAt, &session.LastUsedAt,
+	)
+}
+
+func (r *sessionRepository) FindSession(id int64) (*models.UserSession, error) {
+	session := &models.UserSession{}
+	query := `SELECT id, user_id, ip_address, user_agent, created_at, last_used_at, expires_at, revoked_at
+	          FROM user_session WHERE id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(
+		&session.ID, &session.UserID, &session.IPAddress, &session.UserAgent,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ExtendSession moves the session expiry along with a newly issued refresh token
+func (r *sessionRepository) ExtendSession(tx *sql.Tx, id int64, expiresAt time.Time) error {
+	_, err := tx.Exec(`UPDATE user_session SET expires_at = $1, last_used_at = CURRENT_TIMESTAMP WHERE id = $2`,
+		expiresAt, id)
+	return err
+}
+
+// RevokeSession ends a session; revoking it twice is not an error
+func (r *sessionRepository) RevokeSession(id int64) error {
+	_, err := r.db.Exec(`UPDATE user_session SET revoked_at = CURRENT_TIMESTAMP
+	                     WHERE id = $1 AND revoked_at IS NULL`, id)
+	return err
+}
+
+// RevokeUserSessions ends every session of a user except exceptSessionID
+// (0 ends them all)
+func (r *sessionRepository) RevokeUserSessions(userID int, exceptSessionID int64) error {
+	_, err := r.db.Exec(`UPDATE user_session SET revoked_at = CURRENT_TIMESTAMP
+	                     WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`, userID, exceptSessionID)
+	return err
+}
+
+// DeleteExpiredSessions removes a user's sessions that can no longer be
+// refreshed, together with their refresh tokens
+func (r *sessionRepository) DeleteExpiredSessions(userID int) error {
+	_, err := r.db.Exec(`DELETE FROM user_session WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP`, userID)
+	return err
+}
+
+func (r *sessionRepository) IsSessionActive(id int64) (bool, error) {
+	var active bool
+	query := `SELECT EXISTS (SELECT 1 FROM user_session
+	          WHERE id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP)`
+
+	if err := r.db.QueryRow(query, id).Scan(&active); err != nil {
+		return false, err
+	}
+	return active, nil
+}
+
+func (r *sessionRepository) CreateRefreshToken(tx *sql.Tx, token *models.RefreshToken) error {
+	query := `INSERT INTO refresh_token (session_id, token_hash, expires_at)
+	          VALUES ($1, $2, $3) RETURNING id, created_at`
+
+	return tx.QueryRow(query, token.SessionID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *sessionRepository) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	query := `SELECT id, session_id, token_hash, expires_at, used_at, created_at
+	          FROM refresh_token WHERE token_hash = $1`
+
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID, &token.SessionID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("refresh token not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// MarkRefreshTokenUsed reports false when the token was already used, so two
+// concurrent refreshes with the same token cannot both succeed
+func (r *sessionRepository) MarkRefreshTokenUsed(tx *sql.Tx, id int64) (bool, error) {
+	result, err := tx.Exec(`UPDATE refresh_token SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
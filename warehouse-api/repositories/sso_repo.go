@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"warehouse-api/models"
+)
+
+type SSORepository interface {
+	FindGroupRoles() ([]models.SSOGroupRole, error)
+	ReplaceGroupRoles(mappings []models.SSOGroupRole) error
+	FindUserByIdentity(issuer, subject string) (*models.User, error)
+	CreateUser(user *models.User, issuer, subject string) error
+	CreateState(state *models.SSOLoginState) error
+	TakeState(stateHash string) (*models.SSOLoginState, error)
+	DeleteExpiredStates(now time.Time) error
+}
+
+type ssoRepository struct {
+	db *sql.DB
+}
+
+func NewSSORepository(db *sql.DB) SSORepository {
+	return &ssoRepository{db: db}
+}
+
+func (r *ssoRepository) FindGroupRoles() ([]models.SSOGroupRole, error) {
+	mappings := []models.SSOGroupRole{}
+
+	rows, err := r.db.Query(`SELECT group_name, role, priority FROM sso_group_role ORDER BY priority, group_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.SSOGroupRole
+		if err := rows.Scan(&m.GroupName, &m.Role, &m.Priority); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, m)
+	}
+
+	return mappings, rows.Err()
+}
+
+func (r *ssoRepository) ReplaceGroupRoles(mappings []models.SSOGroupRole) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM sso_group_role`); err != nil {
+		return err
+	}
+
+	for _, m := range mappings {
+		query := `INSERT INTO sso_group_role (group_name, role, priority) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(query, m.GroupName, m.Role, m.Priority); err != nil {
+			return roleError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindUserByIdentity returns the user linked to an IdP subject, or nil
+func (r *ssoRepository) FindUserByIdentity(issuer, subject string) (*models.User, error) {
+	user := &models.User{}
+	query := `SELECT ` + userColumns + ` FROM users WHERE sso_issuer = $1 AND sso_subject = $2`
+
+	err := scanUser(r.db.QueryRow(query, issuer, subject), user)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CreateUser provisions a user linked to an IdP subject. A local account
+// with the same username is never taken over
+func (r *ssoRepository) CreateUser(user *models.User, issuer, subject string) error {
+	var taken bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))`,
+		user.Username).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("username already exists")
+	}
+
+	query := `INSERT INTO users (username, password, nama, role, sso_issuer, sso_subject)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, is_active, created_at, updated_at`
+
+	err := r.db.QueryRow(query, user.Username, user.Password, user.Nama, user.Role, issuer, subject).Scan(
+		&user.ID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+	)
+	return roleError(err)
+}
+
+func (r *ssoRepository) CreateState(state *models.SSOLoginState) error {
+	query := `INSERT INTO sso_login_state (state_hash, nonce, code_verifier, expires_at)
+	          VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	return r.db.QueryRow(query, state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt).
+		Scan(&state.ID, &state.CreatedAt)
+}
+
+// TakeState returns and deletes a login state, so each works once
+func (r *ssoRepository) TakeState(stateHash string) (*models.SSOLoginState, error) {
+	state := &models.SSOLoginState{}
+	query := `DELETE FROM sso_login_state WHERE state_hash = $1
+	          RETURNING id, state_hash, nonce, code_verifier, expires_at, created_at`
+
+	err := r.db.QueryRow(query, stateHash).Scan(&state.ID, &state.StateHash, &state.Nonce, &state.CodeVerifier,
+		&state.ExpiresAt, &state.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("sso state not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (r *ssoRepository) DeleteExpiredStates(now time.Time) error {
+	_, err := r.db.Exec(`DELETE FROM sso_login_state WHERE expires_at < $1`, now)
+	return err
+}
@@ -8,11 +8,17 @@ import (
 
 type StokRepository interface {
 	FindAll() ([]models.StokWithBarang, error)
+	ForEach(fn func(models.StokWithBarang) error) error
 	FindByBarangID(barangID int) (*models.Stok, error)
 	UpdateStok(tx *sql.Tx, barangID int, stokMasuk int, stokKeluar int) error
 	CreateStok(tx *sql.Tx, barangID int) error
+	FindByBarangIDForUpdate(tx *sql.Tx, barangID int) (*models.Stok, error)
+	HasTransactions(tx *sql.Tx, barangID int) (bool, error)
+	SetStokAwal(tx *sql.Tx, barangID int, stokAwal int) (int, error)
 	InsertHistory(tx *sql.Tx, history *models.HistoryStok) error
+	HasHistoryAwal(tx *sql.Tx, barangID int) (bool, error)
 	GetHistoryAll(limit, offset int) ([]models.HistoryStokWithBarang, int, error)
+	ForEachHistory(fn func(models.HistoryStokWithBarang) error) error
 	GetHistoryByBarangID(barangID int, limit, offset int) ([]models.HistoryStok, int, error)
 }
 
@@ -27,6 +33,19 @@ func NewStokRepository(db *sql.DB) StokRepository {
 func (r *stokRepository) FindAll() ([]models.StokWithBarang, error) {
 	var stoks []models.StokWithBarang
 
+	err := r.ForEach(func(s models.StokWithBarang) error {
+		stoks = append(stoks, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stoks, nil
+}
+
+// ForEach calls fn for every stock row, stopping at the first error
+func (r *stokRepository) ForEach(fn func(models.StokWithBarang) error) error {
 	query := `SELECT s.id, s.barang_id, s.stok_awal, s.stok_masuk, s.stok_keluar, 
 	          s.stok_akhir, s.created_at, s.updated_at,
 	          b.kode_barang, b.nama_barang, b.satuan
@@ -36,7 +55,7 @@ func (r *stokRepository) FindAll() ([]models.StokWithBarang, error) {
 
 	rows, err := r.db.Query(query)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -46,12 +65,14 @@ func (r *stokRepository) FindAll() ([]models.StokWithBarang, error) {
 			&s.StokKeluar, &s.StokAkhir, &s.CreatedAt, &s.UpdatedAt,
 			&s.KodeBarang, &s.NamaBarang, &s.Satuan)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(s); err != nil {
+			return err
 		}
-		stoks = append(stoks, s)
 	}
 
-	return stoks, nil
+	return rows.Err()
 }
 
 func (r *stokRepository) FindByBarangID(barangID int) (*models.Stok, error) {
@@ -74,6 +95,53 @@ func (r *stokRepository) FindByBarangID(barangID int) (*models.Stok, error) {
 	return stok, nil
 }
 
+// FindByBarangIDForUpdate loads a stock row and locks it until tx ends; nil when
+// the barang has no stock row yet
+func (r *stokRepository) FindByBarangIDForUpdate(tx *sql.Tx, barangID int) (*models.Stok, error) {
+	stok := &models.Stok{}
+	query := `SELECT id, barang_id, stok_awal, stok_masuk, stok_keluar, stok_akhir, 
+	          created_at, updated_at FROM mstok WHERE barang_id = $1 FOR UPDATE`
+
+	err := tx.QueryRow(query, barangID).Scan(
+		&stok.ID, &stok.BarangID, &stok.StokAwal, &stok.StokMasuk,
+		&stok.StokKeluar, &stok.StokAkhir, &stok.CreatedAt, &stok.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return stok, nil
+}
+
+// HasTransactions reports whether any stock movement other than the opening
+// balance has been recorded for a barang
+func (r *stokRepository) HasTransactions(tx *sql.Tx, barangID int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM history_stok WHERE barang_id = $1 AND jenis_transaksi <> 'awal')
+	          OR EXISTS (SELECT 1 FROM mstok WHERE barang_id = $1 AND (stok_masuk <> 0 OR stok_keluar <> 0))`
+
+	err := tx.QueryRow(query, barangID).Scan(&exists)
+	return exists, err
+}
+
+// SetStokAwal replaces the opening balance and recomputes stok_akhir from it,
+// returning the new stok_akhir
+func (r *stokRepository) SetStokAwal(tx *sql.Tx, barangID int, stokAwal int) (int, error) {
+	var stokAkhir int
+	query := `UPDATE mstok SET stok_awal = $1, stok_akhir = $1 + stok_masuk - stok_keluar
+	          WHERE barang_id = $2 RETURNING stok_akhir`
+
+	err := tx.QueryRow(query, stokAwal, barangID).Scan(&stokAkhir)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("stok not found for barang_id: %d", barangID)
+	}
+	return stokAkhir, err
+}
+
 func (r *stokRepository) UpdateStok(tx *sql.Tx, barangID int, stokMasuk int, stokKeluar int) error {
 	query := `UPDATE mstok SET 
 	          stok_masuk = stok_masuk + $1,
@@ -108,12 +176,22 @@ func (r *stokRepository) CreateStok(tx *sql.Tx, barangID int) error {
 
 func (r *stokRepository) InsertHistory(tx *sql.Tx, history *models.HistoryStok) error {
 	query := `INSERT INTO history_stok (barang_id, jenis_transaksi, qty, stok_sebelum, 
-	          stok_sesudah, keterangan, referensi_id, referensi_tipe)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`
+	          stok_sesudah, keterangan, referensi_id, referensi_tipe, harga)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`
 
 	return tx.QueryRow(query, history.BarangID, history.JenisTransaksi, history.Qty,
 		history.StokSebelum, history.StokSesudah, history.Keterangan,
-		history.ReferensiID, history.ReferensiTipe).Scan(&history.ID, &history.CreatedAt)
+		history.ReferensiID, history.ReferensiTipe, history.Harga).Scan(&history.ID, &history.CreatedAt)
+}
+
+// HasHistoryAwal reports whether the barang's opening balance is already in
+// the ledger, so a new one is written as a correction
+func (r *stokRepository) HasHistoryAwal(tx *sql.Tx, barangID int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM history_stok WHERE barang_id = $1 AND jenis_transaksi = 'awal')`
+
+	err := tx.QueryRow(query, barangID).Scan(&exists)
+	return exists, err
 }
 
 func (r *stokRepository) GetHistoryAll(limit, offset int) ([]models.HistoryStokWithBarang, int, error) {
@@ -129,7 +207,7 @@ func (r *stokRepository) GetHistoryAll(limit, offset int) ([]models.HistoryStokW
 
 	// Get data
 	query := `SELECT h.id, h.barang_id, h.jenis_transaksi, h.qty, h.stok_sebelum, 
-	          h.stok_sesudah, h.keterangan, h.referensi_id, h.referensi_tipe, h.created_at,
+	          h.stok_sesudah, h.keterangan, h.referensi_id, h.referensi_tipe, h.harga, h.created_at,
 	          b.kode_barang, b.nama_barang
 	          FROM history_stok h
 	          JOIN master_barang b ON h.barang_id = b.id
@@ -145,7 +223,7 @@ func (r *stokRepository) GetHistoryAll(limit, offset int) ([]models.HistoryStokW
 		var h models.HistoryStokWithBarang
 		err := rows.Scan(&h.ID, &h.BarangID, &h.JenisTransaksi, &h.Qty,
 			&h.StokSebelum, &h.StokSesudah, &h.Keterangan, &h.ReferensiID,
-			&h.ReferensiTipe, &h.CreatedAt, &h.KodeBarang, &h.NamaBarang)
+			&h.ReferensiTipe, &h.Harga, &h.CreatedAt, &h.KodeBarang, &h.NamaBarang)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -155,6 +233,38 @@ func (r *stokRepository) GetHistoryAll(limit, offset int) ([]models.HistoryStokW
 	return histories, total, nil
 }
 
+// ForEachHistory calls fn for every stock movement, newest first, stopping at
+// the first error
+func (r *stokRepository) ForEachHistory(fn func(models.HistoryStokWithBarang) error) error {
+	query := `SELECT h.id, h.barang_id, h.jenis_transaksi, h.qty, h.stok_sebelum, 
+	          h.stok_sesudah, h.keterangan, h.referensi_id, h.referensi_tipe, h.harga, h.created_at,
+	          b.kode_barang, b.nama_barang
+	          FROM history_stok h
+	          JOIN master_barang b ON h.barang_id = b.id
+	          ORDER BY h.created_at DESC`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h models.HistoryStokWithBarang
+		err := rows.Scan(&h.ID, &h.BarangID, &h.JenisTransaksi, &h.Qty,
+			&h.StokSebelum, &h.StokSesudah, &h.Keterangan, &h.ReferensiID,
+			&h.ReferensiTipe, &h.Harga, &h.CreatedAt, &h.KodeBarang, &h.NamaBarang)
+		if err != nil {
+			return err
+		}
+		if err := fn(h); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func (r *stokRepository) GetHistoryByBarangID(barangID int, limit, offset int) ([]models.HistoryStok, int, error) {
 	var histories []models.HistoryStok
 	var total int
@@ -168,7 +278,7 @@ func (r *stokRepository) GetHistoryByBarangID(barangID int, limit, offset int) (
 
 	// Get data
 	query := `SELECT id, barang_id, jenis_transaksi, qty, stok_sebelum, stok_sesudah,
-	          keterangan, referensi_id, referensi_tipe, created_at
+	          keterangan, referensi_id, referensi_tipe, harga, created_at
 	          FROM history_stok WHERE barang_id = $1
 	          ORDER BY created_at DESC LIMIT $2 OFFSET $3`
 
@@ -182,7 +292,7 @@ func (r *stokRepository) GetHistoryByBarangID(barangID int, limit, offset int) (
 		var h models.HistoryStok
 		err := rows.Scan(&h.ID, &h.BarangID, &h.JenisTransaksi, &h.Qty,
 			&h.StokSebelum, &h.StokSesudah, &h.Keterangan, &h.ReferensiID,
-			&h.ReferensiTipe, &h.CreatedAt)
+			&h.ReferensiTipe, &h.Harga, &h.CreatedAt)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+// supplierNameKey normalizes a supplier name the same way as idx_supplier_nama_key
+const supplierNameKey = `LOWER(REGEXP_REPLACE(REGEXP_REPLACE(BTRIM(%s), '[.,]', '', 'g'), '\s+', ' ', 'g'))`
+
+type SupplierRepository interface {
+	FindAll(search string, limit, offset int) ([]models.Supplier, int, error)
+	FindByID(id int) (*models.Supplier, error)
+	Create(supplier *models.Supplier) error
+	Update(supplier *models.Supplier) error
+	Delete(id int) error
+	GenerateKodeSupplier() (string, error)
+}
+
+type supplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) SupplierRepository {
+	return &supplierRepository{db: db}
+}
+
+func (r *supplierRepository) FindAll(search string, limit, offset int) ([]models.Supplier, int, error) {
+	var suppliers []models.Supplier
+	var total int
+
+	// Count total
+	countQuery := `SELECT COUNT(*) FROM supplier WHERE 
+	               nama_supplier ILIKE $1 OR kode_supplier ILIKE $1`
+	searchPattern := "%" + search + "%"
+	err := r.db.QueryRow(countQuery, searchPattern).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Get data with pagination
+	query := `SELECT id, kode_supplier, nama_supplier, COALESCE(alamat, ''), COALESCE(telepon, ''),
+	          COALESCE(npwp, ''), termin_pembayaran, created_at, updated_at
+	          FROM supplier
+	          WHERE nama_supplier ILIKE $1 OR kode_supplier ILIKE $1
+	          ORDER BY nama_supplier ASC LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, searchPattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Supplier
+		err := rows.Scan(&s.ID, &s.KodeSupplier, &s.NamaSupplier, &s.Alamat, &s.Telepon,
+			&s.NPWP, &s.TerminPembayaran, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		suppliers = append(suppliers, s)
+	}
+
+	return suppliers, total, nil
+}
+
+func (r *supplierRepository) FindByID(id int) (*models.Supplier, error) {
+	supplier := &models.Supplier{}
+	query := `SELECT id, kode_supplier, nama_supplier, COALESCE(alamat, ''), COALESCE(telepon, ''),
+	          COALESCE(npwp, ''), termin_pembayaran, created_at, updated_at
+	          FROM supplier WHERE id = $1`
+
+	err := r.db.QueryRow(query, id).Scan(
+		&supplier.ID, &supplier.KodeSupplier, &supplier.NamaSupplier, &supplier.Alamat,
+		&supplier.Telepon, &supplier.NPWP, &supplier.TerminPembayaran,
+		&supplier.CreatedAt, &supplier.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("supplier not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return supplier, nil
+}
+
+func (r *supplierRepository) GenerateKodeSupplier() (string, error) {
+	var lastNumber int
+	query := `SELECT COALESCE(MAX(CAST(SUBSTRING(kode_supplier FROM 4) AS INTEGER)), 0) 
+	          FROM supplier WHERE kode_supplier ~ '^SUP[0-9]+$'`
+
+	err := r.db.QueryRow(query).Scan(&lastNumber)
+	if err != nil {
+		return "", err
+	}
+
+	nextNumber := lastNumber + 1
+	return fmt.Sprintf("SUP%03d", nextNumber), nil
+}
+
+// nameTaken reports whether another supplier already uses an equivalent name
+func (r *supplierRepository) nameTaken(nama string, excludeID int) (bool, error) {
+	var count int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM supplier WHERE %s = %s AND id <> $2`,
+		fmt.Sprintf(supplierNameKey, "nama_supplier"), fmt.Sprintf(supplierNameKey, "$1"))
+
+	if err := r.db.QueryRow(query, nama, excludeID).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *supplierRepository) Create(supplier *models.Supplier) error {
+	taken, err := r.nameTaken(supplier.NamaSupplier, 0)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("supplier already exists")
+	}
+
+	// Auto-generate kode supplier if empty
+	if supplier.KodeSupplier == "" {
+		kode, err := r.GenerateKodeSupplier()
+		if err != nil {
+			return err
+		}
+		supplier.KodeSupplier = kode
+	}
+
+	query := `INSERT INTO supplier (kode_supplier, nama_supplier, alamat, telepon, npwp, termin_pembayaran)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query, supplier.KodeSupplier, supplier.NamaSupplier, supplier.Alamat,
+		supplier.Telepon, supplier.NPWP, supplier.TerminPembayaran).Scan(
+		&supplier.ID, &supplier.CreatedAt, &supplier.UpdatedAt,
+	)
+}
+
+func (r *supplierRepository) Update(supplier *models.Supplier) error {
+	taken, err := r.nameTaken(supplier.NamaSupplier, supplier.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("supplier already exists")
+	}
+
+	query := `UPDATE supplier SET nama_supplier = $1, alamat = $2, telepon = $3,
+	          npwp = $4, termin_pembayaran = $5 WHERE id = $6`
+
+	result, err := r.db.Exec(query, supplier.NamaSupplier, supplier.Alamat, supplier.Telepon,
+		supplier.NPWP, supplier.TerminPembayaran, supplier.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("supplier not found")
+	}
+
+	return nil
+}
+
+func (r *supplierRepository) Delete(id int) error {
+	// Suppliers referenced by pembelian must be kept for reporting
+	var used int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM beli_header WHERE supplier_id = $1`, id).Scan(&used); err != nil {
+		return err
+	}
+	if used > 0 {
+		return fmt.Errorf("supplier is used by pembelian")
+	}
+
+	query := `DELETE FROM supplier WHERE id = $1`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("supplier not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"warehouse-api/models"
+)
+
+type TemplateDokumenRepository interface {
+	FindAll() ([]models.TemplateDokumen, error)
+	FindByJenis(jenis string) (*models.TemplateDokumen, error)
+	Update(template *models.TemplateDokumen) error
+}
+
+type templateDokumenRepository struct {
+	db *sql.DB
+}
+
+func NewTemplateDokumenRepository(db *sql.DB) TemplateDokumenRepository {
+	return &templateDokumenRepository{db: db}
+}
+
+const templateDokumenColumns = `jenis, judul, nama_perusahaan, COALESCE(alamat, ''), COALESCE(telepon, ''),
+	          COALESCE(email, ''), COALESCE(npwp, ''), COALESCE(catatan, ''), COALESCE(label_ttd_kiri, ''),
+	          COALESCE(label_ttd_kanan, ''), COALESCE(nama_ttd_kanan, ''), ukuran_kertas,
+	          tampilkan_terbilang, updated_by, created_at, updated_at`
+
+func scanTemplateDokumen(row interface{ Scan(...interface{}) error }, t *models.TemplateDokumen) error {
+	return row.Scan(&t.Jenis, &t.Judul, &t.NamaPerusahaan, &t.Alamat, &t.Telepon, &t.Email,
+		&t.NPWP, &t.Catatan, &t.LabelTtdKiri, &t.LabelTtdKanan, &t.NamaTtdKanan, &t.UkuranKertas,
+		&t.TampilkanTerbilang, &t.UpdatedBy, &t.CreatedAt, &t.UpdatedAt)
+}
+
+func (r *templateDokumenRepository) FindAll() ([]models.TemplateDokumen, error) {
+	var templates []models.TemplateDokumen
+
+	query := `SELECT ` + templateDokumenColumns + ` FROM template_dokumen ORDER BY jenis`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.TemplateDokumen
+		if err := scanTemplateDokumen(rows, &t); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, nil
+}
+
+func (r *templateDokumenRepository) FindByJenis(jenis string) (*models.TemplateDokumen, error) {
+	template := &models.TemplateDokumen{}
+	query := `SELECT ` + templateDokumenColumns + ` FROM template_dokumen WHERE jenis = $1`
+
+	err := scanTemplateDokumen(r.db.QueryRow(query, jenis), template)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("template dokumen not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (r *templateDokumenRepository) Update(template *models.TemplateDokumen) error {
+	query := `UPDATE template_dokumen SET judul = $1, nama_perusahaan = $2, alamat = $3, telepon = $4,
+	          email = $5, npwp = $6, catatan = $7, label_ttd_kiri = $8, label_ttd_kanan = $9,
+	          nama_ttd_kanan = $10, ukuran_kertas = $11, tampilkan_terbilang = $12, updated_by = $13
+	          WHERE jenis = $14
+	          RETURNING created_at, updated_at`
+
+	err := r.db.QueryRow(query, template.Judul, template.NamaPerusahaan, template.Alamat,
+		template.Telepon, template.Email, template.NPWP, template.Catatan, template.LabelTtdKiri,
+		template.LabelTtdKanan, template.NamaTtdKanan, template.UkuranKertas,
+		template.TampilkanTerbilang, template.UpdatedBy, template.Jenis).Scan(
+		&template.CreatedAt, &template.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("template dokumen not found")
+	}
+
+	return err
+}
@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"warehouse-api/models"
+
+	"github.com/lib/pq"
+)
+
+type TwoFactorRepository interface {
+	SetSecret(userID int, secret string) error
+	Enable(tx *sql.Tx, userID int, step int64) error
+	Disable(tx *sql.Tx, userID int) error
+	UseStep(userID int, step int64) (bool, error)
+	ReplaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	CountRecoveryCodes(userID int) (int, error)
+	FindUsersWithout2FA(role string) ([]int, error)
+	CreateChallenge(challenge *models.LoginChallenge) error
+	FindChallenge(tokenHash string) (*models.LoginChallenge, error)
+	AddChallengeAttempt(id int) (int, error)
+	DeleteChallenge(id int) error
+	DeleteExpiredChallenges(now time.Time) error
+}
+
+type twoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// SetSecret stores the secret of an enrolment that has not been confirmed
+// yet; it fails once 2FA is enabled, which has to be disabled first
+func (r *twoFactorRepository) SetSecret(userID int, secret string) error {
+	result, err := r.db.Exec(`UPDATE users SET totp_secret = $1 WHERE id = $2 AND NOT totp_enabled`, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("two-factor already enabled")
+	}
+
+	return nil
+}
+
+// Enable turns 2FA on; step is the time step of the code that confirmed it
+func (r *twoFactorRepository) Enable(tx *sql.Tx, userID int, step int64) error {
+	query := `UPDATE users SET totp_enabled = TRUE, totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $1
+	          WHERE id = $2 AND totp_secret IS NOT NULL AND NOT totp_enabled`
+	result, err := tx.Exec(query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("two-factor already enabled")
+	}
+
+	return nil
+}
+
+// Disable turns 2FA off and forgets the secret and the recovery codes
+func (r *twoFactorRepository) Disable(tx *sql.Tx, userID int) error {
+	query := `UPDATE users SET totp_enabled = FALSE, totp_enabled_at = NULL, totp_secret = NULL, totp_last_step = NULL
+	          WHERE id = $1`
+	if _, err := tx.Exec(query, userID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`DELETE FROM user_recovery_code WHERE user_id = $1`, userID)
+	return err
+}
+
+// UseStep records that a code of the given time step was accepted. It
+// returns false when that step or a later one was already used, so each
+// code works only once even with concurrent requests
+func (r *twoFactorRepository) UseStep(userID int, step int64) (bool, error) {
+	query := `UPDATE users SET totp_last_step = $1
+	          WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)`
+	result, err := r.db.Exec(query, step, userID)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_code WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_recovery_code (user_id, code_hash) SELECT $1, UNNEST($2::text[])`
+	_, err := tx.Exec(query, userID, pq.Array(codeHashes))
+	return err
+}
+
+// UseRecoveryCode marks an unused recovery code as used, returning false
+// when there is no such code
+func (r *twoFactorRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	query := `UPDATE user_recovery_code SET used_at = CURRENT_TIMESTAMP
+	          WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *twoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM user_recovery_code WHERE user_id = $1 AND used_at IS NULL`
+
+	if err := r.db.QueryRow(query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindUsersWithout2FA lists the users of a role who have not enabled 2FA
+func (r *twoFactorRepository) FindUsersWithout2FA(role string) ([]int, error) {
+	ids := []int{}
+
+	rows, err := r.db.Query(`SELECT id FROM users WHERE role = $1 AND NOT totp_enabled ORDER BY id`, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *twoFactorRepository) CreateChallenge(challenge *models.LoginChallenge) error {
+	query := `INSERT INTO login_challenge (user_id, token_hash, purpose, ip_address, expires_at)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	return r.db.QueryRow(query, challenge.UserID, challenge.TokenHash, challenge.Purpose, challenge.IPAddress,
+		challenge.ExpiresAt).Scan(&challenge.ID, &challenge.CreatedAt)
+}
+
+func (r *twoFactorRepository) FindChallenge(tokenHash string) (*models.LoginChallenge, error) {
+	challenge := &models.LoginChallenge{}
+	query := `SELECT id, user_id, token_hash, purpose, attempts, ip_address, expires_at, created_at
+	          FROM login_challenge WHERE token_hash = $1`
+
+	err := r.db.QueryRow(query, tokenHash).Scan(&challenge.ID, &challenge.UserID, &challenge.TokenHash,
+		&challenge.Purpose, &challenge.Attempts, &challenge.IPAddress, &challenge.ExpiresAt, &challenge.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("login challenge not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// AddChallengeAttempt counts a wrong code and returns the new count
+func (r *twoFactorRepository) AddChallengeAttempt(id int) (int, error) {
+	var attempts int
+	query := `UPDATE login_challenge SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`
+
+	err := r.db.QueryRow(query, id).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("login challenge not found")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+func (r *twoFactorRepository) DeleteChallenge(id int) error {
+	_, err := r.db.Exec(`DELETE FROM login_challenge WHERE id = $1`, id)
+	return err
+}
+
+func (r *twoFactorRepository) DeleteExpiredChallenges(now time.Time) error {
+	_, err := r.db.Exec(`DELETE FROM login_challenge WHERE expires_at < $1`, now)
+	return err
+}
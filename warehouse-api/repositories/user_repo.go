@@ -2,13 +2,21 @@ package repositories
 
 import (
 	"database/sql"
+	"fmt"
 	"warehouse-api/models"
+
+	"github.com/lib/pq"
 )
 
 type UserRepository interface {
+	FindAll(search, role string, limit, offset int) ([]models.User, int, error)
 	FindByUsername(username string) (*models.User, error)
 	FindByID(id int) (*models.User, error)
 	Create(user *models.User) error
+	Update(user *models.User) error
+	UpdatePassword(id int, password string) error
+	SetActive(id int, active bool) error
+	Delete(id int) error
 }
 
 type userRepository struct {
@@ -19,15 +27,55 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) FindByUsername(username string) (*models.User, error) {
-	user := &models.User{}
-	query := `SELECT id, username, password, nama, role, created_at, updated_at 
-	          FROM users WHERE username = $1`
+const userColumns = `id, username, password, nama, role, is_active, disabled_at, totp_enabled, totp_secret,
+	created_at, updated_at`
 
-	err := r.db.QueryRow(query, username).Scan(
+func scanUser(row interface{ Scan(...interface{}) error }, user *models.User) error {
+	return row.Scan(
 		&user.ID, &user.Username, &user.Password, &user.Nama,
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role, &user.IsActive, &user.DisabledAt, &user.TwoFactorEnabled, &user.TOTPSecret,
+		&user.CreatedAt, &user.UpdatedAt,
 	)
+}
+
+// FindAll lists users whose username or nama matches search, optionally
+// limited to one role
+func (r *userRepository) FindAll(search, role string, limit, offset int) ([]models.User, int, error) {
+	users := []models.User{}
+	var total int
+
+	where := `WHERE (username ILIKE $1 OR nama ILIKE $1) AND ($2 = '' OR role = $2)`
+	searchPattern := "%" + search + "%"
+
+	// Count total
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM users `+where, searchPattern, role).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// Get data with pagination
+	query := `SELECT ` + userColumns + ` FROM users ` + where + ` ORDER BY username ASC LIMIT $3 OFFSET $4`
+	rows, err := r.db.Query(query, searchPattern, role, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, rows.Err()
+}
+
+func (r *userRepository) FindByUsername(username string) (*models.User, error) {
+	user := &models.User{}
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+
+	err := scanUser(r.db.QueryRow(query, username), user)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -41,13 +89,9 @@ func (r *userRepository) FindByUsername(username string) (*models.User, error) {
 
 func (r *userRepository) FindByID(id int) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, username, password, nama, role, created_at, updated_at 
-	          FROM users WHERE id = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Username, &user.Password, &user.Nama,
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRow(query, id), user)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -60,10 +104,104 @@ func (r *userRepository) FindByID(id int) (*models.User, error) {
 }
 
 func (r *userRepository) Create(user *models.User) error {
+	var taken bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))`,
+		user.Username).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("username already exists")
+	}
+
 	query := `INSERT INTO users (username, password, nama, role) 
-	          VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
+	          VALUES ($1, $2, $3, $4) RETURNING id, is_active, created_at, updated_at`
 
-	return r.db.QueryRow(query, user.Username, user.Password, user.Nama, user.Role).Scan(
-		&user.ID, &user.CreatedAt, &user.UpdatedAt,
+	err := r.db.QueryRow(query, user.Username, user.Password, user.Nama, user.Role).Scan(
+		&user.ID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
 	)
+	return roleError(err)
+}
+
+func (r *userRepository) Update(user *models.User) error {
+	query := `UPDATE users SET nama = $1, role = $2 WHERE id = $3 RETURNING updated_at`
+
+	err := r.db.QueryRow(query, user.Nama, user.Role, user.ID).Scan(&user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	}
+	return roleError(err)
+}
+
+// roleError reports a users.role foreign key violation as an unknown role
+func roleError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return fmt.Errorf("role not found")
+	}
+	return err
+}
+
+// UpdatePassword stores an already hashed password
+func (r *userRepository) UpdatePassword(id int, password string) error {
+	result, err := r.db.Exec(`UPDATE users SET password = $1 WHERE id = $2`, password, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SetActive enables or disables a user's login
+func (r *userRepository) SetActive(id int, active bool) error {
+	query := `UPDATE users SET is_active = $1,
+	          disabled_at = CASE WHEN $1 THEN NULL ELSE COALESCE(disabled_at, CURRENT_TIMESTAMP) END
+	          WHERE id = $2`
+
+	result, err := r.db.Exec(query, active, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// Delete removes a user that never created or changed anything. Users
+// referenced by documents, stock or price history must be disabled instead
+func (r *userRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		// 23503 is foreign_key_violation: some row still points at the user
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return fmt.Errorf("user has activity")
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
 }
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"sync"
+	"time"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+const (
+	// apiKeyCacheTTL bounds how long a revoked key can keep working on another
+	// server instance; revocations made through this instance apply immediately
+	apiKeyCacheTTL = 30 * time.Second
+	// apiKeyTouchInterval limits how often last_used_at is written per key
+	apiKeyTouchInterval = time.Minute
+
+	apiKeyPrefix       = "wk_"
+	apiKeyPrefixLength = 11
+)
+
+type APIKeyService interface {
+	GetAll() ([]models.APIKey, error)
+	GetByID(id int) (*models.APIKey, error)
+	Create(key *models.APIKey) (*models.NewAPIKey, error)
+	Update(key *models.APIKey) error
+	Revoke(id int) error
+	AuthenticateAPIKey(key, ip string) (*middleware.Claims, map[string]bool, error)
+}
+
+type apiKeyCacheEntry struct {
+	key      *models.APIKey
+	loadedAt time.Time
+}
+
+type apiKeyService struct {
+	apiKeyRepo  repositories.APIKeyRepository
+	userRepo    repositories.UserRepository
+	roleService RoleService
+
+	mu      sync.Mutex
+	cache   map[string]apiKeyCacheEntry
+	touched map[int]time.Time
+}
+
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepository, userRepo repositories.UserRepository,
+	roleService RoleService) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo:  apiKeyRepo,
+		userRepo:    userRepo,
+		roleService: roleService,
+		cache:       make(map[string]apiKeyCacheEntry),
+		touched:     make(map[int]time.Time),
+	}
+}
+
+func (s *apiKeyService) GetAll() ([]models.APIKey, error) {
+	return s.apiKeyRepo.FindAll()
+}
+
+func (s *apiKeyService) GetByID(id int) (*models.APIKey, error) {
+	return s.apiKeyRepo.FindByID(id)
+}
+
+// Create generates a new random key and stores its hash. The key itself is
+// only ever returned here
+func (s *apiKeyService) Create(key *models.APIKey) (*models.NewAPIKey, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	raw := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	key.KeyPrefix = raw[:apiKeyPrefixLength]
+	key.KeyHash = hashToken(raw)
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, err
+	}
+
+	created, err := s.apiKeyRepo.FindByID(key.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.NewAPIKey{APIKey: *created, Key: raw}, nil
+}
+
+func (s *apiKeyService) Update(key *models.APIKey) error {
+	if err := s.apiKeyRepo.Update(key); err != nil {
+		return err
+	}
+	s.clearCache()
+	return nil
+}
+
+func (s *apiKeyService) Revoke(id int) error {
+	if err := s.apiKeyRepo.Revoke(id); err != nil {
+		return err
+	}
+	s.clearCache()
+	return nil
+}
+
+// AuthenticateAPIKey implements middleware.APIKeyAuthenticator. The key acts
+// as its owner, but only with the scopes the owner's role still grants, so
+// demoting or disabling the owner limits the key too
+func (s *apiKeyService) AuthenticateAPIKey(raw, ip string) (*middleware.Claims, map[string]bool, error) {
+	key, err := s.findKey(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, nil, &middleware.APIKeyError{Reason: "api key revoked"}
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, nil, &middleware.APIKeyError{Reason: "api key expired"}
+	}
+	if !IPAllowed(key.AllowedIPs, ip) {
+		return nil, nil, &middleware.APIKeyError{Reason: "ip address not allowed"}
+	}
+
+	owner, err := s.userRepo.FindByID(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if owner == nil || !owner.IsActive {
+		return nil, nil, &middleware.APIKeyError{Reason: "api key owner is disabled"}
+	}
+
+	granted, err := s.roleService.RolePermissions(owner.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+	permissions := make(map[string]bool, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		if granted[scope] {
+			permissions[scope] = true
+		}
+	}
+
+	if err := s.touch(key.ID, ip, now); err != nil {
+		return nil, nil, err
+	}
+
+	claims := &middleware.Claims{
+		UserID:   owner.ID,
+		Username: "apikey:" + key.KeyPrefix,
+		Role:     owner.Role,
+		APIKeyID: key.ID,
+	}
+	return claims, permissions, nil
+}
+
+// findKey looks a key up by its hash, caching it for apiKeyCacheTTL
+func (s *apiKeyService) findKey(raw string) (*models.APIKey, error) {
+	keyHash := hashToken(raw)
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[keyHash]
+	s.mu.Unlock()
+	if ok && now.Sub(entry.loadedAt) < apiKeyCacheTTL {
+		return entry.key, nil
+	}
+
+	key, err := s.apiKeyRepo.FindByHash(keyHash)
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return nil, &middleware.APIKeyError{Reason: "unknown api key"}
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	// Drop stale entries now and then so the cache does not grow unbounded
+	if len(s.cache) >= 1000 {
+		for h, e := range s.cache {
+			if now.Sub(e.loadedAt) >= apiKeyCacheTTL {
+				delete(s.cache, h)
+			}
+		}
+	}
+	s.cache[keyHash] = apiKeyCacheEntry{key: key, loadedAt: now}
+	s.mu.Unlock()
+
+	return key, nil
+}
+
+// touch records the key's use, at most once per apiKeyTouchInterval
+func (s *apiKeyService) touch(id int, ip string, now time.Time) error {
+	s.mu.Lock()
+	last, ok := s.touched[id]
+	if ok && now.Sub(last) < apiKeyTouchInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.touched[id] = now
+	s.mu.Unlock()
+
+	return s.apiKeyRepo.Touch(id, ip)
+}
+
+func (s *apiKeyService) clearCache() {
+	s.mu.Lock()
+	s.cache = make(map[string]apiKeyCacheEntry)
+	s.mu.Unlock()
+}
+
+// IPAllowed reports whether ip matches one of allowed, each an address or a
+// CIDR range. An empty list allows any IP
+func IPAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(entry); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
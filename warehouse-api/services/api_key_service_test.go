@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestIPAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		ip      string
+		want    bool
+	}{
+		{"no restriction", nil, "203.0.113.7", true},
+		{"no restriction, bad ip", nil, "not-an-ip", true},
+		{"exact address", []string{"203.0.113.7"}, "203.0.113.7", true},
+		{"other address", []string{"203.0.113.7"}, "203.0.113.8", false},
+		{"inside range", []string{"10.0.0.0/8"}, "10.20.30.40", true},
+		{"first address of range", []string{"192.168.1.0/24"}, "192.168.1.0", true},
+		{"last address of range", []string{"192.168.1.0/24"}, "192.168.1.255", true},
+		{"just outside range", []string{"192.168.1.0/24"}, "192.168.2.0", false},
+		{"single host range", []string{"198.51.100.1/32"}, "198.51.100.1", true},
+		{"single host range, other host", []string{"198.51.100.1/32"}, "198.51.100.2", false},
+		{"second entry matches", []string{"10.0.0.0/8", "203.0.113.7"}, "203.0.113.7", true},
+		{"ipv6 range", []string{"2001:db8::/32"}, "2001:db8:1::5", true},
+		{"ipv6 outside range", []string{"2001:db8::/32"}, "2001:db9::1", false},
+		{"ipv4 mapped ipv6", []string{"10.0.0.0/8"}, "::ffff:10.1.2.3", true},
+		{"invalid entry is skipped", []string{"office", "10.0.0.0/8"}, "10.1.2.3", true},
+		{"invalid entry only", []string{"10.0.0.0/33"}, "10.1.2.3", false},
+		{"client ip unparseable", []string{"10.0.0.0/8"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IPAllowed(tt.allowed, tt.ip); got != tt.want {
+				t.Errorf("IPAllowed(%v, %q) = %v, want %v", tt.allowed, tt.ip, got, tt.want)
+			}
+		})
+	}
+}
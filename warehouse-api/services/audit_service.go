@@ -0,0 +1,64 @@
+package services
+
+import (
+	"encoding/json"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+type AuditService interface {
+	Record(entry *models.AuditLog, before, after interface{}) error
+	GetAll(filter *models.AuditFilter, limit, offset int) ([]models.AuditLog, int, error)
+}
+
+type auditService struct {
+	auditRepo repositories.AuditRepository
+}
+
+func NewAuditService(auditRepo repositories.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// Record stores entry with before and after marshalled to JSON; nil means the
+// entity did not exist on that side of the change
+func (s *auditService) Record(entry *models.AuditLog, before, after interface{}) error {
+	if err := setAuditData(entry, before, after); err != nil {
+		return err
+	}
+	return s.auditRepo.Create(entry)
+}
+
+func (s *auditService) GetAll(filter *models.AuditFilter, limit, offset int) ([]models.AuditLog, int, error) {
+	return s.auditRepo.FindAll(filter, limit, offset)
+}
+
+func setAuditData(entry *models.AuditLog, before, after interface{}) error {
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newAuditEntry builds an entry for a change made inside a service
+// transaction, where only the acting user's id is known
+func newAuditEntry(userID *int, action, entityType string, entityID int, label string, before, after interface{}) (*models.AuditLog, error) {
+	entry := &models.AuditLog{
+		UserID:      userID,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    &entityID,
+		EntityLabel: &label,
+	}
+	if err := setAuditData(entry, before, after); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
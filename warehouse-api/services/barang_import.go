@@ -0,0 +1,268 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"warehouse-api/models"
+)
+
+// maxImportRows bounds a single import file
+const maxImportRows = 5000
+
+// ImportBarang validates every row of a barang CSV/XLSX file. Rows with an
+// existing kode_barang update that barang, the others are created. Only in
+// commit mode, and only when no row has errors, are all rows written in one
+// transaction
+func (s *barangService) ImportBarang(filename string, file io.Reader, commit bool, userID int) (*models.ImportBarangResult, error) {
+	records, err := readSpreadsheet(filename, file)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.validateImportRows(records)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ImportBarangResult{Mode: "dry_run", TotalRows: len(rows), Rows: rows}
+	if commit {
+		result.Mode = "commit"
+	}
+	for _, row := range rows {
+		switch {
+		case len(row.Errors) > 0:
+			result.ErrorRows++
+		case row.Action == "update":
+			result.Updated++
+		default:
+			result.Created++
+		}
+	}
+	result.ValidRows = result.TotalRows - result.ErrorRows
+
+	if !commit || result.ErrorRows > 0 {
+		return result, nil
+	}
+
+	if err := s.commitImportRows(rows, userID); err != nil {
+		return nil, err
+	}
+	result.Committed = true
+
+	return result, nil
+}
+
+func (s *barangService) validateImportRows(records [][]string) ([]models.ImportBarangRow, error) {
+	if len(records) == 0 {
+		return nil, &ImportFileError{Reason: "file is empty"}
+	}
+
+	columns := importHeader(records[0])
+	for _, name := range []string{"nama_barang", "satuan"} {
+		if _, ok := columns[name]; !ok {
+			return nil, &ImportFileError{Reason: fmt.Sprintf("missing column %s", name)}
+		}
+	}
+
+	rows := []models.ImportBarangRow{}
+	firstRowByKode := make(map[string]int)
+	for i, record := range records[1:] {
+		if isBlankRow(record) {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, &ImportFileError{Reason: fmt.Sprintf("cannot import more than %d rows at once", maxImportRows)}
+		}
+
+		row := models.ImportBarangRow{
+			Row:        i + 2,
+			KodeBarang: importCell(record, columns, "kode_barang"),
+			NamaBarang: importCell(record, columns, "nama_barang"),
+			Kategori:   importCell(record, columns, "kategori"),
+			Satuan:     importCell(record, columns, "satuan"),
+			Action:     "create",
+		}
+
+		if row.NamaBarang == "" {
+			row.Errors = append(row.Errors, "nama_barang is required")
+		} else if len(row.NamaBarang) > 200 {
+			row.Errors = append(row.Errors, "nama_barang cannot be longer than 200 characters")
+		}
+		if row.Satuan == "" {
+			row.Errors = append(row.Errors, "satuan is required")
+		} else if len(row.Satuan) > 20 {
+			row.Errors = append(row.Errors, "satuan cannot be longer than 20 characters")
+		}
+		if len(row.Kategori) > 100 {
+			row.Errors = append(row.Errors, "kategori cannot be longer than 100 characters")
+		}
+
+		var existing *models.Barang
+		if row.KodeBarang != "" {
+			if len(row.KodeBarang) > 50 {
+				row.Errors = append(row.Errors, "kode_barang cannot be longer than 50 characters")
+			}
+			if first, ok := firstRowByKode[row.KodeBarang]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("duplicate kode_barang %s (first used on row %d)", row.KodeBarang, first))
+			} else {
+				firstRowByKode[row.KodeBarang] = row.Row
+			}
+
+			barang, err := s.barangRepo.FindByKodeBarang(row.KodeBarang)
+			if err != nil && err.Error() != "barang not found" {
+				return nil, err
+			}
+			if barang != nil {
+				existing = barang
+				row.Action = "update"
+			} else {
+				// A new kode_barang must not shadow another barang's barcode
+				owner, err := s.barangRepo.FindByBarcode(row.KodeBarang)
+				if err != nil && err.Error() != "barang not found" {
+					return nil, err
+				}
+				if owner != nil {
+					row.Errors = append(row.Errors, fmt.Sprintf("kode_barang %s is already a barcode of %s", row.KodeBarang, owner.KodeBarang))
+				}
+			}
+		}
+
+		// Blank cells of an existing barang keep their current value
+		var ok bool
+		if row.HargaBeli, ok = parseImportHarga(&row, importCell(record, columns, "harga_beli"), "harga_beli"); !ok && existing != nil {
+			row.HargaBeli = existing.HargaBeli
+		}
+		if row.HargaJual, ok = parseImportHarga(&row, importCell(record, columns, "harga_jual"), "harga_jual"); !ok && existing != nil {
+			row.HargaJual = existing.HargaJual
+		}
+		if row.Kategori == "" && existing != nil {
+			row.Kategori = existing.Kategori
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, &ImportFileError{Reason: "file has no data rows"}
+	}
+
+	return rows, nil
+}
+
+// parseImportHarga parses a price cell, recording an error on the row when it
+// is not a non-negative number; ok is false for a blank or invalid cell
+func parseImportHarga(row *models.ImportBarangRow, value, column string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	harga, err := strconv.ParseFloat(strings.ReplaceAll(value, " ", ""), 64)
+	if err != nil || math.IsNaN(harga) || math.IsInf(harga, 0) {
+		row.Errors = append(row.Errors, fmt.Sprintf("%s must be a number, got %q", column, value))
+		return 0, false
+	}
+	if harga < 0 {
+		row.Errors = append(row.Errors, fmt.Sprintf("%s cannot be negative", column))
+		return 0, false
+	}
+
+	return harga, true
+}
+
+// commitImportRows writes validated rows in one transaction. Rows with their
+// own kode_barang go first so generated codes never collide with them
+func (s *barangService) commitImportRows(rows []models.ImportBarangRow, userID int) error {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rows[order[a]].KodeBarang != "" && rows[order[b]].KodeBarang == ""
+	})
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, i := range order {
+		row := &rows[i]
+		barang := &models.Barang{
+			KodeBarang: row.KodeBarang,
+			NamaBarang: row.NamaBarang,
+			Kategori:   row.Kategori,
+			Satuan:     row.Satuan,
+			HargaBeli:  row.HargaBeli,
+			HargaJual:  row.HargaJual,
+		}
+
+		if row.Action == "create" {
+			if err := s.barangRepo.CreateTx(tx, barang); err != nil {
+				return fmt.Errorf("row %d: %v", row.Row, err)
+			}
+			row.KodeBarang = barang.KodeBarang
+
+			entry, err := newAuditEntry(&userID, "create", "barang", barang.ID, barang.KodeBarang, nil, barang)
+			if err != nil {
+				return err
+			}
+			if err := s.auditRepo.CreateTx(tx, entry); err != nil {
+				return err
+			}
+			continue
+		}
+
+		existing, err := s.barangRepo.FindByKodeBarang(row.KodeBarang)
+		if err != nil {
+			return fmt.Errorf("row %d: %v", row.Row, err)
+		}
+		existing, err = s.barangRepo.FindByIDForUpdate(tx, existing.ID)
+		if err != nil {
+			return fmt.Errorf("row %d: %v", row.Row, err)
+		}
+
+		barang.ID = existing.ID
+		if barang.Kategori == existing.Kategori {
+			barang.KategoriID = existing.KategoriID
+		}
+		if err := s.barangRepo.Update(tx, barang); err != nil {
+			return fmt.Errorf("row %d: %v", row.Row, err)
+		}
+
+		barang.IsKit = existing.IsKit
+		barang.ArchivedAt = existing.ArchivedAt
+		barang.CreatedAt = existing.CreatedAt
+		entry, err := newAuditEntry(&userID, "update", "barang", barang.ID, barang.KodeBarang, existing, barang)
+		if err != nil {
+			return err
+		}
+		if err := s.auditRepo.CreateTx(tx, entry); err != nil {
+			return err
+		}
+
+		if existing.HargaBeli != barang.HargaBeli || existing.HargaJual != barang.HargaJual {
+			history := &models.HistoryHargaBarang{
+				BarangID:      barang.ID,
+				HargaBeliLama: existing.HargaBeli,
+				HargaBeliBaru: barang.HargaBeli,
+				HargaJualLama: existing.HargaJual,
+				HargaJualBaru: barang.HargaJual,
+				Keterangan:    "Import master barang",
+				ChangedBy:     &userID,
+			}
+
+			if err := s.hargaBarangRepo.InsertHistory(tx, history); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Commit transaction
+	return tx.Commit()
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+type BarangService interface {
+	UpdateBarang(barang *models.Barang, userID int) error
+	GetPriceHistory(barangID int, limit, offset int) ([]models.HistoryHargaBarang, int, error)
+	SchedulePriceChange(barangID int, req *models.CreateJadwalHargaRequest, userID int) (*models.JadwalHargaBarang, error)
+	GetPriceSchedules(barangID int) ([]models.JadwalHargaBarang, error)
+	CancelPriceSchedule(barangID, jadwalID int) error
+	ApplyDuePriceChanges() (int, error)
+	ImportBarang(filename string, file io.Reader, commit bool, userID int) (*models.ImportBarangResult, error)
+}
+
+type barangService struct {
+	db              *sql.DB
+	barangRepo      repositories.BarangRepository
+	hargaBarangRepo repositories.HargaBarangRepository
+	auditRepo       repositories.AuditRepository
+}
+
+func NewBarangService(db *sql.DB, barangRepo repositories.BarangRepository,
+	hargaBarangRepo repositories.HargaBarangRepository, auditRepo repositories.AuditRepository) BarangService {
+	return &barangService{
+		db:              db,
+		barangRepo:      barangRepo,
+		hargaBarangRepo: hargaBarangRepo,
+		auditRepo:       auditRepo,
+	}
+}
+
+// UpdateBarang updates master data and records a price history entry when
+// harga_beli or harga_jual changes
+func (s *barangService) UpdateBarang(barang *models.Barang, userID int) error {
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing, err := s.barangRepo.FindByIDForUpdate(tx, barang.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.barangRepo.Update(tx, barang); err != nil {
+		return err
+	}
+
+	if existing.HargaBeli != barang.HargaBeli || existing.HargaJual != barang.HargaJual {
+		history := &models.HistoryHargaBarang{
+			BarangID:      barang.ID,
+			HargaBeliLama: existing.HargaBeli,
+			HargaBeliBaru: barang.HargaBeli,
+			HargaJualLama: existing.HargaJual,
+			HargaJualBaru: barang.HargaJual,
+			Keterangan:    "Update master barang",
+			ChangedBy:     &userID,
+		}
+
+		if err := s.hargaBarangRepo.InsertHistory(tx, history); err != nil {
+			return err
+		}
+	}
+
+	// Commit transaction
+	return tx.Commit()
+}
+
+func (s *barangService) GetPriceHistory(barangID int, limit, offset int) ([]models.HistoryHargaBarang, int, error) {
+	if _, err := s.barangRepo.FindByID(barangID); err != nil {
+		return nil, 0, err
+	}
+	return s.hargaBarangRepo.GetHistoryByBarangID(barangID, limit, offset)
+}
+
+func (s *barangService) SchedulePriceChange(barangID int, req *models.CreateJadwalHargaRequest, userID int) (*models.JadwalHargaBarang, error) {
+	if req.HargaBeli < 0 || req.HargaJual < 0 {
+		return nil, fmt.Errorf("harga cannot be negative")
+	}
+
+	berlakuMulai, err := parseBerlakuMulai(req.BerlakuMulai)
+	if err != nil {
+		return nil, err
+	}
+	if !berlakuMulai.After(time.Now()) {
+		return nil, fmt.Errorf("berlaku_mulai must be in the future")
+	}
+
+	if _, err := s.barangRepo.FindByID(barangID); err != nil {
+		return nil, err
+	}
+
+	jadwal := &models.JadwalHargaBarang{
+		BarangID:     barangID,
+		HargaBeli:    req.HargaBeli,
+		HargaJual:    req.HargaJual,
+		BerlakuMulai: berlakuMulai,
+		CreatedBy:    &userID,
+	}
+
+	if err := s.hargaBarangRepo.CreateJadwal(jadwal); err != nil {
+		return nil, err
+	}
+
+	return jadwal, nil
+}
+
+func (s *barangService) GetPriceSchedules(barangID int) ([]models.JadwalHargaBarang, error) {
+	if _, err := s.barangRepo.FindByID(barangID); err != nil {
+		return nil, err
+	}
+	return s.hargaBarangRepo.FindJadwalByBarangID(barangID)
+}
+
+func (s *barangService) CancelPriceSchedule(barangID, jadwalID int) error {
+	return s.hargaBarangRepo.CancelJadwal(barangID, jadwalID)
+}
+
+// ApplyDuePriceChanges applies every pending schedule whose berlaku_mulai has
+// passed, in effective-date order, and returns how many were applied
+func (s *barangService) ApplyDuePriceChanges() (int, error) {
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	jadwals, err := s.hargaBarangRepo.LockDueJadwal(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, jadwal := range jadwals {
+		barang, err := s.barangRepo.FindByIDForUpdate(tx, jadwal.BarangID)
+		if err != nil {
+			return 0, err
+		}
+
+		history := &models.HistoryHargaBarang{
+			BarangID:      barang.ID,
+			HargaBeliLama: barang.HargaBeli,
+			HargaBeliBaru: jadwal.HargaBeli,
+			HargaJualLama: barang.HargaJual,
+			HargaJualBaru: jadwal.HargaJual,
+			Keterangan:    fmt.Sprintf("Jadwal harga #%d", jadwal.ID),
+			JadwalID:      &jadwal.ID,
+			ChangedBy:     jadwal.CreatedBy,
+		}
+
+		before := *barang
+		barang.HargaBeli = jadwal.HargaBeli
+		barang.HargaJual = jadwal.HargaJual
+		if err := s.barangRepo.Update(tx, barang); err != nil {
+			return 0, err
+		}
+
+		entry, err := newAuditEntry(jadwal.CreatedBy, "apply_price_schedule", "barang", barang.ID, barang.KodeBarang, before, barang)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.auditRepo.CreateTx(tx, entry); err != nil {
+			return 0, err
+		}
+
+		if err := s.hargaBarangRepo.InsertHistory(tx, history); err != nil {
+			return 0, err
+		}
+
+		if err := s.hargaBarangRepo.MarkJadwalApplied(tx, jadwal.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(jadwals), nil
+}
+
+// parseBerlakuMulai accepts RFC3339, datetime-local (YYYY-MM-DDTHH:MM) or a plain date
+func parseBerlakuMulai(value string) (time.Time, error) {
+	layouts := []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid berlaku_mulai format")
+}
+
+// findDetailBarang resolves a transaction line to its barang, by barang_id or,
+// when that is 0, by a scanned barcode. Archived barang cannot be transacted
+func findDetailBarang(barangRepo repositories.BarangRepository, barangID int, barcode string) (*models.Barang, error) {
+	var barang *models.Barang
+	var err error
+	if barangID == 0 && barcode != "" {
+		barang, err = barangRepo.FindByBarcode(barcode)
+		if err != nil {
+			return nil, fmt.Errorf("barang with barcode %s not found", barcode)
+		}
+	} else {
+		barang, err = barangRepo.FindByID(barangID)
+		if err != nil {
+			return nil, fmt.Errorf("barang with id %d not found", barangID)
+		}
+	}
+
+	if barang.ArchivedAt != nil {
+		return nil, &BarangArchivedError{BarangID: barang.ID, KodeBarang: barang.KodeBarang}
+	}
+	return barang, nil
+}
+
+// BarangArchivedError rejects a purchase, sale or production that would use
+// an archived barang, directly or as a kit or BOM komponen
+type BarangArchivedError struct {
+	BarangID   int
+	KodeBarang string
+}
+
+func (e *BarangArchivedError) Error() string {
+	return fmt.Sprintf("barang %s is archived and cannot be used in new transactions", e.KodeBarang)
+}
@@ -0,0 +1,560 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+type DokumenService interface {
+	RenderFakturPenjualan(jualHeaderID int) ([]byte, string, error)
+	RenderPenerimaanBarang(beliHeaderID, userID int) ([]byte, string, error)
+	RenderSuratJalan(jualHeaderID, userID int) ([]byte, string, error)
+}
+
+type dokumenService struct {
+	db            *sql.DB
+	pembelianRepo repositories.PembelianRepository
+	penjualanRepo repositories.PenjualanRepository
+	supplierRepo  repositories.SupplierRepository
+	customerRepo  repositories.CustomerRepository
+	templateRepo  repositories.TemplateDokumenRepository
+	cetakRepo     repositories.CetakDokumenRepository
+}
+
+func NewDokumenService(db *sql.DB, pembelianRepo repositories.PembelianRepository,
+	penjualanRepo repositories.PenjualanRepository, supplierRepo repositories.SupplierRepository,
+	customerRepo repositories.CustomerRepository, templateRepo repositories.TemplateDokumenRepository,
+	cetakRepo repositories.CetakDokumenRepository) DokumenService {
+	return &dokumenService{
+		db:            db,
+		pembelianRepo: pembelianRepo,
+		penjualanRepo: penjualanRepo,
+		supplierRepo:  supplierRepo,
+		customerRepo:  customerRepo,
+		templateRepo:  templateRepo,
+		cetakRepo:     cetakRepo,
+	}
+}
+
+// RenderFakturPenjualan renders the sales invoice of a penjualan and returns
+// the PDF document with its file name
+func (s *dokumenService) RenderFakturPenjualan(jualHeaderID int) ([]byte, string, error) {
+	penjualan, err := s.penjualanRepo.FindByID(jualHeaderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tpl, err := s.templateRepo.FindByJenis("faktur_penjualan")
+	if err != nil {
+		return nil, "", err
+	}
+
+	customer := s.findCustomer(penjualan)
+
+	doc := newDokumenPDF(tpl, false)
+	doc.header([][2]string{
+		{"No. Faktur", penjualan.NoFaktur},
+		{"Tanggal", formatTanggal(penjualan.Tanggal)},
+	})
+	doc.party("Kepada Yth.", customer.NamaCustomer, customer.Alamat,
+		labelled("Telp. ", customer.Telepon), labelled("NPWP ", customer.NPWP))
+
+	table := doc.table(
+		[]string{"No", "Kode", "Nama Barang", "Qty", "Satuan", "Harga", "Subtotal"},
+		[]float64{0.06, 0.12, 0.32, 0.08, 0.10, 0.15, 0.17},
+		[]string{"C", "L", "L", "R", "C", "R", "R"},
+	)
+	for i, d := range penjualan.Details {
+		table.row([]string{
+			fmt.Sprintf("%d", i+1), d.KodeBarang, d.NamaBarang, formatAngka(d.Qty), d.Satuan,
+			formatRupiah(d.Harga), formatRupiah(d.Subtotal),
+		})
+	}
+
+	doc.totals([][2]string{
+		{"Total", formatRupiah(penjualan.Total)},
+		{"Terbayar", formatRupiah(penjualan.Terbayar)},
+		{"Sisa Tagihan", formatRupiah(penjualan.Total - penjualan.Terbayar)},
+	})
+
+	if tpl.TampilkanTerbilang {
+		doc.paragraph("I", "Terbilang: # "+capitalize(terbilang(penjualan.Total))+" #")
+	}
+	if penjualan.Keterangan != "" {
+		doc.paragraph("", "Keterangan: "+penjualan.Keterangan)
+	}
+	if tpl.Catatan != "" {
+		doc.paragraph("", tpl.Catatan)
+	}
+	doc.signatures(tpl.LabelTtdKiri, "", tpl.LabelTtdKanan, tpl.NamaTtdKanan)
+
+	data, err := doc.output()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, fileNameDokumen("faktur", penjualan.NoFaktur), nil
+}
+
+// RenderPenerimaanBarang renders the goods receipt of a pembelian. Every print
+// is counted; reprints carry a COPY watermark.
+func (s *dokumenService) RenderPenerimaanBarang(beliHeaderID, userID int) ([]byte, string, error) {
+	pembelian, err := s.pembelianRepo.FindByID(beliHeaderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tpl, err := s.templateRepo.FindByJenis("penerimaan_barang")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The header keeps a name snapshot; the rest of the address comes from the master
+	supplier := &models.Supplier{NamaSupplier: pembelian.Supplier}
+	if pembelian.SupplierID != 0 {
+		if sp, err := s.supplierRepo.FindByID(pembelian.SupplierID); err == nil {
+			supplier = sp
+			supplier.NamaSupplier = pembelian.Supplier
+		}
+	}
+
+	return s.renderCounted("penerimaan_barang", pembelian.ID, userID, func(cetakanKe int) ([]byte, error) {
+		doc := newDokumenPDF(tpl, cetakanKe > 1)
+		doc.header([][2]string{
+			{"No. Faktur", pembelian.NoFaktur},
+			{"Tanggal", formatTanggal(pembelian.Tanggal)},
+			{"Cetakan ke", fmt.Sprintf("%d", cetakanKe)},
+		})
+		doc.party("Diterima dari", supplier.NamaSupplier, supplier.Alamat, labelled("Telp. ", supplier.Telepon))
+
+		table := doc.table(
+			[]string{"No", "Kode", "Nama Barang", "Qty", "Satuan", "Harga", "Subtotal"},
+			[]float64{0.06, 0.12, 0.32, 0.08, 0.10, 0.15, 0.17},
+			[]string{"C", "L", "L", "R", "C", "R", "R"},
+		)
+		var totalQty int
+		for i, d := range pembelian.Details {
+			table.row([]string{
+				fmt.Sprintf("%d", i+1), d.KodeBarang, d.NamaBarang, formatAngka(d.Qty), d.Satuan,
+				formatRupiah(d.Harga), formatRupiah(d.Subtotal),
+			})
+			totalQty += d.Qty
+		}
+
+		doc.totals([][2]string{
+			{"Total", formatRupiah(pembelian.Total)},
+			{"Jumlah Qty", formatAngka(totalQty)},
+		})
+
+		if tpl.TampilkanTerbilang {
+			doc.paragraph("I", "Terbilang: # "+capitalize(terbilang(pembelian.Total))+" #")
+		}
+		if pembelian.Keterangan != "" {
+			doc.paragraph("", "Keterangan: "+pembelian.Keterangan)
+		}
+		if tpl.Catatan != "" {
+			doc.paragraph("", tpl.Catatan)
+		}
+		doc.signatures(tpl.LabelTtdKiri, "", tpl.LabelTtdKanan, tpl.NamaTtdKanan)
+
+		return doc.output()
+	}, "penerimaan", pembelian.NoFaktur)
+}
+
+// RenderSuratJalan renders the delivery note of a penjualan, without prices.
+// Every print is counted; reprints carry a COPY watermark.
+func (s *dokumenService) RenderSuratJalan(jualHeaderID, userID int) ([]byte, string, error) {
+	penjualan, err := s.penjualanRepo.FindByID(jualHeaderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tpl, err := s.templateRepo.FindByJenis("surat_jalan")
+	if err != nil {
+		return nil, "", err
+	}
+
+	customer := s.findCustomer(penjualan)
+
+	return s.renderCounted("surat_jalan", penjualan.ID, userID, func(cetakanKe int) ([]byte, error) {
+		doc := newDokumenPDF(tpl, cetakanKe > 1)
+		doc.header([][2]string{
+			{"No. Faktur", penjualan.NoFaktur},
+			{"Tanggal", formatTanggal(penjualan.Tanggal)},
+			{"Cetakan ke", fmt.Sprintf("%d", cetakanKe)},
+		})
+		doc.party("Kirim kepada", customer.NamaCustomer, customer.Alamat, labelled("Telp. ", customer.Telepon))
+
+		table := doc.table(
+			[]string{"No", "Kode", "Nama Barang", "Qty", "Satuan", "Keterangan"},
+			[]float64{0.06, 0.14, 0.38, 0.10, 0.12, 0.20},
+			[]string{"C", "L", "L", "R", "C", "L"},
+		)
+		var totalQty int
+		for i, d := range penjualan.Details {
+			table.row([]string{
+				fmt.Sprintf("%d", i+1), d.KodeBarang, d.NamaBarang, formatAngka(d.Qty), d.Satuan, "",
+			})
+			totalQty += d.Qty
+		}
+
+		doc.totals([][2]string{
+			{"Jumlah Qty", formatAngka(totalQty)},
+		})
+
+		if penjualan.Keterangan != "" {
+			doc.paragraph("", "Keterangan: "+penjualan.Keterangan)
+		}
+		if tpl.Catatan != "" {
+			doc.paragraph("", tpl.Catatan)
+		}
+		doc.signatures(tpl.LabelTtdKiri, "", tpl.LabelTtdKanan, tpl.NamaTtdKanan)
+
+		return doc.output()
+	}, "surat-jalan", penjualan.NoFaktur)
+}
+
+// renderCounted records a print of a document and renders it with its print
+// number; the counter is only kept when rendering succeeds
+func (s *dokumenService) renderCounted(jenis string, referensiID, userID int,
+	render func(cetakanKe int) ([]byte, error), prefix, nomor string) ([]byte, string, error) {
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	cetakanKe, err := s.cetakRepo.RecordCetak(tx, jenis, referensiID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := render(cetakanKe)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, "", err
+	}
+
+	filename := fileNameDokumen(prefix, nomor)
+	if cetakanKe > 1 {
+		filename = fileNameDokumen(prefix, fmt.Sprintf("%s-copy-%d", nomor, cetakanKe))
+	}
+
+	return data, filename, nil
+}
+
+// findCustomer returns the customer of a penjualan with the name snapshot taken
+// at the time of sale; the address comes from the master
+func (s *dokumenService) findCustomer(penjualan *models.JualHeaderWithDetail) *models.Customer {
+	customer := &models.Customer{NamaCustomer: penjualan.Customer}
+	if penjualan.CustomerID != 0 {
+		if c, err := s.customerRepo.FindByID(penjualan.CustomerID); err == nil {
+			customer = c
+			customer.NamaCustomer = penjualan.Customer
+		}
+	}
+	return customer
+}
+
+// dokumenPDF draws the common parts of printed documents from a template
+type dokumenPDF struct {
+	pdf    *gofpdf.Fpdf
+	tr     func(string) string
+	tpl    *models.TemplateDokumen
+	margin float64
+	width  float64
+}
+
+// newDokumenPDF starts a document; isCopy marks every page with a COPY watermark
+func newDokumenPDF(tpl *models.TemplateDokumen, isCopy bool) *dokumenPDF {
+	size := tpl.UkuranKertas
+	if size == "" {
+		size = "A4"
+	}
+
+	pdf := gofpdf.New("P", "mm", size, "")
+	margin := 15.0
+	if size == "A5" {
+		margin = 10
+	}
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin+5)
+	pdf.AliasNbPages("{nb}")
+
+	doc := &dokumenPDF{
+		pdf:    pdf,
+		tr:     pdf.UnicodeTranslatorFromDescriptor(""),
+		tpl:    tpl,
+		margin: margin,
+	}
+	pageW, _ := pdf.GetPageSize()
+	doc.width = pageW - 2*margin
+
+	if isCopy {
+		pdf.SetHeaderFunc(func() {
+			doc.watermark("COPY")
+		})
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin)
+		pdf.SetFont("Helvetica", "I", 7)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 4, doc.tr(fmt.Sprintf("%s - Halaman %d dari {nb}", tpl.Judul, pdf.PageNo())),
+			"", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+
+	return doc
+}
+
+// watermark draws large light text diagonally across the page, behind the content
+func (d *dokumenPDF) watermark(text string) {
+	pdf := d.pdf
+	pageW, pageH := pdf.GetPageSize()
+	x, y := pdf.GetXY()
+
+	pdf.SetFont("Helvetica", "B", 110)
+	pdf.SetTextColor(225, 225, 225)
+	textW := pdf.GetStringWidth(text)
+
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, pageW/2, pageH/2)
+	pdf.Text(pageW/2-textW/2, pageH/2+15, text)
+	pdf.TransformEnd()
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetXY(x, y)
+}
+
+// header draws the company block on the left and the document title with its
+// identifying fields on the right, followed by a rule
+func (d *dokumenPDF) header(fields [][2]string) {
+	pdf := d.pdf
+	top := pdf.GetY()
+	leftW := d.width * 0.55
+
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.MultiCell(leftW, 6, d.tr(d.tpl.NamaPerusahaan), "", "L", false)
+	pdf.SetFont("Helvetica", "", 8)
+	for _, line := range []string{d.tpl.Alamat, joinNonEmpty(" | ", labelled("Telp. ", d.tpl.Telepon), d.tpl.Email),
+		labelled("NPWP ", d.tpl.NPWP)} {
+		if line != "" {
+			pdf.MultiCell(leftW, 4, d.tr(line), "", "L", false)
+		}
+	}
+	leftBottom := pdf.GetY()
+
+	rightX := d.margin + leftW
+	rightW := d.width - leftW
+	pdf.SetXY(rightX, top)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(rightW, 7, d.tr(d.tpl.Judul), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, f := range fields {
+		pdf.SetX(rightX)
+		pdf.CellFormat(rightW, 5, d.tr(f[0]+": "+f[1]), "", 2, "R", false, 0, "")
+	}
+
+	y := leftBottom
+	if pdf.GetY() > y {
+		y = pdf.GetY()
+	}
+	y += 2
+	pdf.SetLineWidth(0.4)
+	pdf.Line(d.margin, y, d.margin+d.width, y)
+	pdf.SetLineWidth(0.2)
+	pdf.SetXY(d.margin, y+3)
+}
+
+// party draws the addressee (customer or supplier) block
+func (d *dokumenPDF) party(title, name string, lines ...string) {
+	pdf := d.pdf
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(d.width, 5, d.tr(title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(d.width, 5, d.tr(name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range lines {
+		if line != "" {
+			pdf.MultiCell(d.width*0.6, 4.5, d.tr(line), "", "L", false)
+		}
+	}
+	pdf.Ln(3)
+}
+
+// dokumenTable is a line item table whose header repeats on every page
+type dokumenTable struct {
+	doc    *dokumenPDF
+	titles []string
+	widths []float64
+	aligns []string
+}
+
+func (d *dokumenPDF) table(titles []string, fractions []float64, aligns []string) *dokumenTable {
+	widths := make([]float64, len(fractions))
+	for i, f := range fractions {
+		widths[i] = f * d.width
+	}
+	t := &dokumenTable{doc: d, titles: titles, widths: widths, aligns: aligns}
+	t.drawHeader()
+	return t
+}
+
+func (t *dokumenTable) drawHeader() {
+	pdf := t.doc.pdf
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(230, 230, 230)
+	for i, title := range t.titles {
+		pdf.CellFormat(t.widths[i], 7, t.doc.tr(title), "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+func (t *dokumenTable) row(cells []string) {
+	pdf := t.doc.pdf
+	pdf.SetFont("Helvetica", "", 9)
+
+	// Wrap long cells and give the whole row the height of its tallest cell
+	lineH := 5.0
+	lines := make([][]string, len(cells))
+	maxLines := 1
+	for i, cell := range cells {
+		for _, l := range pdf.SplitLines([]byte(t.doc.tr(cell)), t.widths[i]-2) {
+			lines[i] = append(lines[i], string(l))
+		}
+		if len(lines[i]) > maxLines {
+			maxLines = len(lines[i])
+		}
+	}
+	rowH := float64(maxLines) * lineH
+
+	_, pageH := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+rowH > pageH-bottom-5 {
+		pdf.AddPage()
+		t.drawHeader()
+		pdf.SetFont("Helvetica", "", 9)
+	}
+
+	x, y := pdf.GetXY()
+	for i := range cells {
+		pdf.Rect(x, y, t.widths[i], rowH, "D")
+		for j, l := range lines[i] {
+			pdf.SetXY(x, y+float64(j)*lineH)
+			pdf.CellFormat(t.widths[i], lineH, l, "", 0, t.aligns[i], false, 0, "")
+		}
+		x += t.widths[i]
+	}
+	pdf.SetXY(t.doc.margin, y+rowH)
+}
+
+// totals draws label/value pairs right-aligned under the table
+func (d *dokumenPDF) totals(rows [][2]string) {
+	pdf := d.pdf
+	labelW := d.width * 0.2
+	valueW := d.width * 0.2
+	x := d.margin + d.width - labelW - valueW
+	pdf.Ln(2)
+	for i, r := range rows {
+		style := ""
+		if i == 0 {
+			style = "B"
+		}
+		pdf.SetX(x)
+		pdf.SetFont("Helvetica", style, 9)
+		pdf.CellFormat(labelW, 6, d.tr(r[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(valueW, 6, d.tr(r[1]), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(2)
+}
+
+func (d *dokumenPDF) paragraph(style, text string) {
+	d.pdf.SetFont("Helvetica", style, 9)
+	d.pdf.MultiCell(d.width, 4.5, d.tr(text), "", "L", false)
+	d.pdf.Ln(2)
+}
+
+// signatures draws two signature boxes; an empty name leaves a blank line to sign on
+func (d *dokumenPDF) signatures(labelKiri, namaKiri, labelKanan, namaKanan string) {
+	pdf := d.pdf
+	const blockH = 32.0
+
+	_, pageH := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+blockH > pageH-bottom-5 {
+		pdf.AddPage()
+	}
+
+	pdf.Ln(4)
+	colW := d.width / 3
+	y := pdf.GetY()
+	for i, s := range [][2]string{{labelKiri, namaKiri}, {labelKanan, namaKanan}} {
+		if s[0] == "" && s[1] == "" {
+			continue
+		}
+		x := d.margin + float64(i)*2*colW
+		pdf.SetXY(x, y)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(colW, 5, d.tr(s[0]), "", 0, "C", false, 0, "")
+
+		name := s[1]
+		if name == "" {
+			name = "(                                        )"
+		} else {
+			name = "( " + name + " )"
+		}
+		pdf.SetXY(x, y+22)
+		pdf.CellFormat(colW, 5, d.tr(name), "", 0, "C", false, 0, "")
+	}
+	pdf.SetXY(d.margin, y+blockH)
+}
+
+func (d *dokumenPDF) output() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := d.pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fileNameDokumen builds a download name such as "faktur-JL-20251205-001.pdf"
+func fileNameDokumen(prefix, nomor string) string {
+	replacer := strings.NewReplacer("/", "-", "\\", "-", " ", "_", "\"", "")
+	return fmt.Sprintf("%s-%s.pdf", prefix, replacer.Replace(nomor))
+}
+
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + value
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
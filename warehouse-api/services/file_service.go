@@ -0,0 +1,436 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+	"warehouse-api/storage"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxGambarSize bounds an uploaded barang image
+	MaxGambarSize = 5 << 20
+	// MaxLampiranSize bounds an uploaded pembelian/penjualan attachment
+	MaxLampiranSize = 10 << 20
+
+	// thumbnailSize is the longest side of a generated thumbnail in pixels
+	thumbnailSize = 320
+	// maxImagePixels bounds the decoded size of an image. A small compressed
+	// file can declare huge dimensions, and decoding allocates 4 bytes per pixel
+	maxImagePixels = 40_000_000
+)
+
+// File types accepted for upload, keyed by the sniffed content type. The type
+// sent by the client is ignored
+var (
+	gambarTypes = map[string]string{
+		"image/jpeg": ".jpg",
+		"image/png":  ".png",
+		"image/gif":  ".gif",
+		"image/webp": ".webp",
+	}
+	lampiranTypes = map[string]string{
+		"application/pdf": ".pdf",
+		"image/jpeg":      ".jpg",
+		"image/png":       ".png",
+		"image/gif":       ".gif",
+		"image/webp":      ".webp",
+	}
+)
+
+type FileService interface {
+	ListGambar(barangID int) ([]models.BarangGambar, error)
+	UploadGambar(barangID int, filename string, file io.Reader, userID int) (*models.BarangGambar, error)
+	OpenGambar(barangID, id int, thumbnail bool) (io.ReadCloser, *models.BarangGambar, error)
+	DeleteGambar(barangID, id int) error
+	DeleteGambarFiles(gambars []models.BarangGambar)
+	ListLampiran(tipe string, referensiID int) ([]models.Lampiran, error)
+	UploadLampiran(tipe string, referensiID int, filename, keterangan string, file io.Reader, userID int) (*models.Lampiran, error)
+	OpenLampiran(tipe string, referensiID, id int, thumbnail bool) (io.ReadCloser, *models.Lampiran, error)
+	DeleteLampiran(tipe string, referensiID, id int) error
+}
+
+type fileService struct {
+	storage       storage.Storage
+	gambarRepo    repositories.BarangGambarRepository
+	lampiranRepo  repositories.LampiranRepository
+	barangRepo    repositories.BarangRepository
+	pembelianRepo repositories.PembelianRepository
+	penjualanRepo repositories.PenjualanRepository
+}
+
+func NewFileService(store storage.Storage, gambarRepo repositories.BarangGambarRepository,
+	lampiranRepo repositories.LampiranRepository, barangRepo repositories.BarangRepository,
+	pembelianRepo repositories.PembelianRepository, penjualanRepo repositories.PenjualanRepository) FileService {
+	return &fileService{
+		storage:       store,
+		gambarRepo:    gambarRepo,
+		lampiranRepo:  lampiranRepo,
+		barangRepo:    barangRepo,
+		pembelianRepo: pembelianRepo,
+		penjualanRepo: penjualanRepo,
+	}
+}
+
+func (s *fileService) ListGambar(barangID int) ([]models.BarangGambar, error) {
+	if _, err := s.barangRepo.FindByID(barangID); err != nil {
+		return nil, err
+	}
+
+	gambars, err := s.gambarRepo.FindByBarangID(barangID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range gambars {
+		setGambarURL(&gambars[i])
+	}
+
+	return gambars, nil
+}
+
+// UploadGambar stores an image of a barang together with its thumbnail
+func (s *fileService) UploadGambar(barangID int, filename string, file io.Reader, userID int) (*models.BarangGambar, error) {
+	if _, err := s.barangRepo.FindByID(barangID); err != nil {
+		return nil, err
+	}
+
+	data, contentType, err := readUpload(file, MaxGambarSize, gambarTypes)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := makeThumbnail(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := randomKey()
+	if err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("barang/%d/%s", barangID, key)
+	gambar := &models.BarangGambar{
+		BarangID:     barangID,
+		NamaFile:     uploadName(filename),
+		ContentType:  contentType,
+		Ukuran:       int64(len(data)),
+		FileKey:      base + gambarTypes[contentType],
+		ThumbnailKey: base + "_thumb.jpg",
+		UploadedBy:   &userID,
+	}
+
+	if err := s.saveFiles(gambar.FileKey, data, gambar.ThumbnailKey, thumbnail); err != nil {
+		return nil, err
+	}
+	if err := s.gambarRepo.Create(gambar); err != nil {
+		s.deleteFiles(gambar.FileKey, gambar.ThumbnailKey)
+		return nil, err
+	}
+	setGambarURL(gambar)
+
+	return gambar, nil
+}
+
+func (s *fileService) OpenGambar(barangID, id int, thumbnail bool) (io.ReadCloser, *models.BarangGambar, error) {
+	gambar, err := s.gambarRepo.FindByID(barangID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := gambar.FileKey
+	if thumbnail {
+		key = gambar.ThumbnailKey
+	}
+	file, err := s.storage.Open(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, gambar, nil
+}
+
+func (s *fileService) DeleteGambar(barangID, id int) error {
+	gambar, err := s.gambarRepo.FindByID(barangID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.gambarRepo.Delete(barangID, id); err != nil {
+		return err
+	}
+
+	s.deleteFiles(gambar.FileKey, gambar.ThumbnailKey)
+	return nil
+}
+
+// DeleteGambarFiles removes the stored files of images whose rows are already
+// gone, such as those of a deleted barang
+func (s *fileService) DeleteGambarFiles(gambars []models.BarangGambar) {
+	for _, gambar := range gambars {
+		s.deleteFiles(gambar.FileKey, gambar.ThumbnailKey)
+	}
+}
+
+func (s *fileService) ListLampiran(tipe string, referensiID int) ([]models.Lampiran, error) {
+	if err := s.checkReferensi(tipe, referensiID); err != nil {
+		return nil, err
+	}
+
+	lampirans, err := s.lampiranRepo.FindByReferensi(tipe, referensiID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lampirans {
+		setLampiranURL(&lampirans[i])
+	}
+
+	return lampirans, nil
+}
+
+// UploadLampiran attaches a PDF or image to a pembelian or penjualan; images
+// also get a thumbnail
+func (s *fileService) UploadLampiran(tipe string, referensiID int, filename, keterangan string, file io.Reader, userID int) (*models.Lampiran, error) {
+	if err := s.checkReferensi(tipe, referensiID); err != nil {
+		return nil, err
+	}
+
+	data, contentType, err := readUpload(file, MaxLampiranSize, lampiranTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := randomKey()
+	if err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("%s/%d/%s", tipe, referensiID, key)
+	lampiran := &models.Lampiran{
+		ReferensiTipe: tipe,
+		ReferensiID:   referensiID,
+		NamaFile:      uploadName(filename),
+		ContentType:   contentType,
+		Ukuran:        int64(len(data)),
+		FileKey:       base + lampiranTypes[contentType],
+		UploadedBy:    &userID,
+	}
+	if keterangan = strings.TrimSpace(keterangan); keterangan != "" {
+		lampiran.Keterangan = &keterangan
+	}
+
+	var thumbnail []byte
+	thumbnailKey := ""
+	if strings.HasPrefix(contentType, "image/") {
+		if thumbnail, err = makeThumbnail(data); err != nil {
+			return nil, err
+		}
+		thumbnailKey = base + "_thumb.jpg"
+		lampiran.ThumbnailKey = &thumbnailKey
+	}
+
+	if err := s.saveFiles(lampiran.FileKey, data, thumbnailKey, thumbnail); err != nil {
+		return nil, err
+	}
+	if err := s.lampiranRepo.Create(lampiran); err != nil {
+		s.deleteFiles(lampiran.FileKey, thumbnailKey)
+		return nil, err
+	}
+	setLampiranURL(lampiran)
+
+	return lampiran, nil
+}
+
+func (s *fileService) OpenLampiran(tipe string, referensiID, id int, thumbnail bool) (io.ReadCloser, *models.Lampiran, error) {
+	lampiran, err := s.lampiranRepo.FindByID(tipe, referensiID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := lampiran.FileKey
+	if thumbnail {
+		if lampiran.ThumbnailKey == nil {
+			return nil, nil, fmt.Errorf("thumbnail not found")
+		}
+		key = *lampiran.ThumbnailKey
+	}
+	file, err := s.storage.Open(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, lampiran, nil
+}
+
+func (s *fileService) DeleteLampiran(tipe string, referensiID, id int) error {
+	lampiran, err := s.lampiranRepo.FindByID(tipe, referensiID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.lampiranRepo.Delete(tipe, referensiID, id); err != nil {
+		return err
+	}
+
+	if lampiran.ThumbnailKey != nil {
+		s.deleteFiles(lampiran.FileKey, *lampiran.ThumbnailKey)
+	} else {
+		s.deleteFiles(lampiran.FileKey)
+	}
+	return nil
+}
+
+// checkReferensi makes sure the pembelian or penjualan exists
+func (s *fileService) checkReferensi(tipe string, referensiID int) error {
+	var err error
+	switch tipe {
+	case "pembelian":
+		_, err = s.pembelianRepo.FindByID(referensiID)
+	case "penjualan":
+		_, err = s.penjualanRepo.FindByID(referensiID)
+	default:
+		err = fmt.Errorf("invalid lampiran tipe %q", tipe)
+	}
+	return err
+}
+
+// saveFiles stores a file and, when thumbKey is set, its thumbnail. Nothing is
+// left behind when either fails
+func (s *fileService) saveFiles(key string, data []byte, thumbKey string, thumbnail []byte) error {
+	if err := s.storage.Save(key, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	if thumbKey == "" {
+		return nil
+	}
+	if err := s.storage.Save(thumbKey, bytes.NewReader(thumbnail)); err != nil {
+		s.deleteFiles(key)
+		return err
+	}
+	return nil
+}
+
+// deleteFiles removes stored files on a best-effort basis; a failure only
+// leaves an orphaned file behind, so it is logged rather than returned
+func (s *fileService) deleteFiles(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(key); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
+
+// readUpload reads the whole upload, enforcing maxSize, and sniffs its content
+// type from the first bytes
+func readUpload(file io.Reader, maxSize int64, allowed map[string]string) ([]byte, string, error) {
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", &FileTooLargeError{MaxSize: maxSize}
+	}
+	if len(data) == 0 {
+		return nil, "", &UnsupportedFileError{Reason: "file is empty"}
+	}
+
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if _, ok := allowed[contentType]; !ok {
+		return nil, "", &UnsupportedFileError{Reason: fmt.Sprintf("file type %s is not allowed", contentType)}
+	}
+
+	return data, contentType, nil
+}
+
+// makeThumbnail scales an image down to fit thumbnailSize and encodes it as
+// JPEG on a white background
+func makeThumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &UnsupportedFileError{Reason: "image cannot be read"}
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, &UnsupportedFileError{Reason: fmt.Sprintf("image is larger than %d megapixels", maxImagePixels/1_000_000)}
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, &UnsupportedFileError{Reason: "image cannot be read"}
+	}
+
+	thumb := imaging.Fit(img, thumbnailSize, thumbnailSize, imaging.Lanczos)
+	bounds := thumb.Bounds()
+	background := imaging.New(bounds.Dx(), bounds.Dy(), color.White)
+	thumb = imaging.Overlay(background, thumb, image.Pt(0, 0), 1)
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, thumb, imaging.JPEG, imaging.JPEGQuality(80)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// uploadName keeps only the base name of the client's filename
+func uploadName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if name == "." || name == "/" {
+		name = "file"
+	}
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	return name
+}
+
+func randomKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func setGambarURL(g *models.BarangGambar) {
+	g.URL = fmt.Sprintf("/api/barang/%d/gambar/%d", g.BarangID, g.ID)
+	g.ThumbnailURL = g.URL + "?thumbnail=true"
+}
+
+func setLampiranURL(l *models.Lampiran) {
+	l.URL = fmt.Sprintf("/api/%s/%d/lampiran/%d", l.ReferensiTipe, l.ReferensiID, l.ID)
+	if l.ThumbnailKey != nil {
+		thumbnailURL := l.URL + "?thumbnail=true"
+		l.ThumbnailURL = &thumbnailURL
+	}
+}
+
+// FileTooLargeError rejects an upload above the size limit
+type FileTooLargeError struct {
+	MaxSize int64
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("file is larger than %d MB", e.MaxSize>>20)
+}
+
+// UnsupportedFileError rejects an upload whose content is not an allowed type
+type UnsupportedFileError struct {
+	Reason string
+}
+
+func (e *UnsupportedFileError) Error() string {
+	return e.Reason
+}
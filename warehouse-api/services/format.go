@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// formatRupiah formats an amount the Indonesian way, e.g. "Rp 1.250.000" or "Rp 12.500,50"
+func formatRupiah(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	cents := int64(math.Round(amount * 100))
+	whole := fmt.Sprintf("%d", cents/100)
+
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+
+	if frac := cents % 100; frac != 0 {
+		return fmt.Sprintf("%sRp %s,%02d", sign, b.String(), frac)
+	}
+	return fmt.Sprintf("%sRp %s", sign, b.String())
+}
+
+var satuanTerbilang = []string{"", "satu", "dua", "tiga", "empat", "lima", "enam", "tujuh",
+	"delapan", "sembilan", "sepuluh", "sebelas"}
+
+// terbilang spells out a rupiah amount in Indonesian words, e.g.
+// 1250000 -> "satu juta dua ratus lima puluh ribu rupiah"
+func terbilang(amount float64) string {
+	if amount < 0 {
+		return "minus " + terbilang(-amount)
+	}
+
+	cents := int64(math.Round(amount * 100))
+	whole, sen := cents/100, cents%100
+
+	words := "nol"
+	if whole > 0 {
+		words = terbilangAngka(whole)
+	}
+	words += " rupiah"
+
+	if sen > 0 {
+		words += " " + terbilangAngka(sen) + " sen"
+	}
+
+	return words
+}
+
+func terbilangAngka(n int64) string {
+	switch {
+	case n < 12:
+		return satuanTerbilang[n]
+	case n < 20:
+		return satuanTerbilang[n-10] + " belas"
+	case n < 100:
+		return joinTerbilang(satuanTerbilang[n/10]+" puluh", n%10)
+	case n < 200:
+		return joinTerbilang("seratus", n-100)
+	case n < 1000:
+		return joinTerbilang(satuanTerbilang[n/100]+" ratus", n%100)
+	case n < 2000:
+		return joinTerbilang("seribu", n-1000)
+	case n < 1000000:
+		return joinTerbilang(terbilangAngka(n/1000)+" ribu", n%1000)
+	case n < 1000000000:
+		return joinTerbilang(terbilangAngka(n/1000000)+" juta", n%1000000)
+	case n < 1000000000000:
+		return joinTerbilang(terbilangAngka(n/1000000000)+" miliar", n%1000000000)
+	default:
+		return joinTerbilang(terbilangAngka(n/1000000000000)+" triliun", n%1000000000000)
+	}
+}
+
+func joinTerbilang(prefix string, rest int64) string {
+	if rest == 0 {
+		return prefix
+	}
+	return prefix + " " + terbilangAngka(rest)
+}
+
+var namaBulan = []string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli",
+	"Agustus", "September", "Oktober", "November", "Desember"}
+
+// formatTanggal turns a YYYY-MM-DD date into e.g. "5 Desember 2025"
+func formatTanggal(tanggal string) string {
+	if len(tanggal) >= 10 {
+		tanggal = tanggal[:10]
+	}
+	t, err := time.Parse("2006-01-02", tanggal)
+	if err != nil {
+		return tanggal
+	}
+	return fmt.Sprintf("%d %s %d", t.Day(), namaBulan[t.Month()-1], t.Year())
+}
+
+// formatAngka formats a quantity with Indonesian thousands separators
+func formatAngka(n int) string {
+	return strings.TrimPrefix(formatRupiah(float64(n)), "Rp ")
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// maxLabels bounds a single label print job
+const maxLabels = 5000
+
+// DefaultLabelLayout is an A4 sheet of 3 x 7 labels of 63.5 x 38.1 mm
+var DefaultLabelLayout = models.LabelLayout{
+	PageWidth:   210,
+	PageHeight:  297,
+	MarginTop:   15.15,
+	MarginLeft:  7.2,
+	LabelWidth:  63.5,
+	LabelHeight: 38.1,
+	GapX:        2.5,
+	GapY:        0,
+	Columns:     3,
+	Rows:        7,
+}
+
+type LabelService interface {
+	RenderLabels(req *models.CreateLabelRequest) ([]byte, error)
+}
+
+type labelService struct {
+	barangRepo    repositories.BarangRepository
+	barcodeRepo   repositories.BarcodeRepository
+	pembelianRepo repositories.PembelianRepository
+}
+
+func NewLabelService(barangRepo repositories.BarangRepository, barcodeRepo repositories.BarcodeRepository,
+	pembelianRepo repositories.PembelianRepository) LabelService {
+	return &labelService{
+		barangRepo:    barangRepo,
+		barcodeRepo:   barcodeRepo,
+		pembelianRepo: pembelianRepo,
+	}
+}
+
+// label is one printed label
+type label struct {
+	barang  *models.Barang
+	content string
+	code    barcode.Barcode
+}
+
+// RenderLabels draws barcode labels for the requested barang, or for every
+// line of a pembelian, onto label sheets and returns the PDF document
+func (s *labelService) RenderLabels(req *models.CreateLabelRequest) ([]byte, error) {
+	symbology := strings.ToLower(req.Symbology)
+	if symbology == "" {
+		symbology = "code128"
+	}
+	if symbology != "code128" && symbology != "ean13" {
+		return nil, &LabelRequestError{Reason: "symbology must be code128 or ean13"}
+	}
+
+	layout := DefaultLabelLayout
+	if req.Layout != nil {
+		layout = *req.Layout
+	}
+	if err := validateLabelLayout(&layout); err != nil {
+		return nil, err
+	}
+
+	items := req.Items
+	if req.PembelianID != 0 {
+		pembelian, err := s.pembelianRepo.FindByID(req.PembelianID)
+		if err != nil {
+			return nil, err
+		}
+		items = nil
+		for _, d := range pembelian.Details {
+			items = append(items, models.LabelItemRequest{BarangID: d.BarangID, Qty: d.Qty})
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, &LabelRequestError{Reason: "items cannot be empty"}
+	}
+
+	// Encode each barang once, then repeat it qty times
+	var labels []label
+	for _, item := range items {
+		if item.Qty < 1 {
+			return nil, &LabelRequestError{Reason: "qty must be at least 1"}
+		}
+		if len(labels)+item.Qty > maxLabels {
+			return nil, &LabelRequestError{Reason: fmt.Sprintf("cannot print more than %d labels at once", maxLabels)}
+		}
+
+		l, err := s.buildLabel(item.BarangID, symbology)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < item.Qty; i++ {
+			labels = append(labels, *l)
+		}
+	}
+
+	return drawLabelSheets(labels, &layout, req.StartPosition)
+}
+
+// buildLabel picks the code to print for a barang: for Code128 its first
+// registered barcode or else kode_barang, for EAN-13 its first 12/13-digit barcode
+func (s *labelService) buildLabel(barangID int, symbology string) (*label, error) {
+	barang, err := s.barangRepo.FindByID(barangID)
+	if err != nil {
+		return nil, &LabelRequestError{Reason: fmt.Sprintf("barang with id %d not found", barangID)}
+	}
+
+	barcodes, err := s.barcodeRepo.FindByBarangID(barangID)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &label{barang: barang}
+	if symbology == "ean13" {
+		for _, b := range barcodes {
+			if (len(b.Barcode) == 12 || len(b.Barcode) == 13) && isDigits(b.Barcode) {
+				l.content = b.Barcode
+				break
+			}
+		}
+		if l.content == "" {
+			return nil, &LabelRequestError{Reason: fmt.Sprintf("barang %s has no EAN-13 barcode", barang.KodeBarang)}
+		}
+		l.code, err = ean.Encode(l.content)
+	} else {
+		l.content = barang.KodeBarang
+		if len(barcodes) > 0 {
+			l.content = barcodes[0].Barcode
+		}
+		l.code, err = code128.Encode(l.content)
+	}
+	if err != nil {
+		return nil, &LabelRequestError{Reason: fmt.Sprintf("cannot encode barcode %s: %v", l.content, err)}
+	}
+
+	// EAN encoding adds a missing check digit
+	l.content = l.code.Content()
+	return l, nil
+}
+
+func validateLabelLayout(layout *models.LabelLayout) error {
+	if layout.Columns < 1 || layout.Rows < 1 {
+		return &LabelRequestError{Reason: "layout columns and rows must be at least 1"}
+	}
+	if layout.PageWidth <= 0 || layout.PageHeight <= 0 || layout.LabelWidth <= 0 || layout.LabelHeight <= 0 {
+		return &LabelRequestError{Reason: "layout page and label sizes must be positive"}
+	}
+	if layout.MarginTop < 0 || layout.MarginLeft < 0 || layout.GapX < 0 || layout.GapY < 0 {
+		return &LabelRequestError{Reason: "layout margins and gaps cannot be negative"}
+	}
+	if layout.LabelWidth < 25 || layout.LabelHeight < 15 {
+		return &LabelRequestError{Reason: "layout labels must be at least 25 x 15 mm"}
+	}
+
+	usedWidth := layout.MarginLeft + float64(layout.Columns)*layout.LabelWidth + float64(layout.Columns-1)*layout.GapX
+	usedHeight := layout.MarginTop + float64(layout.Rows)*layout.LabelHeight + float64(layout.Rows-1)*layout.GapY
+	if usedWidth > layout.PageWidth+0.01 || usedHeight > layout.PageHeight+0.01 {
+		return &LabelRequestError{Reason: "layout labels do not fit on the page"}
+	}
+
+	return nil
+}
+
+func drawLabelSheets(labels []label, layout *models.LabelLayout, startPosition int) ([]byte, error) {
+	perPage := layout.Columns * layout.Rows
+	if startPosition < 1 || startPosition > perPage {
+		startPosition = 1
+	}
+
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "mm",
+		Size:    gofpdf.SizeType{Wd: layout.PageWidth, Ht: layout.PageHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, l := range labels {
+		pos := (startPosition - 1 + i) % perPage
+		if i == 0 || pos == 0 {
+			pdf.AddPage()
+		}
+
+		col := pos % layout.Columns
+		row := pos / layout.Columns
+		x := layout.MarginLeft + float64(col)*(layout.LabelWidth+layout.GapX)
+		y := layout.MarginTop + float64(row)*(layout.LabelHeight+layout.GapY)
+
+		drawLabel(pdf, tr, l, x, y, layout.LabelWidth, layout.LabelHeight)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// drawLabel lays out one label: nama_barang on top, the bars with their
+// human-readable code, and kode_barang with harga_jual at the bottom
+func drawLabel(pdf *gofpdf.Fpdf, tr func(string) string, l label, x, y, w, h float64) {
+	const padding = 2.0
+	innerW := w - 2*padding
+
+	pdf.SetTextColor(0, 0, 0)
+
+	// Nama barang
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetXY(x+padding, y+padding)
+	pdf.CellFormat(innerW, 3.5, fitText(pdf, tr(l.barang.NamaBarang), innerW), "", 0, "L", false, 0, "")
+
+	// Kode barang and harga jual
+	bottom := y + h - padding - 3.5
+	pdf.SetFont("Helvetica", "", 7)
+	pdf.SetXY(x+padding, bottom)
+	pdf.CellFormat(innerW/2, 3.5, fitText(pdf, tr(l.barang.KodeBarang), innerW/2), "", 0, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetXY(x+padding+innerW/2, bottom)
+	pdf.CellFormat(innerW/2, 3.5, tr(formatRupiah(l.barang.HargaJual)), "", 0, "R", false, 0, "")
+
+	// Human-readable code under the bars
+	codeY := bottom - 3
+	pdf.SetFont("Courier", "", 7)
+	pdf.SetXY(x+padding, codeY)
+	pdf.CellFormat(innerW, 3, l.content, "", 0, "C", false, 0, "")
+
+	// Bars, with a quiet zone of 10 modules on each side
+	barsTop := y + padding + 4.5
+	barsHeight := codeY - barsTop - 0.5
+	modules := l.code.Bounds().Dx()
+	moduleW := innerW / float64(modules+20)
+	if moduleW > 0.5 {
+		moduleW = 0.5
+	}
+	barsX := x + (w-float64(modules)*moduleW)/2
+
+	pdf.SetFillColor(0, 0, 0)
+	for m := 0; m < modules; {
+		if !isBar(l.code, m) {
+			m++
+			continue
+		}
+		start := m
+		for m < modules && isBar(l.code, m) {
+			m++
+		}
+		pdf.Rect(barsX+float64(start)*moduleW, barsTop, float64(m-start)*moduleW, barsHeight, "F")
+	}
+}
+
+func isBar(code barcode.Barcode, module int) bool {
+	bounds := code.Bounds()
+	r, _, _, _ := code.At(bounds.Min.X+module, bounds.Min.Y).RGBA()
+	return r < 0x8000
+}
+
+// fitText shortens s, already translated to the single-byte core font
+// encoding, with an ellipsis until it fits width
+func fitText(pdf *gofpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Custom error for a label request that cannot be printed as given
+type LabelRequestError struct {
+	Reason string
+}
+
+func (e *LabelRequestError) Error() string {
+	return e.Reason
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"strings"
+	"time"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+// loginLimit is the policy for one kind of key. The first free failures cost
+// nothing; after that each failure blocks the key for backoff, doubling up to
+// maxBackoff, and from lockoutAfter failures on for the full lockout
+type loginLimit struct {
+	free         int
+	lockoutAfter int
+}
+
+var (
+	// A single account is locked quickly; an IP may be a whole office behind
+	// NAT, so it gets more room
+	usernameLoginLimit = loginLimit{free: 3, lockoutAfter: 10}
+	ipLoginLimit       = loginLimit{free: 10, lockoutAfter: 50}
+)
+
+const (
+	loginBackoff     = time.Second
+	loginMaxBackoff  = 5 * time.Minute
+	loginLockout     = 15 * time.Minute
+	loginResetWindow = time.Hour
+)
+
+// LoginBlock describes the state of a username or IP after a failed login
+type LoginBlock struct {
+	Failures    int        `json:"failures"`
+	LockedUntil *time.Time `json:"locked_until"`
+	Lockout     bool       `json:"lockout"`
+}
+
+// LoginReservation is one login attempt, already counted as a failure
+// against the username and the IP until it is released
+type LoginReservation struct {
+	keys  []string
+	locks map[string]*time.Time
+	block *LoginBlock
+	done  bool
+}
+
+type LoginLimiter interface {
+	// Check returns how long the caller has to wait before trying to log in
+	// as username from ip; zero means go ahead
+	Check(username, ip string) (time.Duration, error)
+	// Reserve starts an attempt to log in as username from ip with a
+	// password or code. It returns how long to wait instead when the username
+	// or IP is blocked. Every reservation ends with RecordFailure or Release
+	Reserve(username, ip string) (*LoginReservation, time.Duration, error)
+	RecordFailure(reservation *LoginReservation) (*LoginBlock, error)
+	Release(reservation *LoginReservation) error
+	RecordSuccess(username string) error
+	GetLocked() ([]models.LoginAttempt, error)
+	Unlock(key string) error
+}
+
+type loginLimiter struct {
+	attemptRepo repositories.LoginAttemptRepository
+}
+
+func NewLoginLimiter(attemptRepo repositories.LoginAttemptRepository) LoginLimiter {
+	return &loginLimiter{attemptRepo: attemptRepo}
+}
+
+// LoginUsernameKey and LoginIPKey are the keys failures are counted under
+func LoginUsernameKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func LoginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (l *loginLimiter) Check(username, ip string) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+
+	for _, key := range []string{LoginUsernameKey(username), LoginIPKey(ip)} {
+		attempt, err := l.attemptRepo.Find(key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt != nil && attempt.LockedUntil != nil {
+			if d := attempt.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+
+	return wait, nil
+}
+
+// Reserve counts the attempt as a failure against the username and the IP
+// up front, blocking them as the policy says. Checking and counting in one
+// step means parallel requests cannot each get a free guess: once the next
+// failure would block a key, the first attempt blocks it and the others wait
+// for its outcome
+func (l *loginLimiter) Reserve(username, ip string) (*LoginReservation, time.Duration, error) {
+	now := time.Now()
+	reservation := &LoginReservation{locks: make(map[string]*time.Time)}
+
+	for _, k := range []struct {
+		key   string
+		limit loginLimit
+	}{
+		{LoginUsernameKey(username), usernameLoginLimit},
+		{LoginIPKey(ip), ipLoginLimit},
+	} {
+		attempt, reserved, err := l.attemptRepo.Reserve(k.key, now, now.Add(-loginResetWindow), k.limit.delay)
+		if err != nil {
+			l.Release(reservation)
+			return nil, 0, err
+		}
+		if !reserved {
+			// Give back what was counted against the other key
+			if err := l.Release(reservation); err != nil {
+				return nil, 0, err
+			}
+			wait := attempt.LockedUntil.Sub(now)
+			if wait < time.Second {
+				wait = time.Second
+			}
+			return nil, wait, nil
+		}
+
+		reservation.keys = append(reservation.keys, k.key)
+		reservation.locks[k.key] = attempt.LockedUntil
+		if reservation.block == nil {
+			reservation.block = &LoginBlock{
+				Failures:    attempt.Failures,
+				LockedUntil: attempt.LockedUntil,
+				Lockout:     attempt.Failures >= k.limit.lockoutAfter,
+			}
+		}
+	}
+
+	return reservation, 0, nil
+}
+
+// RecordFailure keeps the failure the reservation counted. The returned
+// block is the username's
+func (l *loginLimiter) RecordFailure(reservation *LoginReservation) (*LoginBlock, error) {
+	reservation.done = true
+	return reservation.block, nil
+}
+
+// Release takes back the failure counted by a reservation whose attempt did
+// not fail. It does nothing once the reservation has ended
+func (l *loginLimiter) Release(reservation *LoginReservation) error {
+	if reservation.done {
+		return nil
+	}
+	reservation.done = true
+
+	for _, key := range reservation.keys {
+		if err := l.attemptRepo.Release(key, reservation.locks[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delay is how long a key is blocked after its nth failure
+func (limit loginLimit) delay(failures int) time.Duration {
+	if failures >= limit.lockoutAfter {
+		return loginLockout
+	}
+	if failures <= limit.free {
+		return 0
+	}
+
+	delay := loginBackoff
+	for i := limit.free + 1; i < failures && delay < loginMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > loginMaxBackoff {
+		delay = loginMaxBackoff
+	}
+	return delay
+}
+
+// RecordSuccess clears the username's failures. The IP's count is left to
+// expire, so one valid account cannot be used to keep guessing others
+func (l *loginLimiter) RecordSuccess(username string) error {
+	return l.attemptRepo.Reset(LoginUsernameKey(username))
+}
+
+// GetLocked lists the usernames and IPs that are blocked right now
+func (l *loginLimiter) GetLocked() ([]models.LoginAttempt, error) {
+	return l.attemptRepo.FindLocked(time.Now())
+}
+
+func (l *loginLimiter) Unlock(key string) error {
+	return l.attemptRepo.Reset(key)
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"warehouse-api/repositories"
+)
+
+func TestLoginLimitDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    loginLimit
+		failures int
+		want     time.Duration
+	}{
+		{"username, none", usernameLoginLimit, 0, 0},
+		{"username, last free", usernameLoginLimit, 3, 0},
+		{"username, first blocked", usernameLoginLimit, 4, time.Second},
+		{"username, doubles", usernameLoginLimit, 5, 2 * time.Second},
+		{"username, before lockout", usernameLoginLimit, 9, 32 * time.Second},
+		{"username, lockout", usernameLoginLimit, 10, loginLockout},
+		{"username, past lockout", usernameLoginLimit, 25, loginLockout},
+		{"ip, last free", ipLoginLimit, 10, 0},
+		{"ip, first blocked", ipLoginLimit, 11, time.Second},
+		{"ip, below cap", ipLoginLimit, 19, 256 * time.Second},
+		{"ip, capped", ipLoginLimit, 20, loginMaxBackoff},
+		{"ip, stays capped", ipLoginLimit, 49, loginMaxBackoff},
+		{"ip, lockout", ipLoginLimit, 50, loginLockout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.delay(tt.failures); got != tt.want {
+				t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReserveAllowsOneAttemptOnceBlocking(t *testing.T) {
+	limiter := NewLoginLimiter(repositories.NewMemoryLoginAttemptRepository())
+
+	// Use up the free failures
+	for i := 0; i < usernameLoginLimit.free; i++ {
+		reservation, wait, err := limiter.Reserve("admin", fmt.Sprintf("10.0.0.%d", i))
+		if err != nil || reservation == nil {
+			t.Fatalf("free attempt %d: wait %v, error %v", i+1, wait, err)
+		}
+		limiter.RecordFailure(reservation)
+	}
+
+	// The next failure blocks the username, so only one of many parallel
+	// attempts may go ahead
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	granted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reservation, _, err := limiter.Reserve("admin", fmt.Sprintf("10.1.0.%d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if reservation != nil {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+				limiter.RecordFailure(reservation)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if granted != 1 {
+		t.Errorf("%d parallel attempts went ahead, want 1", granted)
+	}
+}
+
+func TestReleaseTakesBackAttempt(t *testing.T) {
+	limiter := NewLoginLimiter(repositories.NewMemoryLoginAttemptRepository())
+
+	for i := 0; i < usernameLoginLimit.free; i++ {
+		reservation, _, _ := limiter.Reserve("admin", "10.0.0.1")
+		limiter.RecordFailure(reservation)
+	}
+
+	// A successful attempt past the free failures must not leave a block
+	reservation, wait, err := limiter.Reserve("admin", "10.0.0.1")
+	if err != nil || reservation == nil {
+		t.Fatalf("Reserve() wait %v, error %v", wait, err)
+	}
+	if err := limiter.Release(reservation); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Release(reservation); err != nil {
+		t.Fatal(err)
+	}
+
+	wait, err = limiter.Check("admin", "10.0.0.1")
+	if err != nil || wait != 0 {
+		t.Fatalf("Check() after release = %v, %v, want no wait", wait, err)
+	}
+
+	// The count is back where it was, so the next failure blocks again
+	reservation, _, _ = limiter.Reserve("admin", "10.0.0.1")
+	block, err := limiter.RecordFailure(reservation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Failures != usernameLoginLimit.free+1 || block.LockedUntil == nil {
+		t.Errorf("block after release = %+v, want %d failures and a lock", block, usernameLoginLimit.free+1)
+	}
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// SSOStateTTL is how long the user has to log in at the IdP; the state
+	// cookie lasts as long
+	SSOStateTTL = 10 * time.Minute
+	// oidcRequestTimeout bounds each call to the IdP
+	oidcRequestTimeout = 10 * time.Second
+
+	maxUsernameLength = 50
+	maxNamaLength     = 100
+)
+
+// OIDCConfig is the client registration at the IdP and how its claims map
+// to local users. SSO is disabled when IssuerURL is empty
+type OIDCConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	GroupsClaim   string
+	UsernameClaim string
+	DefaultRole   string
+}
+
+type OIDCService interface {
+	Enabled() bool
+	Begin(ctx context.Context) (authURL, state string, err error)
+	Complete(ctx context.Context, state, code string) (*models.SSOLogin, error)
+	GetGroupRoles() ([]models.SSOGroupRole, error)
+	SetGroupRoles(mappings []models.SSOGroupRole) error
+}
+
+type oidcService struct {
+	ssoRepo  repositories.SSORepository
+	userRepo repositories.UserRepository
+	cfg      OIDCConfig
+	client   *http.Client
+
+	// The provider is discovered on first use, so the API still starts while
+	// the IdP is down
+	mu       sync.Mutex
+	provider *oidc.Provider
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCService(ssoRepo repositories.SSORepository, userRepo repositories.UserRepository, cfg OIDCConfig) OIDCService {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{oidc.ScopeOpenID, "profile", "email", "groups"}
+	}
+	return &oidcService{
+		ssoRepo:  ssoRepo,
+		userRepo: userRepo,
+		cfg:      cfg,
+		client:   &http.Client{Timeout: oidcRequestTimeout},
+	}
+}
+
+func (s *oidcService) Enabled() bool {
+	return s.cfg.IssuerURL != ""
+}
+
+func (s *oidcService) discover(ctx context.Context) (*oidc.Provider, *oauth2.Config, *oidc.IDTokenVerifier, error) {
+	if !s.Enabled() {
+		return nil, nil, nil, fmt.Errorf("sso not configured")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.provider == nil {
+		provider, err := oidc.NewProvider(ctx, s.cfg.IssuerURL)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("oidc discovery: %w", err)
+		}
+
+		scopes := s.cfg.Scopes
+		hasOpenID := false
+		for _, scope := range scopes {
+			if scope == oidc.ScopeOpenID {
+				hasOpenID = true
+			}
+		}
+		if !hasOpenID {
+			scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+		}
+
+		s.provider = provider
+		s.oauth = &oauth2.Config{
+			ClientID:     s.cfg.ClientID,
+			ClientSecret: s.cfg.ClientSecret,
+			RedirectURL:  s.cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		}
+		s.verifier = provider.Verifier(&oidc.Config{ClientID: s.cfg.ClientID})
+	}
+
+	return s.provider, s.oauth, s.verifier, nil
+}
+
+// Begin stores a new login state and returns the IdP URL to send the
+// browser to. The state, nonce and PKCE verifier are only ever sent to the
+// browser or the IdP; the database holds the state's hash
+func (s *oidcService) Begin(ctx context.Context) (string, string, error) {
+	ctx = oidc.ClientContext(ctx, s.client)
+	_, oauth, _, err := s.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	if err := s.ssoRepo.DeleteExpiredStates(now); err != nil {
+		return "", "", err
+	}
+
+	state, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	if err := s.ssoRepo.CreateState(&models.SSOLoginState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(SSOStateTTL),
+	}); err != nil {
+		return "", "", err
+	}
+
+	authURL := oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return authURL, state, nil
+}
+
+// Complete exchanges the code from the IdP callback, verifies the ID token
+// and returns the local user, creating it on the first login. The user's
+// role is set from their groups every time
+func (s *oidcService) Complete(ctx context.Context, state, code string) (*models.SSOLogin, error) {
+	ctx = oidc.ClientContext(ctx, s.client)
+	provider, oauth, verifier, err := s.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loginState, err := s.ssoRepo.TakeState(hashToken(state))
+	if err != nil {
+		if err.Error() == "sso state not found" {
+			return nil, fmt.Errorf("invalid sso state")
+		}
+		return nil, err
+	}
+	if time.Now().After(loginState.ExpiresAt) {
+		return nil, fmt.Errorf("invalid sso state")
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(loginState.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc token exchange: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("oidc token response has no id_token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc id_token: %w", err)
+	}
+	if idToken.Nonce != loginState.Nonce {
+		return nil, fmt.Errorf("oidc id_token: nonce does not match")
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	// Some IdPs only put the groups in the userinfo response
+	if _, ok := claims[s.cfg.GroupsClaim]; !ok && provider.UserInfoEndpoint() != "" {
+		info, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, fmt.Errorf("oidc userinfo: %w", err)
+		}
+		extra := map[string]interface{}{}
+		if err := info.Claims(&extra); err != nil {
+			return nil, err
+		}
+		if info.Subject != idToken.Subject {
+			return nil, fmt.Errorf("oidc userinfo: subject does not match")
+		}
+		for k, v := range extra {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	identity := s.identity(idToken, claims)
+	if identity.Username == "" {
+		return nil, fmt.Errorf("oidc id_token has no username")
+	}
+	return s.resolveUser(identity)
+}
+
+func (s *oidcService) identity(idToken *oidc.IDToken, claims map[string]interface{}) *models.SSOIdentity {
+	identity := &models.SSOIdentity{
+		Issuer:   idToken.Issuer,
+		Subject:  idToken.Subject,
+		Username: stringClaim(claims, s.cfg.UsernameClaim),
+		Nama:     stringClaim(claims, "name"),
+		Groups:   listClaim(claims, s.cfg.GroupsClaim),
+	}
+	if identity.Username == "" {
+		identity.Username = stringClaim(claims, "email")
+	}
+	identity.Username = truncate(identity.Username, maxUsernameLength)
+	if identity.Nama == "" {
+		identity.Nama = identity.Username
+	}
+	identity.Nama = truncate(identity.Nama, maxNamaLength)
+	return identity
+}
+
+// resolveUser finds or provisions the user for identity and applies the
+// role their groups map to
+func (s *oidcService) resolveUser(identity *models.SSOIdentity) (*models.SSOLogin, error) {
+	role, err := s.roleForGroups(identity.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.ssoRepo.FindUserByIdentity(identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		// SSO users log in through the IdP only; the password is a random
+		// one nobody knows
+		password, err := randomString(32)
+		if err != nil {
+			return nil, err
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+
+		user = &models.User{
+			Username: identity.Username,
+			Password: string(hashed),
+			Nama:     identity.Nama,
+			Role:     role,
+		}
+		if err := s.ssoRepo.CreateUser(user, identity.Issuer, identity.Subject); err != nil {
+			return nil, err
+		}
+		return &models.SSOLogin{User: user, Created: true}, nil
+	}
+
+	if !user.IsActive {
+		return nil, fmt.Errorf("user is disabled")
+	}
+
+	login := &models.SSOLogin{User: user}
+	if user.Role != role {
+		login.PreviousRole = user.Role
+		user.Role = role
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+	}
+	return login, nil
+}
+
+// roleForGroups returns the role of the first mapping, by priority, whose
+// group the user is in, or the default role
+func (s *oidcService) roleForGroups(groups []string) (string, error) {
+	mappings, err := s.ssoRepo.FindGroupRoles()
+	if err != nil {
+		return "", err
+	}
+
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+	for _, m := range mappings {
+		if member[m.GroupName] {
+			return m.Role, nil
+		}
+	}
+
+	if s.cfg.DefaultRole == "" {
+		return "", fmt.Errorf("no role for groups")
+	}
+	return s.cfg.DefaultRole, nil
+}
+
+func (s *oidcService) GetGroupRoles() ([]models.SSOGroupRole, error) {
+	return s.ssoRepo.FindGroupRoles()
+}
+
+// SetGroupRoles replaces the mappings; their order in the list is their
+// priority
+func (s *oidcService) SetGroupRoles(mappings []models.SSOGroupRole) error {
+	seen := make(map[string]bool, len(mappings))
+	for i := range mappings {
+		if seen[mappings[i].GroupName] {
+			return fmt.Errorf("duplicate group")
+		}
+		seen[mappings[i].GroupName] = true
+		mappings[i].Priority = i + 1
+	}
+	return s.ssoRepo.ReplaceGroupRoles(mappings)
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// listClaim reads a claim that is either a list of strings or, with some
+// IdPs when there is only one value, a single string
+func listClaim(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
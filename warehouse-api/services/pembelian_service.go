@@ -10,6 +10,7 @@ import (
 type PembelianService interface {
 	CreatePembelian(req *models.CreatePembelianRequest, userID int) (*models.BeliHeaderWithDetail, error)
 	GetAllPembelian(limit, offset int) ([]models.BeliHeader, int, error)
+	ForEachPembelian(fn func(models.BeliHeader) error) error
 	GetPembelianByID(id int) (*models.BeliHeaderWithDetail, error)
 }
 
@@ -18,15 +19,18 @@ type pembelianService struct {
 	pembelianRepo repositories.PembelianRepository
 	barangRepo    repositories.BarangRepository
 	stokRepo      repositories.StokRepository
+	supplierRepo  repositories.SupplierRepository
 }
 
 func NewPembelianService(db *sql.DB, pembelianRepo repositories.PembelianRepository,
-	barangRepo repositories.BarangRepository, stokRepo repositories.StokRepository) PembelianService {
+	barangRepo repositories.BarangRepository, stokRepo repositories.StokRepository,
+	supplierRepo repositories.SupplierRepository) PembelianService {
 	return &pembelianService{
 		db:            db,
 		pembelianRepo: pembelianRepo,
 		barangRepo:    barangRepo,
 		stokRepo:      stokRepo,
+		supplierRepo:  supplierRepo,
 	}
 }
 
@@ -36,6 +40,12 @@ func (s *pembelianService) CreatePembelian(req *models.CreatePembelianRequest, u
 		return nil, fmt.Errorf("details cannot be empty")
 	}
 
+	// Validate supplier exists
+	supplier, err := s.supplierRepo.FindByID(req.SupplierID)
+	if err != nil {
+		return nil, fmt.Errorf("supplier with id %d not found", req.SupplierID)
+	}
+
 	// Auto-generate no faktur if empty
 	if req.NoFaktur == "" {
 		noFaktur, err := s.pembelianRepo.GenerateNoFaktur(req.Tanggal)
@@ -48,10 +58,16 @@ func (s *pembelianService) CreatePembelian(req *models.CreatePembelianRequest, u
 	// Calculate total
 	var total float64
 	for i, detail := range req.Details {
-		// Validate barang exists and get harga beli
-		barang, err := s.barangRepo.FindByID(detail.BarangID)
+		// Validate barang exists (by id or scanned barcode) and get harga beli
+		barang, err := findDetailBarang(s.barangRepo, detail.BarangID, detail.Barcode)
 		if err != nil {
-			return nil, fmt.Errorf("barang with id %d not found", detail.BarangID)
+			return nil, err
+		}
+		req.Details[i].BarangID = barang.ID
+
+		// Kits have no stock of their own; their components are purchased instead
+		if barang.IsKit {
+			return nil, fmt.Errorf("barang %s is a kit and cannot be purchased", barang.KodeBarang)
 		}
 
 		// Auto-fill harga beli from master barang if not provided
@@ -74,7 +90,8 @@ func (s *pembelianService) CreatePembelian(req *models.CreatePembelianRequest, u
 	header := &models.BeliHeader{
 		NoFaktur:   req.NoFaktur,
 		Tanggal:    req.Tanggal,
-		Supplier:   req.Supplier,
+		SupplierID: supplier.ID,
+		Supplier:   supplier.NamaSupplier,
 		Total:      total,
 		Keterangan: req.Keterangan,
 		CreatedBy:  userID,
@@ -166,6 +183,10 @@ func (s *pembelianService) GetAllPembelian(limit, offset int) ([]models.BeliHead
 	return s.pembelianRepo.FindAll(limit, offset)
 }
 
+func (s *pembelianService) ForEachPembelian(fn func(models.BeliHeader) error) error {
+	return s.pembelianRepo.ForEach(fn)
+}
+
 func (s *pembelianService) GetPembelianByID(id int) (*models.BeliHeaderWithDetail, error) {
 	return s.pembelianRepo.FindByID(id)
 }
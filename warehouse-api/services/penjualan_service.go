@@ -3,6 +3,7 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"warehouse-api/models"
 	"warehouse-api/repositories"
 )
@@ -10,7 +11,10 @@ import (
 type PenjualanService interface {
 	CreatePenjualan(req *models.CreatePenjualanRequest, userID int) (*models.JualHeaderWithDetail, error)
 	GetAllPenjualan(limit, offset int) ([]models.JualHeader, int, error)
+	ForEachPenjualan(fn func(models.JualHeader) error) error
 	GetPenjualanByID(id int) (*models.JualHeaderWithDetail, error)
+	CreatePembayaran(jualHeaderID int, req *models.CreatePembayaranRequest, userID int) (*models.PembayaranPenjualan, error)
+	ResolveHarga(customerID, barangID, qty int) (*models.ResolvedHarga, error)
 }
 
 type penjualanService struct {
@@ -18,15 +22,23 @@ type penjualanService struct {
 	penjualanRepo repositories.PenjualanRepository
 	barangRepo    repositories.BarangRepository
 	stokRepo      repositories.StokRepository
+	customerRepo  repositories.CustomerRepository
+	priceListRepo repositories.PriceListRepository
+	kitRepo       repositories.KitRepository
 }
 
 func NewPenjualanService(db *sql.DB, penjualanRepo repositories.PenjualanRepository,
-	barangRepo repositories.BarangRepository, stokRepo repositories.StokRepository) PenjualanService {
+	barangRepo repositories.BarangRepository, stokRepo repositories.StokRepository,
+	customerRepo repositories.CustomerRepository, priceListRepo repositories.PriceListRepository,
+	kitRepo repositories.KitRepository) PenjualanService {
 	return &penjualanService{
 		db:            db,
 		penjualanRepo: penjualanRepo,
 		barangRepo:    barangRepo,
 		stokRepo:      stokRepo,
+		customerRepo:  customerRepo,
+		priceListRepo: priceListRepo,
+		kitRepo:       kitRepo,
 	}
 }
 
@@ -36,6 +48,12 @@ func (s *penjualanService) CreatePenjualan(req *models.CreatePenjualanRequest, u
 		return nil, fmt.Errorf("details cannot be empty")
 	}
 
+	// Validate customer exists
+	customer, err := s.customerRepo.FindByID(req.CustomerID)
+	if err != nil {
+		return nil, fmt.Errorf("customer with id %d not found", req.CustomerID)
+	}
+
 	// Auto-generate no faktur if empty
 	if req.NoFaktur == "" {
 		noFaktur, err := s.penjualanRepo.GenerateNoFaktur(req.Tanggal)
@@ -45,58 +63,125 @@ func (s *penjualanService) CreatePenjualan(req *models.CreatePenjualanRequest, u
 		req.NoFaktur = noFaktur
 	}
 
-	// Validate stock availability and calculate total
+	// Validate barang, resolve harga and calculate total
 	var total float64
+	barangs := make([]*models.Barang, len(req.Details))
+	komponens := make(map[int][]models.KomponenKit)
+	needed := make(map[int]int)
+	var neededOrder []int
+	addNeeded := func(barangID, qty int) {
+		if _, ok := needed[barangID]; !ok {
+			neededOrder = append(neededOrder, barangID)
+		}
+		needed[barangID] += qty
+	}
+
 	for i, detail := range req.Details {
-		// Validate barang exists and get harga jual
-		barang, err := s.barangRepo.FindByID(detail.BarangID)
+		// Validate barang exists (by id or scanned barcode) and get harga jual
+		barang, err := findDetailBarang(s.barangRepo, detail.BarangID, detail.Barcode)
 		if err != nil {
-			return nil, fmt.Errorf("barang with id %d not found", detail.BarangID)
+			return nil, err
 		}
+		req.Details[i].BarangID = barang.ID
+		barangs[i] = barang
 
-		// Auto-fill harga jual from master barang if not provided
+		// Auto-fill harga from the customer's price list, then master barang
 		if detail.Harga == 0 {
-			req.Details[i].Harga = barang.HargaJual
+			resolved, err := s.resolveHarga(customer, barang, detail.Qty)
+			if err != nil {
+				return nil, err
+			}
+			req.Details[i].Harga = resolved.Harga
 		}
 
-		// Check stock
-		currentStok, err := s.stokRepo.FindByBarangID(detail.BarangID)
+		// A kit consumes its components instead of its own stock
+		if barang.IsKit {
+			if _, ok := komponens[barang.ID]; !ok {
+				items, err := s.kitRepo.FindKomponen(barang.ID)
+				if err != nil {
+					return nil, err
+				}
+				if len(items) == 0 {
+					return nil, fmt.Errorf("kit %s has no komponen", barang.KodeBarang)
+				}
+				for _, k := range items {
+					if k.ArchivedAt != nil {
+						return nil, &BarangArchivedError{BarangID: k.KomponenBarangID, KodeBarang: k.KodeBarang}
+					}
+				}
+				komponens[barang.ID] = items
+			}
+			for _, k := range komponens[barang.ID] {
+				addNeeded(k.KomponenBarangID, detail.Qty*k.Qty)
+			}
+		} else {
+			addNeeded(barang.ID, detail.Qty)
+		}
+
+		subtotal := float64(detail.Qty) * req.Details[i].Harga
+		total += subtotal
+	}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Check stock for the combined quantity of every barang leaving the
+	// warehouse. The rows stay locked until commit so concurrent sales and
+	// production cannot take the same units; locking in barang_id order keeps
+	// them from deadlocking
+	sort.Ints(neededOrder)
+	stokAkhir := make(map[int]int)
+	for _, barangID := range neededOrder {
+		currentStok, err := s.stokRepo.FindByBarangIDForUpdate(tx, barangID)
 		if err != nil {
 			return nil, err
 		}
 
-		if currentStok == nil || currentStok.StokAkhir < detail.Qty {
+		if currentStok == nil {
 			return nil, &InsufficientStockError{
-				BarangID:     detail.BarangID,
-				RequestedQty: detail.Qty,
+				BarangID:     barangID,
+				RequestedQty: needed[barangID],
 				AvailableQty: 0,
 			}
 		}
 
-		if currentStok.StokAkhir < detail.Qty {
+		if currentStok.StokAkhir < needed[barangID] {
 			return nil, &InsufficientStockError{
-				BarangID:     detail.BarangID,
-				RequestedQty: detail.Qty,
+				BarangID:     barangID,
+				RequestedQty: needed[barangID],
 				AvailableQty: currentStok.StokAkhir,
 			}
 		}
 
-		subtotal := float64(detail.Qty) * req.Details[i].Harga
-		total += subtotal
+		stokAkhir[barangID] = currentStok.StokAkhir
 	}
 
-	// Begin transaction
-	tx, err := s.db.Begin()
+	// Check credit limit (0 means no limit) unless an admin overrides it. The
+	// customer stays locked until commit, so concurrent sales cannot each pass
+	// the check and together go over the limit
+	customer, err = s.customerRepo.FindByIDForUpdate(tx, customer.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback()
+	if customer.LimitKredit > 0 && !req.OverrideLimitKredit && customer.Piutang+total > customer.LimitKredit {
+		return nil, &CreditLimitExceededError{
+			CustomerID:  customer.ID,
+			LimitKredit: customer.LimitKredit,
+			Piutang:     customer.Piutang,
+			Requested:   total,
+		}
+	}
 
 	// Create header
 	header := &models.JualHeader{
 		NoFaktur:   req.NoFaktur,
 		Tanggal:    req.Tanggal,
-		Customer:   req.Customer,
+		CustomerID: customer.ID,
+		Customer:   customer.NamaCustomer,
 		Total:      total,
 		Keterangan: req.Keterangan,
 		CreatedBy:  userID,
@@ -106,9 +191,32 @@ func (s *penjualanService) CreatePenjualan(req *models.CreatePenjualanRequest, u
 		return nil, err
 	}
 
+	// Reduce stock and write history, tracking the running balance per barang
+	keluar := func(barangID, qty int, keterangan string) error {
+		if err := s.stokRepo.UpdateStok(tx, barangID, 0, qty); err != nil {
+			return err
+		}
+
+		history := &models.HistoryStok{
+			BarangID:       barangID,
+			JenisTransaksi: "keluar",
+			Qty:            qty,
+			StokSebelum:    stokAkhir[barangID],
+			StokSesudah:    stokAkhir[barangID] - qty,
+			Keterangan:     keterangan,
+			ReferensiID:    &header.ID,
+			ReferensiTipe:  "penjualan",
+		}
+		stokAkhir[barangID] -= qty
+
+		return s.stokRepo.InsertHistory(tx, history)
+	}
+
 	// Process each detail
 	var details []models.JualDetailWithBarang
-	for _, detailReq := range req.Details {
+	for i, detailReq := range req.Details {
+		barang := barangs[i]
+
 		// Create detail
 		detail := &models.JualDetail{
 			JualHeaderID: header.ID,
@@ -122,35 +230,19 @@ func (s *penjualanService) CreatePenjualan(req *models.CreatePenjualanRequest, u
 			return nil, err
 		}
 
-		// Get current stock
-		currentStok, err := s.stokRepo.FindByBarangID(detailReq.BarangID)
-		if err != nil {
-			return nil, err
-		}
-
-		// Update stock (reduce qty)
-		if err := s.stokRepo.UpdateStok(tx, detailReq.BarangID, 0, detailReq.Qty); err != nil {
-			return nil, err
-		}
-
-		// Insert history
-		history := &models.HistoryStok{
-			BarangID:       detailReq.BarangID,
-			JenisTransaksi: "keluar",
-			Qty:            detailReq.Qty,
-			StokSebelum:    currentStok.StokAkhir,
-			StokSesudah:    currentStok.StokAkhir - detailReq.Qty,
-			Keterangan:     fmt.Sprintf("Penjualan - %s", req.NoFaktur),
-			ReferensiID:    &header.ID,
-			ReferensiTipe:  "penjualan",
-		}
-
-		if err := s.stokRepo.InsertHistory(tx, history); err != nil {
-			return nil, err
+		if barang.IsKit {
+			for _, k := range komponens[barang.ID] {
+				keterangan := fmt.Sprintf("Penjualan - %s (Kit %s)", req.NoFaktur, barang.KodeBarang)
+				if err := keluar(k.KomponenBarangID, detailReq.Qty*k.Qty, keterangan); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			if err := keluar(barang.ID, detailReq.Qty, fmt.Sprintf("Penjualan - %s", req.NoFaktur)); err != nil {
+				return nil, err
+			}
 		}
 
-		// Get barang info for response
-		barang, _ := s.barangRepo.FindByID(detailReq.BarangID)
 		detailWithBarang := models.JualDetailWithBarang{
 			JualDetail: *detail,
 			KodeBarang: barang.KodeBarang,
@@ -177,10 +269,95 @@ func (s *penjualanService) GetAllPenjualan(limit, offset int) ([]models.JualHead
 	return s.penjualanRepo.FindAll(limit, offset)
 }
 
+func (s *penjualanService) ForEachPenjualan(fn func(models.JualHeader) error) error {
+	return s.penjualanRepo.ForEach(fn)
+}
+
 func (s *penjualanService) GetPenjualanByID(id int) (*models.JualHeaderWithDetail, error) {
 	return s.penjualanRepo.FindByID(id)
 }
 
+func (s *penjualanService) ResolveHarga(customerID, barangID, qty int) (*models.ResolvedHarga, error) {
+	customer, err := s.customerRepo.FindByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	barang, err := s.barangRepo.FindByID(barangID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolveHarga(customer, barang, qty)
+}
+
+// resolveHarga picks the selling price: the customer's price list with the
+// largest quantity break not above qty, falling back to harga_jual
+func (s *penjualanService) resolveHarga(customer *models.Customer, barang *models.Barang, qty int) (*models.ResolvedHarga, error) {
+	resolved := &models.ResolvedHarga{
+		BarangID: barang.ID,
+		Qty:      qty,
+		Harga:    barang.HargaJual,
+	}
+
+	if customer.PriceListID == nil {
+		return resolved, nil
+	}
+
+	item, err := s.priceListRepo.FindHarga(*customer.PriceListID, barang.ID, qty)
+	if err != nil {
+		return nil, err
+	}
+	if item != nil {
+		resolved.Harga = item.Harga
+		resolved.PriceListID = &item.PriceListID
+		resolved.MinQty = item.MinQty
+	}
+
+	return resolved, nil
+}
+
+func (s *penjualanService) CreatePembayaran(jualHeaderID int, req *models.CreatePembayaranRequest, userID int) (*models.PembayaranPenjualan, error) {
+	if req.Jumlah <= 0 {
+		return nil, fmt.Errorf("jumlah must be greater than zero")
+	}
+
+	// Validate penjualan exists
+	if _, err := s.penjualanRepo.FindByID(jualHeaderID); err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.penjualanRepo.AddTerbayar(tx, jualHeaderID, req.Jumlah); err != nil {
+		return nil, err
+	}
+
+	pembayaran := &models.PembayaranPenjualan{
+		JualHeaderID: jualHeaderID,
+		Tanggal:      req.Tanggal,
+		Jumlah:       req.Jumlah,
+		Keterangan:   req.Keterangan,
+		CreatedBy:    userID,
+	}
+
+	if err := s.penjualanRepo.CreatePembayaran(tx, pembayaran); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return pembayaran, nil
+}
+
 // Custom error for insufficient stock
 type InsufficientStockError struct {
 	BarangID     int
@@ -192,3 +369,16 @@ func (e *InsufficientStockError) Error() string {
 	return fmt.Sprintf("insufficient stock for barang_id %d: requested %d, available %d",
 		e.BarangID, e.RequestedQty, e.AvailableQty)
 }
+
+// Custom error for a sale that would exceed the customer's credit limit
+type CreditLimitExceededError struct {
+	CustomerID  int
+	LimitKredit float64
+	Piutang     float64
+	Requested   float64
+}
+
+func (e *CreditLimitExceededError) Error() string {
+	return fmt.Sprintf("credit limit exceeded for customer_id %d: limit %.2f, outstanding %.2f, requested %.2f",
+		e.CustomerID, e.LimitKredit, e.Piutang, e.Requested)
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// StartPriceScheduler applies due scheduled price changes immediately and then
+// every interval in a background goroutine
+func StartPriceScheduler(barangService BarangService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			applied, err := barangService.ApplyDuePriceChanges()
+			if err != nil {
+				log.Printf("Price scheduler: failed to apply scheduled prices: %v", err)
+			} else if applied > 0 {
+				log.Printf("Price scheduler: applied %d scheduled price change(s)", applied)
+			}
+
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+type ProduksiService interface {
+	CreateProduksi(req *models.CreateProduksiRequest, userID int) (*models.ProduksiWithDetail, error)
+	GetAllProduksi(status string, limit, offset int) ([]models.ProduksiHeader, int, error)
+	GetProduksiByID(id int) (*models.ProduksiWithDetail, error)
+	CreateRealisasi(produksiID int, req *models.CreateRealisasiRequest, userID int) (*models.ProduksiWithDetail, error)
+	CancelProduksi(produksiID int) (*models.ProduksiWithDetail, error)
+}
+
+type produksiService struct {
+	db              *sql.DB
+	produksiRepo    repositories.ProduksiRepository
+	bomRepo         repositories.BOMRepository
+	barangRepo      repositories.BarangRepository
+	stokRepo        repositories.StokRepository
+	hargaBarangRepo repositories.HargaBarangRepository
+	auditRepo       repositories.AuditRepository
+}
+
+func NewProduksiService(db *sql.DB, produksiRepo repositories.ProduksiRepository,
+	bomRepo repositories.BOMRepository, barangRepo repositories.BarangRepository,
+	stokRepo repositories.StokRepository, hargaBarangRepo repositories.HargaBarangRepository,
+	auditRepo repositories.AuditRepository) ProduksiService {
+	return &produksiService{
+		db:              db,
+		produksiRepo:    produksiRepo,
+		bomRepo:         bomRepo,
+		barangRepo:      barangRepo,
+		stokRepo:        stokRepo,
+		hargaBarangRepo: hargaBarangRepo,
+		auditRepo:       auditRepo,
+	}
+}
+
+func (s *produksiService) CreateProduksi(req *models.CreateProduksiRequest, userID int) (*models.ProduksiWithDetail, error) {
+	if req.QtyRencana < 1 {
+		return nil, fmt.Errorf("qty_rencana must be at least 1")
+	}
+
+	// Validate BOM exists and has components
+	bom, err := s.bomRepo.FindByID(req.BomID)
+	if err != nil {
+		return nil, fmt.Errorf("bom with id %d not found", req.BomID)
+	}
+	if len(bom.Details) == 0 {
+		return nil, fmt.Errorf("bom %s has no komponen", bom.KodeBOM)
+	}
+	if err := s.checkNotArchived(bom.BarangID, bom.Details); err != nil {
+		return nil, err
+	}
+
+	// Auto-generate no produksi if empty
+	if req.NoProduksi == "" {
+		noProduksi, err := s.produksiRepo.GenerateNoProduksi(req.Tanggal)
+		if err != nil {
+			return nil, err
+		}
+		req.NoProduksi = noProduksi
+	}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header := &models.ProduksiHeader{
+		NoProduksi: req.NoProduksi,
+		Tanggal:    req.Tanggal,
+		BomID:      bom.ID,
+		BarangID:   bom.BarangID,
+		QtyRencana: req.QtyRencana,
+		Keterangan: req.Keterangan,
+		CreatedBy:  userID,
+	}
+
+	if err := s.produksiRepo.CreateHeader(tx, header); err != nil {
+		return nil, err
+	}
+
+	// The order keeps the BOM as it is now; later BOM edits only apply to new orders
+	if err := s.produksiRepo.CreateKomponen(tx, header.ID, bom.Details); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.produksiRepo.FindByID(header.ID)
+}
+
+func (s *produksiService) GetAllProduksi(status string, limit, offset int) ([]models.ProduksiHeader, int, error) {
+	return s.produksiRepo.FindAll(status, limit, offset)
+}
+
+func (s *produksiService) GetProduksiByID(id int) (*models.ProduksiWithDetail, error) {
+	return s.produksiRepo.FindByID(id)
+}
+
+// CreateRealisasi records a (possibly partial) completion of a production order.
+// Components are those the order was created with, not the BOM's current lines.
+// Components for both finished and scrapped units leave the warehouse, finished
+// units enter it, and the component cost is rolled into the finished good's
+// harga_beli as a weighted average, all in one transaction.
+func (s *produksiService) CreateRealisasi(produksiID int, req *models.CreateRealisasiRequest, userID int) (*models.ProduksiWithDetail, error) {
+	if req.QtySelesai < 0 || req.QtyScrap < 0 {
+		return nil, fmt.Errorf("qty cannot be negative")
+	}
+	dikerjakan := req.QtySelesai + req.QtyScrap
+	if dikerjakan == 0 {
+		return nil, fmt.Errorf("qty_selesai or qty_scrap must be greater than zero")
+	}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header, err := s.produksiRepo.FindByIDForUpdate(tx, produksiID)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Status != "proses" {
+		return nil, fmt.Errorf("produksi is already %s", header.Status)
+	}
+
+	sisa := header.QtyRencana - header.QtySelesai - header.QtyScrap
+	if dikerjakan > sisa {
+		return nil, fmt.Errorf("qty exceeds remaining produksi quantity of %d", sisa)
+	}
+
+	komponen, err := s.produksiRepo.FindKomponenTx(tx, header.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotArchived(header.BarangID, komponen); err != nil {
+		return nil, err
+	}
+
+	// Lock the stock of the komponen, and of the finished good when units are
+	// finished, until commit so concurrent sales and production cannot take
+	// the same units. Rows are locked in barang_id order, as sales do, so the
+	// two cannot deadlock
+	ids := make([]int, 0, len(komponen)+1)
+	for _, k := range komponen {
+		ids = append(ids, k.KomponenBarangID)
+	}
+	if req.QtySelesai > 0 {
+		ids = append(ids, header.BarangID)
+	}
+	sort.Ints(ids)
+
+	stoks := make(map[int]*models.Stok)
+	for _, id := range ids {
+		stok, err := s.stokRepo.FindByBarangIDForUpdate(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		stoks[id] = stok
+	}
+
+	// Check component stock and add up their cost
+	var biayaKomponen float64
+	stokAkhir := make(map[int]int)
+	for _, k := range komponen {
+		qty := k.Qty * dikerjakan
+
+		available := 0
+		if currentStok := stoks[k.KomponenBarangID]; currentStok != nil {
+			available = currentStok.StokAkhir
+		}
+		if available < qty {
+			return nil, &InsufficientStockError{
+				BarangID:     k.KomponenBarangID,
+				RequestedQty: qty,
+				AvailableQty: available,
+			}
+		}
+
+		stokAkhir[k.KomponenBarangID] = available
+		biayaKomponen += float64(qty) * k.HargaBeli
+	}
+
+	realisasi := &models.ProduksiRealisasi{
+		ProduksiHeaderID: header.ID,
+		Tanggal:          req.Tanggal,
+		QtySelesai:       req.QtySelesai,
+		QtyScrap:         req.QtyScrap,
+		BiayaKomponen:    biayaKomponen,
+		Keterangan:       req.Keterangan,
+		CreatedBy:        userID,
+	}
+
+	// Scrapped units carry no value, so their cost is absorbed by finished units
+	if req.QtySelesai > 0 {
+		realisasi.BiayaPerUnit = math.Round(biayaKomponen/float64(req.QtySelesai)*100) / 100
+	}
+
+	if err := s.produksiRepo.CreateRealisasi(tx, realisasi); err != nil {
+		return nil, err
+	}
+
+	keterangan := fmt.Sprintf("Produksi - %s", header.NoProduksi)
+
+	// Reduce component stock and write history
+	for _, k := range komponen {
+		qty := k.Qty * dikerjakan
+
+		if err := s.stokRepo.UpdateStok(tx, k.KomponenBarangID, 0, qty); err != nil {
+			return nil, err
+		}
+
+		history := &models.HistoryStok{
+			BarangID:       k.KomponenBarangID,
+			JenisTransaksi: "keluar",
+			Qty:            qty,
+			StokSebelum:    stokAkhir[k.KomponenBarangID],
+			StokSesudah:    stokAkhir[k.KomponenBarangID] - qty,
+			Keterangan:     keterangan,
+			ReferensiID:    &header.ID,
+			ReferensiTipe:  "produksi",
+		}
+		stokAkhir[k.KomponenBarangID] -= qty
+
+		if err := s.stokRepo.InsertHistory(tx, history); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.QtySelesai > 0 {
+		if err := s.terimaBarangJadi(tx, header, stoks[header.BarangID], realisasi, keterangan, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update progress, closing the order once every planned unit is accounted for
+	header.QtySelesai += req.QtySelesai
+	header.QtyScrap += req.QtyScrap
+	if header.QtySelesai+header.QtyScrap >= header.QtyRencana {
+		header.Status = "selesai"
+	}
+
+	if err := s.produksiRepo.UpdateProgress(tx, header); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.produksiRepo.FindByID(header.ID)
+}
+
+// checkNotArchived rejects production when the finished good or any komponen
+// has been archived
+func (s *produksiService) checkNotArchived(barangID int, komponen []models.BOMDetail) error {
+	barang, err := s.barangRepo.FindByID(barangID)
+	if err != nil {
+		return err
+	}
+	if barang.ArchivedAt != nil {
+		return &BarangArchivedError{BarangID: barang.ID, KodeBarang: barang.KodeBarang}
+	}
+
+	for _, k := range komponen {
+		if k.ArchivedAt != nil {
+			return &BarangArchivedError{BarangID: k.KomponenBarangID, KodeBarang: k.KodeBarang}
+		}
+	}
+	return nil
+}
+
+// terimaBarangJadi adds finished units to stock and moves the finished good's
+// harga_beli to the weighted average of existing stock and this realisasi,
+// recording the change in price history and the audit log. currentStok is
+// the finished good's stock row, already locked, or nil when it has none
+func (s *produksiService) terimaBarangJadi(tx *sql.Tx, header *models.ProduksiHeader, currentStok *models.Stok,
+	realisasi *models.ProduksiRealisasi, keterangan string, userID int) error {
+	barang, err := s.barangRepo.FindByIDForUpdate(tx, header.BarangID)
+	if err != nil {
+		return err
+	}
+
+	// If stock doesn't exist, create it
+	if currentStok == nil {
+		if err := s.stokRepo.CreateStok(tx, header.BarangID); err != nil {
+			return err
+		}
+		currentStok = &models.Stok{
+			BarangID:  header.BarangID,
+			StokAkhir: 0,
+		}
+	}
+
+	if err := s.stokRepo.UpdateStok(tx, header.BarangID, realisasi.QtySelesai, 0); err != nil {
+		return err
+	}
+
+	history := &models.HistoryStok{
+		BarangID:       header.BarangID,
+		JenisTransaksi: "masuk",
+		Qty:            realisasi.QtySelesai,
+		StokSebelum:    currentStok.StokAkhir,
+		StokSesudah:    currentStok.StokAkhir + realisasi.QtySelesai,
+		Keterangan:     keterangan,
+		ReferensiID:    &header.ID,
+		ReferensiTipe:  "produksi",
+	}
+
+	if err := s.stokRepo.InsertHistory(tx, history); err != nil {
+		return err
+	}
+
+	stokLama := currentStok.StokAkhir
+	if stokLama < 0 {
+		stokLama = 0
+	}
+	hargaBeli := (float64(stokLama)*barang.HargaBeli + realisasi.BiayaKomponen) /
+		float64(stokLama+realisasi.QtySelesai)
+	hargaBeli = math.Round(hargaBeli*100) / 100
+
+	if hargaBeli == barang.HargaBeli {
+		return nil
+	}
+
+	priceHistory := &models.HistoryHargaBarang{
+		BarangID:      barang.ID,
+		HargaBeliLama: barang.HargaBeli,
+		HargaBeliBaru: hargaBeli,
+		HargaJualLama: barang.HargaJual,
+		HargaJualBaru: barang.HargaJual,
+		Keterangan:    keterangan,
+		ChangedBy:     &userID,
+	}
+
+	before := *barang
+	barang.HargaBeli = hargaBeli
+	if err := s.barangRepo.Update(tx, barang); err != nil {
+		return err
+	}
+
+	entry, err := newAuditEntry(&userID, "produksi_cost", "barang", barang.ID, barang.KodeBarang, before, barang)
+	if err != nil {
+		return err
+	}
+	if err := s.auditRepo.CreateTx(tx, entry); err != nil {
+		return err
+	}
+
+	return s.hargaBarangRepo.InsertHistory(tx, priceHistory)
+}
+
+// CancelProduksi closes an order that is still in progress. Realisasi already
+// recorded stays in stock; only the remaining planned quantity is dropped.
+func (s *produksiService) CancelProduksi(produksiID int) (*models.ProduksiWithDetail, error) {
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header, err := s.produksiRepo.FindByIDForUpdate(tx, produksiID)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Status != "proses" {
+		return nil, fmt.Errorf("produksi is already %s", header.Status)
+	}
+
+	header.Status = "batal"
+	if err := s.produksiRepo.UpdateProgress(tx, header); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.produksiRepo.FindByID(header.ID)
+}
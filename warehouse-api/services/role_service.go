@@ -0,0 +1,115 @@
+package services
+
+import (
+	"sync"
+	"time"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+// rolePermissionCacheTTL bounds how long a permission change can take to
+// reach another server instance; changes made through this instance apply
+// immediately
+const rolePermissionCacheTTL = 30 * time.Second
+
+type RoleService interface {
+	GetAll() ([]models.Role, error)
+	GetByID(id int) (*models.Role, error)
+	GetPermissions() ([]models.Permission, error)
+	Create(role *models.Role) error
+	Update(role *models.Role) error
+	Delete(id int) error
+	SetRequire2FA(id int, require bool) error
+	RolePermissions(role string) (map[string]bool, error)
+}
+
+type rolePermissionEntry struct {
+	permissions map[string]bool
+	loadedAt    time.Time
+}
+
+type roleService struct {
+	roleRepo repositories.RoleRepository
+
+	mu    sync.Mutex
+	cache map[string]rolePermissionEntry
+}
+
+func NewRoleService(roleRepo repositories.RoleRepository) RoleService {
+	return &roleService{
+		roleRepo: roleRepo,
+		cache:    make(map[string]rolePermissionEntry),
+	}
+}
+
+func (s *roleService) GetAll() ([]models.Role, error) {
+	return s.roleRepo.FindAll()
+}
+
+func (s *roleService) GetByID(id int) (*models.Role, error) {
+	return s.roleRepo.FindByID(id)
+}
+
+func (s *roleService) GetPermissions() ([]models.Permission, error) {
+	return s.roleRepo.FindPermissions()
+}
+
+func (s *roleService) Create(role *models.Role) error {
+	return s.roleRepo.Create(role)
+}
+
+func (s *roleService) Update(role *models.Role) error {
+	if err := s.roleRepo.Update(role); err != nil {
+		return err
+	}
+	s.clearCache()
+	return nil
+}
+
+func (s *roleService) Delete(id int) error {
+	if err := s.roleRepo.Delete(id); err != nil {
+		return err
+	}
+	s.clearCache()
+	return nil
+}
+
+func (s *roleService) SetRequire2FA(id int, require bool) error {
+	return s.roleRepo.SetRequire2FA(id, require)
+}
+
+// RolePermissions implements middleware.PermissionSource. Results are cached
+// for rolePermissionCacheTTL so requests do not each hit the database
+func (s *roleService) RolePermissions(role string) (map[string]bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[role]
+	s.mu.Unlock()
+	if ok && now.Sub(entry.loadedAt) < rolePermissionCacheTTL {
+		return entry.permissions, nil
+	}
+
+	list, err := s.roleRepo.FindRolePermissions(role)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(list))
+	for _, p := range list {
+		permissions[p] = true
+	}
+
+	s.mu.Lock()
+	s.cache[role] = rolePermissionEntry{permissions: permissions, loadedAt: now}
+	s.mu.Unlock()
+
+	return permissions, nil
+}
+
+// clearCache drops every cached role; a rename changes the key as well
+func (s *roleService) clearCache() {
+	s.mu.Lock()
+	s.cache = make(map[string]rolePermissionEntry)
+	s.mu.Unlock()
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+	"warehouse-api/middleware"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+// sessionCacheTTL bounds how long a revoked session can keep working on
+// another server instance; revocations made through this instance apply
+// immediately
+const sessionCacheTTL = 30 * time.Second
+
+type SessionService interface {
+	CreateSession(user *models.User, ipAddress, userAgent string) (*models.TokenPair, error)
+	Refresh(refreshToken string) (*models.TokenPair, *models.User, error)
+	Revoke(sessionID int64) error
+	RevokeUserSessions(userID int, exceptSessionID int64) error
+	IsSessionActive(sessionID int64) (bool, error)
+}
+
+type sessionCacheEntry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+type sessionService struct {
+	db              *sql.DB
+	sessionRepo     repositories.SessionRepository
+	userRepo        repositories.UserRepository
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[int64]sessionCacheEntry
+}
+
+func NewSessionService(db *sql.DB, sessionRepo repositories.SessionRepository, userRepo repositories.UserRepository,
+	accessTokenTTL, refreshTokenTTL time.Duration) SessionService {
+	return &sessionService{
+		db:              db,
+		sessionRepo:     sessionRepo,
+		userRepo:        userRepo,
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+		cache:           make(map[int64]sessionCacheEntry),
+	}
+}
+
+// CreateSession starts a session for a user who has just logged in
+func (s *sessionService) CreateSession(user *models.User, ipAddress, userAgent string) (*models.TokenPair, error) {
+	// Sessions that can no longer be refreshed are of no use to anyone
+	if err := s.sessionRepo.DeleteExpiredSessions(user.ID); err != nil {
+		return nil, err
+	}
+
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	session := &models.UserSession{
+		UserID:    user.ID,
+		IPAddress: &ipAddress,
+		UserAgent: &userAgent,
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.sessionRepo.CreateSession(tx, session); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.createRefreshToken(tx, session.ID, session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.tokenPair(user, session.ID, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token. Each
+// refresh token works once; presenting it again revokes the session
+func (s *sessionService) Refresh(refreshToken string) (*models.TokenPair, *models.User, error) {
+	token, err := s.sessionRepo.FindRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		if err.Error() == "refresh token not found" {
+			return nil, nil, fmt.Errorf("invalid refresh token")
+		}
+		return nil, nil, err
+	}
+
+	session, err := s.sessionRepo.FindSession(token.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session.RevokedAt != nil {
+		return nil, nil, fmt.Errorf("session revoked")
+	}
+	if token.UsedAt != nil {
+		if err := s.Revoke(session.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("refresh token reused")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, nil, fmt.Errorf("refresh token expired")
+	}
+
+	// The role and the disabled flag are reloaded so changes apply on refresh
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || !user.IsActive {
+		if err := s.Revoke(session.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("user is disabled")
+	}
+
+	expiresAt := time.Now().Add(s.refreshTokenTTL)
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	fresh, err := s.sessionRepo.MarkRefreshTokenUsed(tx, token.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !fresh {
+		tx.Rollback()
+		if err := s.Revoke(session.ID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("refresh token reused")
+	}
+
+	newToken, err := s.createRefreshToken(tx, session.ID, expiresAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.sessionRepo.ExtendSession(tx, session.ID, expiresAt); err != nil {
+		return nil, nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := s.tokenPair(user, session.ID, newToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// Revoke ends a session, e.g. on logout
+func (s *sessionService) Revoke(sessionID int64) error {
+	if err := s.sessionRepo.RevokeSession(sessionID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, sessionID)
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeUserSessions ends every session of a user except exceptSessionID
+// (0 ends them all), e.g. when the user is disabled or their password changes
+func (s *sessionService) RevokeUserSessions(userID int, exceptSessionID int64) error {
+	if err := s.sessionRepo.RevokeUserSessions(userID, exceptSessionID); err != nil {
+		return err
+	}
+
+	// The cache is keyed by session, so drop it all; this is rare
+	s.mu.Lock()
+	s.cache = make(map[int64]sessionCacheEntry)
+	s.mu.Unlock()
+	return nil
+}
+
+// IsSessionActive implements middleware.SessionChecker. Results are cached
+// for sessionCacheTTL so authenticated requests do not each hit the database
+func (s *sessionService) IsSessionActive(sessionID int64) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[sessionID]
+	s.mu.Unlock()
+	if ok && now.Sub(entry.checkedAt) < sessionCacheTTL {
+		return entry.active, nil
+	}
+
+	active, err := s.sessionRepo.IsSessionActive(sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	// Drop stale entries now and then so the cache does not grow unbounded
+	if len(s.cache) >= 10000 {
+		for id, e := range s.cache {
+			if now.Sub(e.checkedAt) >= sessionCacheTTL {
+				delete(s.cache, id)
+			}
+		}
+	}
+	s.cache[sessionID] = sessionCacheEntry{active: active, checkedAt: now}
+	s.mu.Unlock()
+
+	return active, nil
+}
+
+// createRefreshToken stores the hash of a new random token and returns the
+// token itself
+func (s *sessionService) createRefreshToken(tx *sql.Tx, sessionID int64, expiresAt time.Time) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+
+	token := &models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.sessionRepo.CreateRefreshToken(tx, token); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+func (s *sessionService) tokenPair(user *models.User, sessionID int64, refreshToken string) (*models.TokenPair, error) {
+	accessToken, err := middleware.GenerateToken(user.ID, user.Username, user.Role, sessionID, s.accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// hashToken is the SHA-256 of a refresh token in hex, as stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
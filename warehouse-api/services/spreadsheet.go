@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// readSpreadsheet reads every row of an uploaded .csv file or of the first
+// sheet of an .xlsx workbook, choosing the format by file extension
+func readSpreadsheet(filename string, r io.Reader) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return readCSV(r)
+	case ".xlsx":
+		return readXLSX(r)
+	default:
+		return nil, &ImportFileError{Reason: "file must be a .csv or .xlsx file"}
+	}
+}
+
+// readCSV accepts comma or semicolon separated files, the latter being what
+// spreadsheet programs write under Indonesian locale settings
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+
+	// Skip a UTF-8 byte order mark
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// Peek returns what it could buffer even when the file is shorter
+	head, _ := br.Peek(4096)
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	if bytes.Count(head, []byte(";")) > bytes.Count(head, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, &ImportFileError{Reason: "invalid CSV file: " + err.Error()}
+	}
+	return rows, nil
+}
+
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, &ImportFileError{Reason: "invalid XLSX file: " + err.Error()}
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, &ImportFileError{Reason: "XLSX file has no sheets"}
+	}
+
+	return f.GetRows(sheets[0])
+}
+
+// importHeader maps normalised column names ("Harga Beli" -> "harga_beli")
+// to their index in the header row
+func importHeader(header []string) map[string]int {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		name = strings.ReplaceAll(name, " ", "_")
+		if _, ok := columns[name]; !ok && name != "" {
+			columns[name] = i
+		}
+	}
+	return columns
+}
+
+// importCell returns the trimmed value of a named column, or "" when the
+// column or the cell is missing. The apostrophe an export puts before text
+// that looks like a formula is dropped again
+func importCell(row []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	value := row[i]
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+		value = value[1:]
+	}
+	return strings.TrimSpace(value)
+}
+
+func isBlankRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Custom error for an uploaded file that cannot be read or has the wrong columns
+type ImportFileError struct {
+	Reason string
+}
+
+func (e *ImportFileError) Error() string {
+	return e.Reason
+}
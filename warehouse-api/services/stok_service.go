@@ -0,0 +1,263 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+)
+
+type StokService interface {
+	ReadStokAwalFile(filename string, file io.Reader) ([]models.StokAwalItem, error)
+	ImportStokAwal(req *models.ImportStokAwalRequest, userID int) (*models.ImportStokAwalResult, error)
+}
+
+type stokService struct {
+	db         *sql.DB
+	stokRepo   repositories.StokRepository
+	barangRepo repositories.BarangRepository
+	auditRepo  repositories.AuditRepository
+}
+
+func NewStokService(db *sql.DB, stokRepo repositories.StokRepository, barangRepo repositories.BarangRepository,
+	auditRepo repositories.AuditRepository) StokService {
+	return &stokService{
+		db:         db,
+		stokRepo:   stokRepo,
+		barangRepo: barangRepo,
+		auditRepo:  auditRepo,
+	}
+}
+
+// ReadStokAwalFile reads opening balances from a CSV or XLSX file with the
+// columns kode_barang, qty and optionally harga. Unparseable cells are turned
+// into invalid values so ImportStokAwal reports them per row
+func (s *stokService) ReadStokAwalFile(filename string, file io.Reader) ([]models.StokAwalItem, error) {
+	records, err := readSpreadsheet(filename, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, &ImportFileError{Reason: "file is empty"}
+	}
+
+	columns := importHeader(records[0])
+	for _, name := range []string{"kode_barang", "qty"} {
+		if _, ok := columns[name]; !ok {
+			return nil, &ImportFileError{Reason: fmt.Sprintf("missing column %s", name)}
+		}
+	}
+
+	var items []models.StokAwalItem
+	for i, record := range records[1:] {
+		if isBlankRow(record) {
+			continue
+		}
+		if len(items) == maxImportRows {
+			return nil, &ImportFileError{Reason: fmt.Sprintf("cannot import more than %d rows at once", maxImportRows)}
+		}
+
+		item := models.StokAwalItem{Row: i + 2, KodeBarang: importCell(record, columns, "kode_barang"), Qty: -1}
+		if qty, err := strconv.Atoi(importCell(record, columns, "qty")); err == nil {
+			item.Qty = qty
+		}
+		if value := importCell(record, columns, "harga"); value != "" {
+			harga, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				harga = -1
+			}
+			item.Harga = &harga
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// ImportStokAwal sets the opening balance of every item in one transaction:
+// stok_awal is replaced and stok_akhir recomputed. The first opening balance
+// of a barang is written to the ledger as an 'awal' row with the opening cost;
+// a later one as a 'koreksi' row for the difference, so ledger rows are never
+// changed. A barang that already has other stock movements is rejected unless
+// req.Force is set. Every change is audited as userID. Nothing is written
+// when any item has errors
+func (s *stokService) ImportStokAwal(req *models.ImportStokAwalRequest, userID int) (*models.ImportStokAwalResult, error) {
+	if len(req.Items) == 0 {
+		return nil, &ImportFileError{Reason: "items cannot be empty"}
+	}
+	if len(req.Items) > maxImportRows {
+		return nil, &ImportFileError{Reason: fmt.Sprintf("cannot import more than %d rows at once", maxImportRows)}
+	}
+
+	result := &models.ImportStokAwalResult{Forced: req.Force, TotalRows: len(req.Items)}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	seen := make(map[int]int)
+	for i, item := range req.Items {
+		if item.Row == 0 {
+			item.Row = i + 1
+		}
+		row := models.StokAwalRow{Row: item.Row, BarangID: item.BarangID, KodeBarang: item.KodeBarang, Qty: item.Qty}
+
+		barang, err := s.findStokAwalBarang(item)
+		if err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		} else {
+			row.BarangID = barang.ID
+			row.KodeBarang = barang.KodeBarang
+			row.NamaBarang = barang.NamaBarang
+			row.Harga = barang.HargaBeli
+
+			if barang.IsKit {
+				row.Errors = append(row.Errors, "a kit has no stock of its own")
+			}
+			if first, ok := seen[barang.ID]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("duplicate barang %s (first used on row %d)", barang.KodeBarang, first))
+			} else {
+				seen[barang.ID] = row.Row
+			}
+		}
+
+		if item.Qty < 0 {
+			row.Errors = append(row.Errors, "qty must be a whole number of at least 0")
+		}
+		if item.Harga != nil {
+			if *item.Harga < 0 {
+				row.Errors = append(row.Errors, "harga must be a number of at least 0")
+			}
+			row.Harga = *item.Harga
+		}
+
+		if len(row.Errors) == 0 {
+			if err := s.applyStokAwal(tx, &row, req.Force, userID); err != nil {
+				if _, ok := err.(*stokAwalBlockedError); !ok {
+					return nil, err
+				}
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+
+		if len(row.Errors) > 0 {
+			result.ErrorRows++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if result.ErrorRows > 0 {
+		return result, nil
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	result.Committed = true
+
+	return result, nil
+}
+
+func (s *stokService) findStokAwalBarang(item models.StokAwalItem) (*models.Barang, error) {
+	if item.BarangID != 0 {
+		barang, err := s.barangRepo.FindByID(item.BarangID)
+		if err != nil {
+			return nil, fmt.Errorf("barang with id %d not found", item.BarangID)
+		}
+		return barang, nil
+	}
+
+	if item.KodeBarang == "" {
+		return nil, fmt.Errorf("barang_id or kode_barang is required")
+	}
+	barang, err := s.barangRepo.FindByKodeBarang(item.KodeBarang)
+	if err != nil {
+		return nil, fmt.Errorf("barang %s not found", item.KodeBarang)
+	}
+	return barang, nil
+}
+
+// applyStokAwal writes one opening balance inside tx
+func (s *stokService) applyStokAwal(tx *sql.Tx, row *models.StokAwalRow, force bool, userID int) error {
+	stok, err := s.stokRepo.FindByBarangIDForUpdate(tx, row.BarangID)
+	if err != nil {
+		return err
+	}
+	if stok == nil {
+		if err := s.stokRepo.CreateStok(tx, row.BarangID); err != nil {
+			return err
+		}
+		stok = &models.Stok{BarangID: row.BarangID}
+	}
+
+	hasTransactions, err := s.stokRepo.HasTransactions(tx, row.BarangID)
+	if err != nil {
+		return err
+	}
+	if hasTransactions && !force {
+		return &stokAwalBlockedError{KodeBarang: row.KodeBarang}
+	}
+
+	hasAwal, err := s.stokRepo.HasHistoryAwal(tx, row.BarangID)
+	if err != nil {
+		return err
+	}
+
+	selisih := row.Qty - stok.StokAwal
+	if hasAwal && selisih == 0 {
+		row.StokAkhir = stok.StokAkhir
+		return nil
+	}
+
+	stokAkhir, err := s.stokRepo.SetStokAwal(tx, row.BarangID, row.Qty)
+	if err != nil {
+		return err
+	}
+	row.StokAkhir = stokAkhir
+
+	// The ledger continues from the current balance; an opening balance
+	// already in it is corrected by the difference
+	harga := row.Harga
+	history := &models.HistoryStok{
+		BarangID:       row.BarangID,
+		JenisTransaksi: "awal",
+		Qty:            row.Qty,
+		StokSebelum:    stok.StokAkhir,
+		StokSesudah:    stokAkhir,
+		Keterangan:     "Stok awal",
+		ReferensiTipe:  "stok_awal",
+		Harga:          &harga,
+	}
+	if hasAwal {
+		history.JenisTransaksi = "koreksi"
+		history.Qty = selisih
+		history.Keterangan = fmt.Sprintf("Koreksi stok awal %d -> %d", stok.StokAwal, row.Qty)
+	}
+	if err := s.stokRepo.InsertHistory(tx, history); err != nil {
+		return err
+	}
+
+	before := map[string]interface{}{"stok_awal": stok.StokAwal, "stok_akhir": stok.StokAkhir}
+	after := map[string]interface{}{"stok_awal": row.Qty, "stok_akhir": stokAkhir, "harga": row.Harga, "force": force}
+	entry, err := newAuditEntry(&userID, "stok_awal", "barang", row.BarangID, row.KodeBarang, before, after)
+	if err != nil {
+		return err
+	}
+	return s.auditRepo.CreateTx(tx, entry)
+}
+
+// stokAwalBlockedError rejects an opening balance for a barang that already
+// has transactions
+type stokAwalBlockedError struct {
+	KodeBarang string
+}
+
+func (e *stokAwalBlockedError) Error() string {
+	return fmt.Sprintf("barang %s already has stock transactions; an admin must use force to reset its opening balance", e.KodeBarang)
+}
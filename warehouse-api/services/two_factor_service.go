@@ -0,0 +1,380 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod = 30
+	// totpSkew accepts codes one step before and after the current one, for
+	// clocks that are slightly off
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+
+	loginChallengeTTL         = 5 * time.Minute
+	loginChallengeMaxAttempts = 5
+)
+
+type TwoFactorService interface {
+	Status(user *models.User) (*models.TwoFactorStatus, error)
+	IsRequired(role string) (bool, error)
+	Setup(user *models.User) (*models.TwoFactorSetup, error)
+	Enable(userID int, code string) ([]string, error)
+	Verify(user *models.User, code, recoveryCode string) error
+	Disable(userID int) error
+	RegenerateRecoveryCodes(userID int) ([]string, error)
+	UsersWithout2FA(role string) ([]int, error)
+	CreateChallenge(user *models.User, purpose, ip string) (*models.LoginChallengeResponse, error)
+	FindChallenge(token string) (*models.LoginChallenge, error)
+	ChallengeFailed(challenge *models.LoginChallenge) error
+	CompleteChallenge(challenge *models.LoginChallenge) error
+}
+
+type twoFactorService struct {
+	db            *sql.DB
+	twoFactorRepo repositories.TwoFactorRepository
+	userRepo      repositories.UserRepository
+	roleRepo      repositories.RoleRepository
+	issuer        string
+}
+
+func NewTwoFactorService(db *sql.DB, twoFactorRepo repositories.TwoFactorRepository, userRepo repositories.UserRepository,
+	roleRepo repositories.RoleRepository, issuer string) TwoFactorService {
+	return &twoFactorService{
+		db:            db,
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		issuer:        issuer,
+	}
+}
+
+func (s *twoFactorService) Status(user *models.User) (*models.TwoFactorStatus, error) {
+	required, err := s.IsRequired(user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := 0
+	if user.TwoFactorEnabled {
+		if remaining, err = s.twoFactorRepo.CountRecoveryCodes(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.TwoFactorStatus{
+		Enabled:                user.TwoFactorEnabled,
+		Required:               required,
+		RecoveryCodesRemaining: remaining,
+	}, nil
+}
+
+// IsRequired reports whether users of role must use 2FA
+func (s *twoFactorService) IsRequired(role string) (bool, error) {
+	return s.roleRepo.FindRequire2FA(role)
+}
+
+// Setup starts an enrolment with a new secret. 2FA is not enabled until a
+// code from it has been confirmed with Enable; calling Setup again replaces
+// the secret
+func (s *twoFactorService) Setup(user *models.User) (*models.TwoFactorSetup, error) {
+	if user.TwoFactorEnabled {
+		return nil, fmt.Errorf("two-factor already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.issuer,
+		AccountName: user.Username,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.SetSecret(user.ID, key.Secret()); err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetup{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Enable confirms an enrolment with a code from the authenticator app and
+// returns the new recovery codes, which are only shown this once
+func (s *twoFactorService) Enable(userID int, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.TwoFactorEnabled {
+		return nil, fmt.Errorf("two-factor already enabled")
+	}
+	if user.TOTPSecret == nil {
+		return nil, fmt.Errorf("two-factor setup not started")
+	}
+
+	step, ok := matchCode(*user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("invalid two-factor code")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.twoFactorRepo.Enable(tx, user.ID, step); err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(tx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks a code from the authenticator app or, when code is empty,
+// a recovery code. Either works only once
+func (s *twoFactorService) Verify(user *models.User, code, recoveryCode string) error {
+	if !user.TwoFactorEnabled || user.TOTPSecret == nil {
+		return fmt.Errorf("two-factor not enabled")
+	}
+
+	if code != "" {
+		step, ok := matchCode(*user.TOTPSecret, code, time.Now())
+		if !ok {
+			return fmt.Errorf("invalid two-factor code")
+		}
+		fresh, err := s.twoFactorRepo.UseStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return fmt.Errorf("invalid two-factor code")
+		}
+		return nil
+	}
+
+	if recoveryCode == "" {
+		return fmt.Errorf("invalid two-factor code")
+	}
+	used, err := s.twoFactorRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return fmt.Errorf("invalid two-factor code")
+	}
+	return nil
+}
+
+// Disable turns 2FA off, e.g. when a user has lost their device
+func (s *twoFactorService) Disable(userID int) error {
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.twoFactorRepo.Disable(tx, userID); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	return tx.Commit()
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of a user
+func (s *twoFactorService) RegenerateRecoveryCodes(userID int) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(tx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *twoFactorService) UsersWithout2FA(role string) ([]int, error) {
+	return s.twoFactorRepo.FindUsersWithout2FA(role)
+}
+
+// CreateChallenge issues the token that carries a login from the password
+// step to the code step
+func (s *twoFactorService) CreateChallenge(user *models.User, purpose, ip string) (*models.LoginChallengeResponse, error) {
+	now := time.Now()
+	if err := s.twoFactorRepo.DeleteExpiredChallenges(now); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	challenge := &models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		Purpose:   purpose,
+		IPAddress: &ip,
+		ExpiresAt: now.Add(loginChallengeTTL),
+	}
+	if err := s.twoFactorRepo.CreateChallenge(challenge); err != nil {
+		return nil, err
+	}
+
+	return &models.LoginChallengeResponse{
+		ChallengeToken: token,
+		Purpose:        purpose,
+		ExpiresIn:      int(loginChallengeTTL.Seconds()),
+	}, nil
+}
+
+func (s *twoFactorService) FindChallenge(token string) (*models.LoginChallenge, error) {
+	challenge, err := s.twoFactorRepo.FindChallenge(hashToken(token))
+	if err != nil {
+		if err.Error() == "login challenge not found" {
+			return nil, fmt.Errorf("invalid login challenge")
+		}
+		return nil, err
+	}
+
+	if time.Now().After(challenge.ExpiresAt) {
+		if err := s.twoFactorRepo.DeleteChallenge(challenge.ID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid login challenge")
+	}
+
+	return challenge, nil
+}
+
+// ChallengeFailed counts a wrong code; after loginChallengeMaxAttempts the
+// challenge is dropped and the user has to enter their password again
+func (s *twoFactorService) ChallengeFailed(challenge *models.LoginChallenge) error {
+	attempts, err := s.twoFactorRepo.AddChallengeAttempt(challenge.ID)
+	if err != nil {
+		return err
+	}
+	if attempts >= loginChallengeMaxAttempts {
+		return s.twoFactorRepo.DeleteChallenge(challenge.ID)
+	}
+	return nil
+}
+
+// CompleteChallenge drops a challenge once the login went through, so its
+// token cannot be used again
+func (s *twoFactorService) CompleteChallenge(challenge *models.LoginChallenge) error {
+	return s.twoFactorRepo.DeleteChallenge(challenge.ID)
+}
+
+// matchCode returns the time step code belongs to, checking totpSkew steps
+// on either side of now
+func matchCode(secret, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != otp.DigitsSix.Length() {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes returns recoveryCodeCount codes formatted as
+// xxxxx-xxxxx and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode accepts a recovery code with or without the dash
+// and in any case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"warehouse-api/models"
+	"warehouse-api/repositories"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func testCode(t *testing.T, at time.Time) string {
+	t.Helper()
+	code, err := totp.GenerateCodeCustom(testTOTPSecret, at, totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestMatchCode(t *testing.T) {
+	// The middle of a step, so the step boundaries are clear of it
+	now := time.Unix(1700000000/totpPeriod*totpPeriod+totpPeriod/2, 0)
+	current := now.Unix() / totpPeriod
+	step := time.Duration(totpPeriod) * time.Second
+
+	code := testCode(t, now)
+	spaced := code[:3] + " " + code[3:]
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code, current, true},
+		{"previous step", testCode(t, now.Add(-step)), current - 1, true},
+		{"next step", testCode(t, now.Add(step)), current + 1, true},
+		{"two steps old", testCode(t, now.Add(-2*step)), 0, false},
+		{"two steps ahead", testCode(t, now.Add(2*step)), 0, false},
+		{"spaces", spaced, current, true},
+		{"surrounding whitespace", " " + code + "\n", current, true},
+		{"wrong code", wrong, 0, false},
+		{"too short", code[:5], 0, false},
+		{"too long", code + "0", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := matchCode(testTOTPSecret, tt.code, now)
+			if gotOK != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("matchCode(%q) = %d, %v, want %d, %v", tt.code, gotStep, gotOK, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// stepRepository keeps the last used step like the totp_last_step column;
+// other methods are not used by Verify
+type stepRepository struct {
+	repositories.TwoFactorRepository
+	lastStep *int64
+}
+
+func (r *stepRepository) UseStep(userID int, step int64) (bool, error) {
+	if r.lastStep != nil && *r.lastStep >= step {
+		return false, nil
+	}
+	r.lastStep = &step
+	return true, nil
+}
+
+func TestVerifyRejectsReplayedStep(t *testing.T) {
+	secret := testTOTPSecret
+	user := &models.User{ID: 1, TwoFactorEnabled: true, TOTPSecret: &secret}
+
+	// Steps are counted from the current one
+	tests := []struct {
+		name    string
+		used    bool
+		usedAt  int64
+		codeAt  int64
+		wantErr bool
+	}{
+		{"first use", false, 0, 0, false},
+		{"same step again", true, 0, 0, true},
+		{"older step after a newer one", true, 1, 0, true},
+		{"newer step", true, -1, 0, false},
+		{"previous step within skew", true, -2, -1, false},
+		{"previous step already used", true, -1, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			current := now.Unix() / totpPeriod
+
+			repo := &stepRepository{}
+			if tt.used {
+				lastStep := current + tt.usedAt
+				repo.lastStep = &lastStep
+			}
+
+			s := &twoFactorService{twoFactorRepo: repo}
+			code := testCode(t, time.Unix((current+tt.codeAt)*totpPeriod, 0))
+			err := s.Verify(user, code, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
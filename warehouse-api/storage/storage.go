@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned by Open when no file is stored under the key
+var ErrNotFound = errors.New("file not found")
+
+// Storage keeps uploaded files under slash-separated keys such as
+// "barang/12/3f9a.jpg". Implementations must be safe for concurrent use
+type Storage interface {
+	Save(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
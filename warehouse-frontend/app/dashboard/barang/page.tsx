@@ -122,6 +122,16 @@ export default function BarangPage() {
     })
   }
 
+  const handleArchive = async (id: number) => {
+    try {
+      await api.post(`/barang/${id}/archive`)
+      toast.success('Barang berhasil diarsipkan!')
+      fetchBarangs()
+    } catch (error: any) {
+      toast.error(error.response?.data?.message || 'Gagal mengarsipkan barang')
+    }
+  }
+
   const resetForm = () => {
     setEditId(null)
     setFormData({
@@ -204,6 +214,12 @@ export default function BarangPage() {
                     >
                       Edit
                     </button>
+                    <button
+                      onClick={() => handleArchive(barang.id)}
+                      className="text-yellow-600 hover:text-yellow-900 mr-3"
+                    >
+                      Archive
+                    </button>
                     <button
                       onClick={() => handleDelete(barang.id)}
                       className="text-red-600 hover:text-red-900"
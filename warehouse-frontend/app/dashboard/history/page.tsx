@@ -51,6 +51,18 @@ export default function HistoryStokPage() {
           Masuk
         </span>
       )
+    } else if (jenis === 'awal') {
+      return (
+        <span className="px-2 inline-flex text-xs leading-5 font-semibold rounded-full bg-blue-100 text-blue-800">
+          Stok Awal
+        </span>
+      )
+    } else if (jenis === 'koreksi') {
+      return (
+        <span className="px-2 inline-flex text-xs leading-5 font-semibold rounded-full bg-yellow-100 text-yellow-800">
+          Koreksi
+        </span>
+      )
     } else if (jenis === 'keluar') {
       return (
         <span className="px-2 inline-flex text-xs leading-5 font-semibold rounded-full bg-red-100 text-red-800">
@@ -114,7 +126,7 @@ export default function HistoryStokPage() {
                       {getTransactionBadge(history.jenis_transaksi)}
                     </td>
                     <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-900 font-semibold">
-                      {history.jenis_transaksi === 'masuk' ? '+' : '-'}{history.qty}
+                      {history.jenis_transaksi === 'keluar' ? '-' : history.qty < 0 ? '' : '+'}{history.qty}
                     </td>
                     <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">
                       {history.stok_sebelum}
@@ -2,6 +2,7 @@
 
 import { useEffect, useState } from 'react'
 import { useRouter } from 'next/navigation'
+import api, { clearSession } from '@/lib/api'
 
 export default function DashboardLayout({
   children,
@@ -25,9 +26,13 @@ export default function DashboardLayout({
     }
   }, [router])
 
-  const handleLogout = () => {
-    localStorage.removeItem('token')
-    localStorage.removeItem('user')
+  const handleLogout = async () => {
+    try {
+      await api.post('/logout')
+    } catch {
+      // the session is dropped locally either way
+    }
+    clearSession()
     router.push('/login')
   }
 
@@ -22,6 +22,12 @@ interface Barang {
   harga_jual: number
 }
 
+interface Supplier {
+  id: number
+  kode_supplier: string
+  nama_supplier: string
+}
+
 interface DetailItem {
   barang_id: number
   qty: number
@@ -32,6 +38,7 @@ export default function PembelianPage() {
   const router = useRouter()
   const [pembelians, setPembelians] = useState<Pembelian[]>([])
   const [barangs, setBarangs] = useState<Barang[]>([])
+  const [suppliers, setSuppliers] = useState<Supplier[]>([])
   const [loading, setLoading] = useState(false)
   const [showModal, setShowModal] = useState(false)
   const [page, setPage] = useState(1)
@@ -41,7 +48,7 @@ export default function PembelianPage() {
   const [formData, setFormData] = useState({
     no_faktur: '',
     tanggal: '',
-    supplier: '',
+    supplier_id: 0,
     keterangan: ''
   })
 
@@ -52,6 +59,7 @@ export default function PembelianPage() {
   useEffect(() => {
     fetchPembelians()
     fetchBarangs()
+    fetchSuppliers()
   }, [page])
 
   const fetchPembelians = async () => {
@@ -76,6 +84,15 @@ export default function PembelianPage() {
     }
   }
 
+  const fetchSuppliers = async () => {
+    try {
+      const response = await api.get('/supplier?limit=100')
+      setSuppliers(response.data.data || [])
+    } catch (error) {
+      console.error('Error fetching supplier:', error)
+    }
+  }
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
     setLoading(true)
@@ -126,12 +143,22 @@ export default function PembelianPage() {
     setFormData({
       no_faktur: '',
       tanggal: '',
-      supplier: '',
+      supplier_id: 0,
       keterangan: ''
     })
     setDetails([{ barang_id: 0, qty: 0, harga: 0 }])
   }
 
+  const openPdf = async (path: string) => {
+    try {
+      const response = await api.get(path, { responseType: 'blob' })
+      const url = URL.createObjectURL(response.data)
+      window.open(url, '_blank')
+    } catch (error) {
+      toast.error('Gagal membuat dokumen')
+    }
+  }
+
   const totalPages = Math.ceil(total / limit)
 
   return (
@@ -164,16 +191,17 @@ export default function PembelianPage() {
               <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Supplier</th>
               <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Total</th>
               <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Keterangan</th>
+              <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Aksi</th>
             </tr>
           </thead>
           <tbody className="bg-white divide-y divide-gray-200">
             {loading ? (
               <tr>
-                <td colSpan={5} className="px-6 py-4 text-center">Loading...</td>
+                <td colSpan={6} className="px-6 py-4 text-center">Loading...</td>
               </tr>
             ) : pembelians.length === 0 ? (
               <tr>
-                <td colSpan={5} className="px-6 py-4 text-center">No data found</td>
+                <td colSpan={6} className="px-6 py-4 text-center">No data found</td>
               </tr>
             ) : (
               pembelians.map((pembelian) => (
@@ -185,6 +213,14 @@ export default function PembelianPage() {
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">{pembelian.supplier}</td>
                   <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">Rp {pembelian.total.toLocaleString()}</td>
                   <td className="px-6 py-4 text-sm text-gray-500">{pembelian.keterangan}</td>
+                  <td className="px-6 py-4 whitespace-nowrap text-sm">
+                    <button
+                      onClick={() => openPdf(`/pembelian/${pembelian.id}/pdf`)}
+                      className="text-indigo-600 hover:text-indigo-900"
+                    >
+                      Cetak Penerimaan
+                    </button>
+                  </td>
                 </tr>
               ))
             )}
@@ -252,13 +288,19 @@ export default function PembelianPage() {
 
                   <div className="mb-4">
                     <label className="block text-gray-700 text-sm font-bold mb-2">Supplier</label>
-                    <input
-                      type="text"
-                      value={formData.supplier}
-                      onChange={(e) => setFormData({ ...formData, supplier: e.target.value })}
-                      className="shadow appearance-none border rounded w-full py-2 px-3 text-gray-700"
+                    <select
+                      value={formData.supplier_id}
+                      onChange={(e) => setFormData({ ...formData, supplier_id: Number(e.target.value) })}
+                      className="shadow border rounded w-full py-2 px-3 text-gray-700"
                       required
-                    />
+                    >
+                      <option value={0}>Pilih Supplier</option>
+                      {suppliers.map((supplier) => (
+                        <option key={supplier.id} value={supplier.id}>
+                          {supplier.kode_supplier} - {supplier.nama_supplier}
+                        </option>
+                      ))}
+                    </select>
                   </div>
 
                   <div className="mb-4">
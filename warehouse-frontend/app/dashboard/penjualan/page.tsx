@@ -22,6 +22,12 @@ interface Barang {
   harga_jual: number;
 }
 
+interface Customer {
+  id: number;
+  kode_customer: string;
+  nama_customer: string;
+}
+
 interface DetailItem {
   barang_id: number;
   qty: number;
@@ -32,6 +38,7 @@ export default function PenjualanPage() {
   const router = useRouter();
   const [penjualans, setPenjualans] = useState<Penjualan[]>([]);
   const [barangs, setBarangs] = useState<Barang[]>([]);
+  const [customers, setCustomers] = useState<Customer[]>([]);
   const [loading, setLoading] = useState(false);
   const [showModal, setShowModal] = useState(false);
   const [page, setPage] = useState(1);
@@ -41,7 +48,7 @@ export default function PenjualanPage() {
   const [formData, setFormData] = useState({
     no_faktur: "",
     tanggal: "",
-    customer: "",
+    customer_id: 0,
     keterangan: "",
   });
 
@@ -52,6 +59,7 @@ export default function PenjualanPage() {
   useEffect(() => {
     fetchPenjualans();
     fetchBarangs();
+    fetchCustomers();
   }, [page]);
 
   const fetchPenjualans = async () => {
@@ -76,6 +84,15 @@ export default function PenjualanPage() {
     }
   };
 
+  const fetchCustomers = async () => {
+    try {
+      const response = await api.get("/customer?limit=100");
+      setCustomers(response.data.data || []);
+    } catch (error) {
+      console.error("Error fetching customer:", error);
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     setLoading(true);
@@ -124,18 +141,52 @@ export default function PenjualanPage() {
     }
 
     setDetails(newDetails);
+
+    // Resolve harga from the customer's price list and quantity breaks
+    if (field === "barang_id" || field === "qty") {
+      resolveHarga(index, newDetails[index]);
+    }
+  };
+
+  const resolveHarga = async (index: number, detail: DetailItem) => {
+    if (!formData.customer_id || !detail.barang_id) return;
+    try {
+      const response = await api.get(
+        `/penjualan/harga?customer_id=${formData.customer_id}&barang_id=${
+          detail.barang_id
+        }&qty=${detail.qty || 1}`
+      );
+      const harga = response.data.data?.harga;
+      setDetails((current) =>
+        current.map((d, i) =>
+          i === index && d.barang_id === detail.barang_id ? { ...d, harga } : d
+        )
+      );
+    } catch (error) {
+      console.error("Error resolving harga:", error);
+    }
   };
 
   const resetForm = () => {
     setFormData({
       no_faktur: "",
       tanggal: "",
-      customer: "",
+      customer_id: 0,
       keterangan: "",
     });
     setDetails([{ barang_id: 0, qty: 0, harga: 0 }]);
   };
 
+  const openPdf = async (path: string) => {
+    try {
+      const response = await api.get(path, { responseType: "blob" });
+      const url = URL.createObjectURL(response.data);
+      window.open(url, "_blank");
+    } catch (error) {
+      toast.error("Gagal membuat dokumen");
+    }
+  };
+
   const totalPages = Math.ceil(total / limit);
 
   return (
@@ -178,18 +229,21 @@ export default function PenjualanPage() {
               <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
                 Keterangan
               </th>
+              <th className="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">
+                Aksi
+              </th>
             </tr>
           </thead>
           <tbody className="bg-white divide-y divide-gray-200">
             {loading ? (
               <tr>
-                <td colSpan={5} className="px-6 py-4 text-center">
+                <td colSpan={6} className="px-6 py-4 text-center">
                   Loading...
                 </td>
               </tr>
             ) : penjualans.length === 0 ? (
               <tr>
-                <td colSpan={5} className="px-6 py-4 text-center">
+                <td colSpan={6} className="px-6 py-4 text-center">
                   No data found
                 </td>
               </tr>
@@ -211,6 +265,22 @@ export default function PenjualanPage() {
                   <td className="px-6 py-4 text-sm text-gray-500">
                     {penjualan.keterangan}
                   </td>
+                  <td className="px-6 py-4 whitespace-nowrap text-sm space-x-3">
+                    <button
+                      onClick={() => openPdf(`/penjualan/${penjualan.id}/pdf`)}
+                      className="text-indigo-600 hover:text-indigo-900"
+                    >
+                      Cetak Faktur
+                    </button>
+                    <button
+                      onClick={() =>
+                        openPdf(`/penjualan/${penjualan.id}/surat-jalan/pdf`)
+                      }
+                      className="text-indigo-600 hover:text-indigo-900"
+                    >
+                      Surat Jalan
+                    </button>
+                  </td>
                 </tr>
               ))
             )}
@@ -298,15 +368,24 @@ export default function PenjualanPage() {
                     <label className="block text-gray-700 text-sm font-bold mb-2">
                       Customer
                     </label>
-                    <input
-                      type="text"
-                      value={formData.customer}
+                    <select
+                      value={formData.customer_id}
                       onChange={(e) =>
-                        setFormData({ ...formData, customer: e.target.value })
+                        setFormData({
+                          ...formData,
+                          customer_id: Number(e.target.value),
+                        })
                       }
-                      className="shadow appearance-none border rounded w-full py-2 px-3 text-gray-700"
+                      className="shadow border rounded w-full py-2 px-3 text-gray-700"
                       required
-                    />
+                    >
+                      <option value={0}>Pilih Customer</option>
+                      {customers.map((customer) => (
+                        <option key={customer.id} value={customer.id}>
+                          {customer.kode_customer} - {customer.nama_customer}
+                        </option>
+                      ))}
+                    </select>
                   </div>
 
                   <div className="mb-4">
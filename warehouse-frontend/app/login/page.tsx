@@ -1,15 +1,79 @@
 'use client'
 
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
 import { useRouter } from 'next/navigation'
 import toast from 'react-hot-toast'
 import api from '@/lib/api'
 
+// Reasons the SSO callback sends back in ?sso_error=
+const ssoErrors: Record<string, string> = {
+  not_configured: 'Single sign-on is not configured',
+  invalid_state: 'Single sign-on expired, please try again',
+  denied: 'Login was cancelled at the identity provider',
+  no_role: 'Your groups do not give access to this application',
+  username_taken: 'A local account with your username already exists, ask an administrator',
+  disabled: 'User is disabled',
+  failed: 'Single sign-on failed',
+}
+
 export default function LoginPage() {
   const router = useRouter()
   const [username, setUsername] = useState('')
   const [password, setPassword] = useState('')
   const [loading, setLoading] = useState(false)
+  // Second login step for users with two-factor authentication
+  const [challenge, setChallenge] = useState<{ challenge_token: string; purpose: string } | null>(null)
+  const [setup, setSetup] = useState<{ secret: string; qr_code: string } | null>(null)
+  const [code, setCode] = useState('')
+  const [useRecovery, setUseRecovery] = useState(false)
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([])
+  const [pendingLogin, setPendingLogin] = useState<any>(null)
+  const [ssoEnabled, setSsoEnabled] = useState(false)
+
+  useEffect(() => {
+    api.get('/oidc/config')
+      .then((response) => setSsoEnabled(response.data.data.enabled))
+      .catch(() => setSsoEnabled(false))
+
+    // Back from the identity provider
+    const params = new URLSearchParams(window.location.search)
+    const ssoCode = params.get('sso_code')
+    const ssoError = params.get('sso_error')
+    if (!ssoCode && !ssoError) return
+    router.replace('/login')
+
+    if (ssoError) {
+      toast.error(ssoErrors[ssoError] || ssoErrors.failed)
+      return
+    }
+    setLoading(true)
+    api.post('/login/sso', { code: ssoCode })
+      .then((response) => handleLoginResponse(response.data.data))
+      .catch((err: any) => toast.error(err.response?.data?.message || 'Login failed'))
+      .finally(() => setLoading(false))
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [])
+
+  const finishLogin = (data: any) => {
+    localStorage.setItem('token', data.token)
+    localStorage.setItem('refresh_token', data.refresh_token)
+    localStorage.setItem('user', JSON.stringify(data.user))
+    toast.success('Login successful!')
+    router.push('/dashboard')
+  }
+
+  // A password or SSO login either returns tokens or a two-factor challenge
+  const handleLoginResponse = async (data: any) => {
+    if (data.challenge_token) {
+      setChallenge(data)
+      if (data.purpose === 'enroll') {
+        const setupResponse = await api.post('/login/2fa/setup', { challenge_token: data.challenge_token })
+        setSetup(setupResponse.data.data)
+      }
+      return
+    }
+    finishLogin(data)
+  }
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
@@ -19,10 +83,7 @@ export default function LoginPage() {
       const response = await api.post('/login', { username, password })
       
       if (response.data.success) {
-        localStorage.setItem('token', response.data.data.token)
-        localStorage.setItem('user', JSON.stringify(response.data.data.user))
-        toast.success('Login successful!')
-        router.push('/dashboard')
+        await handleLoginResponse(response.data.data)
       }
     } catch (err: any) {
       toast.error(err.response?.data?.message || 'Login failed')
@@ -31,6 +92,107 @@ export default function LoginPage() {
     }
   }
 
+  const handleCode = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (!challenge) return
+    setLoading(true)
+
+    try {
+      if (challenge.purpose === 'enroll') {
+        const response = await api.post('/login/2fa/enable', { challenge_token: challenge.challenge_token, code })
+        // Show the recovery codes once before continuing
+        setRecoveryCodes(response.data.data.recovery_codes)
+        setPendingLogin(response.data.data)
+      } else {
+        const body = useRecovery ? { recovery_code: code } : { code }
+        const response = await api.post('/login/2fa', { challenge_token: challenge.challenge_token, ...body })
+        finishLogin(response.data.data)
+      }
+    } catch (err: any) {
+      if (err.response?.status === 401 && err.response?.data?.message !== 'Invalid two-factor code') {
+        setChallenge(null)
+        setSetup(null)
+      }
+      toast.error(err.response?.data?.message || 'Verification failed')
+    } finally {
+      setCode('')
+      setLoading(false)
+    }
+  }
+
+  if (recoveryCodes.length > 0) {
+    return (
+      <div className="flex min-h-screen items-center justify-center bg-gray-100">
+        <div className="w-full max-w-md bg-white shadow-md rounded-lg px-8 py-10">
+          <h2 className="text-2xl font-bold text-gray-800 mb-4">Recovery codes</h2>
+          <p className="text-sm text-gray-600 mb-4">
+            Store these codes somewhere safe. Each one can be used once to log in without your authenticator app.
+          </p>
+          <ul className="grid grid-cols-2 gap-2 font-mono mb-6">
+            {recoveryCodes.map((c) => (
+              <li key={c}>{c}</li>
+            ))}
+          </ul>
+          <button
+            onClick={() => finishLogin(pendingLogin)}
+            className="w-full bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded"
+          >
+            I have saved them
+          </button>
+        </div>
+      </div>
+    )
+  }
+
+  if (challenge) {
+    return (
+      <div className="flex min-h-screen items-center justify-center bg-gray-100">
+        <div className="w-full max-w-md bg-white shadow-md rounded-lg px-8 py-10">
+          <h2 className="text-2xl font-bold text-gray-800 mb-4">Two-factor authentication</h2>
+
+          {challenge.purpose === 'enroll' && setup && (
+            <div className="mb-6 text-sm text-gray-600">
+              <p className="mb-2">Your role requires two-factor authentication. Scan this code with an authenticator app:</p>
+              <img src={setup.qr_code} alt="QR code" className="mx-auto w-48 h-48" />
+              <p className="mt-2 break-all font-mono text-xs">{setup.secret}</p>
+            </div>
+          )}
+
+          <form onSubmit={handleCode}>
+            <label className="block text-gray-700 text-sm font-bold mb-2">
+              {useRecovery ? 'Recovery code' : 'Code from your authenticator app'}
+            </label>
+            <input
+              type="text"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              className="shadow appearance-none border rounded w-full py-2 px-3 mb-4 text-gray-700 leading-tight focus:outline-none focus:shadow-outline"
+              autoComplete="one-time-code"
+              autoFocus
+              required
+            />
+            <button
+              type="submit"
+              disabled={loading}
+              className="w-full bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded focus:outline-none focus:shadow-outline disabled:opacity-50"
+            >
+              {loading ? 'Loading...' : 'Verify'}
+            </button>
+          </form>
+
+          {challenge.purpose === 'verify' && (
+            <button
+              onClick={() => setUseRecovery(!useRecovery)}
+              className="mt-4 w-full text-sm text-blue-600 hover:underline"
+            >
+              {useRecovery ? 'Use authenticator app' : 'Use a recovery code'}
+            </button>
+          )}
+        </div>
+      </div>
+    )
+  }
+
   return (
     <div className="flex min-h-screen items-center justify-center bg-gray-100">
       <div className="w-full max-w-md">
@@ -77,6 +239,15 @@ export default function LoginPage() {
             </button>
           </form>
 
+          {ssoEnabled && (
+            <a
+              href={`${api.defaults.baseURL}/oidc/login`}
+              className="mt-4 block w-full text-center border border-blue-500 text-blue-600 hover:bg-blue-50 font-bold py-2 px-4 rounded"
+            >
+              Sign in with SSO
+            </a>
+          )}
+
           <div className="mt-6 text-center text-sm text-gray-600">
             <p>Default credentials:</p>
             <p className="font-mono">admin / password123</p>
@@ -7,6 +7,12 @@ const api = axios.create({
   },
 })
 
+const clearSession = () => {
+  localStorage.removeItem('token')
+  localStorage.removeItem('refresh_token')
+  localStorage.removeItem('user')
+}
+
 // Request interceptor to add token
 api.interceptors.request.use(
   (config) => {
@@ -21,17 +27,55 @@ api.interceptors.request.use(
   }
 )
 
-// Response interceptor to handle errors
+// One refresh at a time; requests failing meanwhile wait for it
+let refreshing: Promise<string> | null = null
+
+const refreshToken = async (): Promise<string> => {
+  const refresh_token = localStorage.getItem('refresh_token')
+  if (!refresh_token) {
+    throw new Error('No refresh token')
+  }
+
+  // Plain axios so a failed refresh does not go through the interceptor again
+  const response = await axios.post(`${api.defaults.baseURL}/refresh`, { refresh_token })
+  const { token, refresh_token: next, user } = response.data.data
+  localStorage.setItem('token', token)
+  localStorage.setItem('refresh_token', next)
+  localStorage.setItem('user', JSON.stringify(user))
+  return token
+}
+
+// Response interceptor to handle errors: an expired access token is
+// refreshed once and the request retried, anything else logs out. The login
+// steps handle their own 401s (wrong password or two-factor code)
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const original = error.config
+    if (original?.url?.startsWith('/login')) {
+      return Promise.reject(error)
+    }
+    if (error.response?.status === 401 && original && !original._retry) {
+      original._retry = true
+      try {
+        refreshing = refreshing || refreshToken()
+        const token = await refreshing
+        original.headers.Authorization = `Bearer ${token}`
+        return api(original)
+      } catch {
+        // fall through to logout
+      } finally {
+        refreshing = null
+      }
+    }
+
     if (error.response?.status === 401) {
-      localStorage.removeItem('token')
-      localStorage.removeItem('user')
+      clearSession()
       window.location.href = '/login'
     }
     return Promise.reject(error)
   }
 )
 
+export { clearSession }
 export default api